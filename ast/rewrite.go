@@ -0,0 +1,210 @@
+package ast
+
+import (
+	"fmt"
+
+	"interpreter/lox"
+)
+
+// Rewrite replaces nodes in the tree rooted at node, bottom up: it rewrites a
+// node's children, then calls f with the node and puts whatever f returns in
+// its place. f returns its argument to keep a node. Returning nil removes a
+// statement from a list, such as a block's statements, and clears any other
+// field; clearing a field the interpreter needs leaves an invalid tree.
+//
+// Rewrite changes nodes in place and returns the new root. A for loop's
+// clauses are kept pointing at the nodes that replace the ones they shared
+// with the desugared loop. Trees should be resolved after they are rewritten.
+func Rewrite(node Node, f func(Node) Node) Node {
+	r := rewriter{f: f}
+	return r.node(node)
+}
+
+type rewriter struct {
+	f func(Node) Node
+}
+
+func (r *rewriter) node(node Node) Node {
+	switch n := node.(type) {
+	case *lox.BinaryExpr:
+		n.Left = r.expr(n.Left)
+		n.Right = r.expr(n.Right)
+	case *lox.GroupingExpr:
+		n.Expression = r.expr(n.Expression)
+	case *lox.LiteralExpr, *lox.VariableExpr:
+	case *lox.UnaryExpr:
+		n.Right = r.expr(n.Right)
+	case *lox.AssignExpr:
+		n.Value = r.expr(n.Value)
+	case *lox.LogicalExpr:
+		n.Left = r.expr(n.Left)
+		n.Right = r.expr(n.Right)
+	case *lox.CallExpr:
+		n.Callee = r.expr(n.Callee)
+		n.Arguments = r.exprs(n.Arguments)
+	case *lox.FunctionExpr:
+		n.Body = r.stmts(n.Body)
+	case *lox.GetExpr:
+		n.Object = r.expr(n.Object)
+	case *lox.SetExpr:
+		n.Object = r.expr(n.Object)
+		n.Value = r.expr(n.Value)
+	case *lox.IndexExpr:
+		n.Object = r.expr(n.Object)
+		n.Index = r.expr(n.Index)
+	case *lox.IndexSetExpr:
+		n.Object = r.expr(n.Object)
+		n.Index = r.expr(n.Index)
+		n.Value = r.expr(n.Value)
+	case *lox.ConditionalExpr:
+		n.Condition = r.expr(n.Condition)
+		n.ThenBranch = r.expr(n.ThenBranch)
+		n.ElseBranch = r.expr(n.ElseBranch)
+	case *lox.StringifyExpr:
+		n.Expression = r.expr(n.Expression)
+
+	case *lox.ExprStmt:
+		n.Expression = r.expr(n.Expression)
+	case *lox.PrintStmt:
+		n.Expression = r.expr(n.Expression)
+	case *lox.VarDeclStmt:
+		n.Initializer = r.expr(n.Initializer)
+	case *lox.BlockStmt:
+		r.block(n)
+	case *lox.IfStmt:
+		n.Condition = r.expr(n.Condition)
+		n.ThenBranch = r.stmt(n.ThenBranch)
+		n.ElseBranch = r.stmt(n.ElseBranch)
+	case *lox.WhileStmt:
+		r.while(n)
+	case *lox.ForInStmt:
+		n.Iterable = r.expr(n.Iterable)
+		n.Body = r.stmt(n.Body)
+	case *lox.FunctionDeclStmt:
+		n.Body = r.stmts(n.Body)
+	case *lox.ReturnStmt:
+		n.Value = r.expr(n.Value)
+	case *lox.ThrowStmt:
+		n.Value = r.expr(n.Value)
+	case *lox.TryStmt:
+		n.Body = r.stmts(n.Body)
+		n.CatchBody = r.stmts(n.CatchBody)
+		n.FinallyBody = r.stmts(n.FinallyBody)
+	case *lox.ImportStmt:
+	case *lox.ExportStmt:
+		n.Declaration = r.stmt(n.Declaration)
+
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", n))
+	}
+
+	return r.f(node)
+}
+
+func (r *rewriter) expr(expr lox.Expr) lox.Expr {
+	if expr == nil {
+		return nil
+	}
+
+	switch n := r.node(expr).(type) {
+	case nil:
+		return nil
+	case lox.Expr:
+		return n
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: %T replaces an expression", n))
+	}
+}
+
+func (r *rewriter) stmt(stmt lox.Stmt) lox.Stmt {
+	if stmt == nil {
+		return nil
+	}
+
+	switch n := r.node(stmt).(type) {
+	case nil:
+		return nil
+	case lox.Stmt:
+		return n
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: %T replaces a statement", n))
+	}
+}
+
+func (r *rewriter) exprs(exprs []lox.Expr) []lox.Expr {
+	if exprs == nil {
+		return nil
+	}
+
+	rewritten := make([]lox.Expr, 0, len(exprs))
+	for _, expr := range exprs {
+		if expr = r.expr(expr); expr != nil {
+			rewritten = append(rewritten, expr)
+		}
+	}
+	return rewritten
+}
+
+func (r *rewriter) stmts(stmts []lox.Stmt) []lox.Stmt {
+	if stmts == nil {
+		return nil
+	}
+
+	rewritten := make([]lox.Stmt, 0, len(stmts))
+	for _, stmt := range stmts {
+		if stmt = r.stmt(stmt); stmt != nil {
+			rewritten = append(rewritten, stmt)
+		}
+	}
+	return rewritten
+}
+
+// block rewrites a block, keeping the initializer of a for loop the parser
+// wrapped in it in step with the block's first statement.
+func (r *rewriter) block(block *lox.BlockStmt) {
+	var loop *lox.WhileStmt
+	if len(block.Statements) == 2 {
+		if l, ok := block.Statements[1].(*lox.WhileStmt); ok && l.For != nil && l.For.Initializer == block.Statements[0] {
+			loop = l
+		}
+	}
+
+	block.Statements = r.stmts(block.Statements)
+
+	if loop != nil {
+		loop.For.Initializer = nil
+		if len(block.Statements) == 2 && block.Statements[1] == lox.Stmt(loop) {
+			loop.For.Initializer = block.Statements[0]
+		}
+	}
+}
+
+// while rewrites a while loop, keeping the condition and increment of the
+// for loop it may come from in step with the loop's.
+func (r *rewriter) while(loop *lox.WhileStmt) {
+	sharesCondition := loop.For != nil && loop.For.Condition != nil && loop.For.Condition == loop.Condition
+	sharesIncrement := loop.For != nil && loop.For.Increment != nil && loop.For.Increment == increment(loop)
+
+	loop.Condition = r.expr(loop.Condition)
+	loop.Body = r.stmt(loop.Body)
+
+	if sharesCondition {
+		loop.For.Condition = loop.Condition
+	}
+	if sharesIncrement {
+		loop.For.Increment = increment(loop)
+	}
+}
+
+// increment returns the increment the parser appends to the body of a for
+// loop, or nil if the loop's body has no such shape.
+func increment(loop *lox.WhileStmt) lox.Expr {
+	body, ok := loop.Body.(*lox.BlockStmt)
+	if !ok || len(body.Statements) != 2 {
+		return nil
+	}
+	if stmt, ok := body.Statements[1].(*lox.ExprStmt); ok {
+		return stmt.Expression
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package ast
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"interpreter/lox"
+)
+
+func run(t *testing.T, statements []lox.Stmt) string {
+	t.Helper()
+
+	var output bytes.Buffer
+	interpreter := lox.NewInterpreter(lox.WithStdout(&output))
+	require.NoError(t, lox.NewResolver(interpreter).Resolve(statements))
+	require.NoError(t, interpreter.Interpret(statements))
+	return output.String()
+}
+
+func TestRewrite(t *testing.T) {
+	statements := parse(t, `
+var limit = 3;
+for (var i = 0; i < limit; i = i + 1) print i;
+print "done";`)
+
+	// Replace the variable limit with 2, the loop's increment with i + 2,
+	// and drop the print of a literal.
+	block := lox.NewBlockStmt(statements)
+	Rewrite(block, func(node Node) Node {
+		switch n := node.(type) {
+		case *lox.VariableExpr:
+			if n.Name.Lexeme == "limit" {
+				return lox.NewLiteralExpr(n.Name, lox.NewLiteral(2.0))
+			}
+		case *lox.LiteralExpr:
+			if n.Value.Value == 1.0 {
+				return lox.NewLiteralExpr(n.Token, lox.NewLiteral(2.0))
+			}
+		case *lox.PrintStmt:
+			if _, ok := n.Expression.(*lox.LiteralExpr); ok {
+				return nil
+			}
+		}
+		return node
+	})
+
+	assert.Equal(t, "0\n", run(t, block.Statements))
+
+	loop := block.Statements[1].(*lox.BlockStmt).Statements[1].(*lox.WhileStmt)
+	assert.Same(t, loop.Condition, loop.For.Condition)
+	assert.Same(t, loop.Body.(*lox.BlockStmt).Statements[1].(*lox.ExprStmt).Expression, loop.For.Increment)
+	assert.Same(t, block.Statements[1].(*lox.BlockStmt).Statements[0], loop.For.Initializer)
+}
+
+func TestRewriteReplacesRoot(t *testing.T) {
+	statements := parse(t, `print 1;`)
+
+	root := Rewrite(statements[0], func(node Node) Node {
+		if _, ok := node.(*lox.PrintStmt); ok {
+			return lox.NewExprStmt(lox.NewLiteralExpr(lox.Token{}, lox.NewLiteral(nil)))
+		}
+		return node
+	})
+	assert.IsType(t, &lox.ExprStmt{}, root)
+}
+
+func TestRewritePanicsOnWrongKind(t *testing.T) {
+	statements := parse(t, `print 1;`)
+
+	assert.PanicsWithValue(t, "ast.Rewrite: *lox.ExprStmt replaces an expression", func() {
+		Rewrite(statements[0], func(node Node) Node {
+			if literal, ok := node.(*lox.LiteralExpr); ok {
+				return lox.NewExprStmt(literal)
+			}
+			return node
+		})
+	})
+}
@@ -0,0 +1,139 @@
+// Package ast walks and rewrites Lox syntax trees, so that passes outside the
+// lox package need not implement a method for every node type.
+package ast
+
+import (
+	"fmt"
+
+	"interpreter/lox"
+)
+
+// Node is a lox.Expr or a lox.Stmt.
+type Node any
+
+// A Visitor's Visit method is called by Walk for each node. If the visitor w
+// it returns is not nil, Walk visits each of the node's children with w and
+// then calls w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node depth first, children in source
+// order. It follows the tree the interpreter runs, so a for loop appears as
+// the block and while loop the parser desugars it into, and each node is
+// visited once.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *lox.BinaryExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *lox.GroupingExpr:
+		Walk(v, n.Expression)
+	case *lox.LiteralExpr, *lox.VariableExpr:
+	case *lox.UnaryExpr:
+		Walk(v, n.Right)
+	case *lox.AssignExpr:
+		Walk(v, n.Value)
+	case *lox.LogicalExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *lox.CallExpr:
+		Walk(v, n.Callee)
+		walkExprs(v, n.Arguments)
+	case *lox.FunctionExpr:
+		walkStmts(v, n.Body)
+	case *lox.GetExpr:
+		Walk(v, n.Object)
+	case *lox.SetExpr:
+		Walk(v, n.Object)
+		Walk(v, n.Value)
+	case *lox.IndexExpr:
+		Walk(v, n.Object)
+		Walk(v, n.Index)
+	case *lox.IndexSetExpr:
+		Walk(v, n.Object)
+		Walk(v, n.Index)
+		Walk(v, n.Value)
+	case *lox.ConditionalExpr:
+		Walk(v, n.Condition)
+		Walk(v, n.ThenBranch)
+		Walk(v, n.ElseBranch)
+	case *lox.StringifyExpr:
+		Walk(v, n.Expression)
+
+	case *lox.ExprStmt:
+		Walk(v, n.Expression)
+	case *lox.PrintStmt:
+		Walk(v, n.Expression)
+	case *lox.VarDeclStmt:
+		if n.Initializer != nil {
+			Walk(v, n.Initializer)
+		}
+	case *lox.BlockStmt:
+		walkStmts(v, n.Statements)
+	case *lox.IfStmt:
+		Walk(v, n.Condition)
+		Walk(v, n.ThenBranch)
+		if n.ElseBranch != nil {
+			Walk(v, n.ElseBranch)
+		}
+	case *lox.WhileStmt:
+		Walk(v, n.Condition)
+		Walk(v, n.Body)
+	case *lox.ForInStmt:
+		Walk(v, n.Iterable)
+		Walk(v, n.Body)
+	case *lox.FunctionDeclStmt:
+		walkStmts(v, n.Body)
+	case *lox.ReturnStmt:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *lox.ThrowStmt:
+		Walk(v, n.Value)
+	case *lox.TryStmt:
+		walkStmts(v, n.Body)
+		walkStmts(v, n.CatchBody)
+		walkStmts(v, n.FinallyBody)
+	case *lox.ImportStmt:
+	case *lox.ExportStmt:
+		Walk(v, n.Declaration)
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkExprs(v Visitor, exprs []lox.Expr) {
+	for _, expr := range exprs {
+		Walk(v, expr)
+	}
+}
+
+func walkStmts(v Visitor, stmts []lox.Stmt) {
+	for _, stmt := range stmts {
+		Walk(v, stmt)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node like Walk, calling f for each
+// node. If f returns true, Inspect visits the node's children and then calls
+// f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
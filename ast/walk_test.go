@@ -0,0 +1,87 @@
+package ast
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"interpreter/lox"
+)
+
+func parse(t *testing.T, source string) []lox.Stmt {
+	t.Helper()
+
+	tokens, errs := lox.NewScanner(source).ScanTokens()
+	require.Empty(t, errs)
+	statements, err := lox.NewParser(tokens).Parse()
+	require.NoError(t, err)
+	return statements
+}
+
+func TestInspect(t *testing.T) {
+	statements := parse(t, `
+var a = 1;
+fun f(x) { return x + a; }
+for (var i = 0; i < 2; i = i + 1) print f(i) ? "y" : "n";
+try { throw a; } catch (e) { print e; } finally { a = -a; }`)
+
+	var names []string
+	for _, stmt := range statements {
+		Inspect(stmt, func(node Node) bool {
+			if v, ok := node.(*lox.VariableExpr); ok {
+				names = append(names, v.Name.Lexeme)
+			}
+			return true
+		})
+	}
+	assert.Equal(t, []string{"x", "a", "i", "f", "i", "i", "a", "e", "a"}, names)
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	statements := parse(t, `fun f() { print inner; } print outer;`)
+
+	var names []string
+	for _, stmt := range statements {
+		Inspect(stmt, func(node Node) bool {
+			if v, ok := node.(*lox.VariableExpr); ok {
+				names = append(names, v.Name.Lexeme)
+			}
+			_, isFunction := node.(*lox.FunctionDeclStmt)
+			return !isFunction
+		})
+	}
+	assert.Equal(t, []string{"outer"}, names)
+}
+
+// depthVisitor records each node it visits, indented by its depth, and the
+// end of each node's children.
+type depthVisitor struct {
+	depth int
+	trace *[]string
+}
+
+func (v depthVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		*v.trace = append(*v.trace, fmt.Sprintf("%*send", v.depth*2, ""))
+		return nil
+	}
+	*v.trace = append(*v.trace, fmt.Sprintf("%*s%T", v.depth*2, "", node))
+	return depthVisitor{depth: v.depth + 1, trace: v.trace}
+}
+
+func TestWalk(t *testing.T) {
+	statements := parse(t, `print -a;`)
+
+	var trace []string
+	Walk(depthVisitor{trace: &trace}, statements[0])
+	assert.Equal(t, []string{
+		"*lox.PrintStmt",
+		"  *lox.UnaryExpr",
+		"    *lox.VariableExpr",
+		"      end",
+		"    end",
+		"  end",
+	}, trace)
+}
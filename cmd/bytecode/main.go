@@ -1,20 +1,20 @@
 package main
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 
 	"interpreter/lox"
+	"interpreter/repl"
 )
 
 func main() {
 	argc := len(os.Args)
 
 	if argc == 1 {
-		repl()
+		runREPL()
 	} else if argc == 2 {
 		runFile(os.Args[1])
 	} else {
@@ -23,21 +23,32 @@ func main() {
 	}
 }
 
-func repl() {
-	scanner := bufio.NewScanner(os.Stdin)
-	for {
-		fmt.Print("> ")
+func runREPL() {
+	r := repl.New(vmSession{}, os.Stdin, os.Stdout, os.Stderr)
+	r.SetHistoryFile(repl.DefaultHistoryFile())
+	if err := r.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(74)
+	}
+}
+
+// vmSession evaluates REPL input on the VM. The VM has no globals yet, so
+// there is nothing to keep from one input to the next.
+type vmSession struct{}
 
-		if scanner.Scan() {
-			line := scanner.Text()
-			_ = lox.Interpret(line)
-		} else {
-			if errors.Is(scanner.Err(), io.EOF) {
-				break
-			}
-			panic(scanner.Err())
-		}
+func (vmSession) Eval(source string) error {
+	err := lox.Interpret(source)
+	// The VM has already reported these.
+	if errors.Is(err, lox.ErrInterpretCompile) || errors.Is(err, lox.ErrInterpretRuntime) {
+		return nil
 	}
+	return err
+}
+
+func (vmSession) Reset() {}
+
+func (vmSession) Globals() []string {
+	return nil
 }
 
 func runFile(path string) {
@@ -0,0 +1,17 @@
+// Command loxls is a language server for Lox. Editors start it and talk to it
+// over standard input and output.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"interpreter/lsp"
+)
+
+func main() {
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(74)
+	}
+}
@@ -1,26 +1,119 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
+	"interpreter/debug"
 	"interpreter/lox"
+	"interpreter/repl"
 )
 
 func main() {
-	if len(os.Args) < 3 {
+	if len(os.Args) < 2 {
 		fmt.Fprintln(os.Stderr, "Usage: ./your_program.sh tokenize <filename>")
 		os.Exit(1)
 	}
 
 	command := os.Args[1]
 
-	if command != "tokenize" && command != "parse" && command != "evaluate" && command != "run" {
+	if command != "tokenize" && command != "parse" && command != "evaluate" && command != "run" && command != "repl" && command != "debug" && command != "dap" && command != "fmt" && command != "lint" && command != "check" {
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		os.Exit(1)
 	}
 
-	filename := os.Args[2]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	allowRead := flags.String("allow-read", "", "let scripts read files under `dir`")
+	allowWrite := flags.String("allow-write", "", "let scripts write files under `dir`")
+	allowEnv := flags.Bool("allow-env", false, "let scripts read environment variables")
+	allowStdin := flags.Bool("allow-stdin", false, "let scripts read standard input")
+	timeout := flags.Duration("timeout", 0, "run: stop scripts that run longer than `duration`")
+	maxSteps := flags.Int("max-steps", 0, "stop scripts that execute more than `n` statements")
+	maxCallDepth := flags.Int("max-call-depth", lox.DefaultMaxCallDepth, "stop scripts whose calls nest deeper than `n`, or 0 for no limit")
+	maxAllocations := flags.Int("max-allocations", 0, "stop scripts that create more than `n` objects")
+	maxMemory := flags.Int("max-memory", 0, "stop scripts that allocate more than `bytes`")
+	check := flags.Bool("check", false, "fmt: list files whose formatting differs, and fail if there are any")
+	write := flags.Bool("write", false, "fmt: write the result back to each file instead of printing it")
+	outputFormat := flags.String("format", "text", "parse, lint: print the result as `text` or json")
+	enable := flags.String("enable", "", "lint: check only the comma-separated `rules`")
+	disable := flags.String("disable", "", "lint: skip the comma-separated `rules`")
+	_ = flags.Parse(os.Args[2:])
+
+	// The debugger and the repl wait on the user, so a deadline on the whole
+	// session would cut it off mid-use rather than stop a runaway script.
+	if *timeout > 0 && command != "run" {
+		fmt.Fprintf(os.Stderr, "The -timeout flag only applies to run, not %s.\n", command)
+		os.Exit(1)
+	}
+
+	options := []lox.Option{
+		lox.WithMaxSteps(*maxSteps),
+		lox.WithMaxCallDepth(*maxCallDepth),
+		lox.WithMaxAllocations(*maxAllocations),
+		lox.WithMaxMemory(*maxMemory),
+	}
+	capabilities := lox.Capabilities{
+		ReadRoot:  *allowRead,
+		WriteRoot: *allowWrite,
+		Env:       *allowEnv,
+		Stdin:     *allowStdin,
+	}
+
+	if command == "repl" {
+		session := lox.NewSession(options...)
+		session.Interpreter().SetArgs(flags.Args())
+		session.Interpreter().SetCapabilities(capabilities)
+
+		r := repl.New(session, os.Stdin, os.Stdout, os.Stderr)
+		r.SetHistoryFile(repl.DefaultHistoryFile())
+		err := r.Run()
+		var exitErr *lox.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(74)
+		}
+		return
+	}
+
+	if command == "dap" {
+		server := debug.NewDAPServer(os.Stdin, os.Stdout, options...)
+		server.SetCapabilities(capabilities)
+		if err := server.Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(74)
+		}
+		return
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags] <filename> [args...]\n", os.Args[0], command)
+		flags.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if command == "fmt" {
+		os.Exit(format(flags.Args(), *check, *write))
+	}
+
+	if command == "lint" {
+		linter, err := newLinter(*enable, *disable)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(lint(linter, flags.Args(), *outputFormat))
+	}
+
+	filename := flags.Arg(0)
 	fileContents, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
@@ -44,15 +137,7 @@ func main() {
 		}
 
 	case "parse":
-		parser := lox.NewParser(tokens)
-		expr, err := parser.ParseExpr()
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(65)
-		}
-
-		var printer lox.AstPrinter
-		printer.Print(expr)
+		os.Exit(parse(tokens, *outputFormat))
 
 	case "evaluate":
 		parser := lox.NewParser(tokens)
@@ -83,17 +168,240 @@ func main() {
 			os.Exit(65)
 		}
 
-		interpreter := lox.NewInterpreter()
+		interpreter := lox.NewInterpreter(options...)
+		interpreter.SetScriptPath(filename)
+		interpreter.SetArgs(flags.Args()[1:])
+		interpreter.SetCapabilities(capabilities)
 		resolver := lox.NewResolver(interpreter)
 
 		if err := resolver.Resolve(stmts); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(66)
 		}
+		stmts = lox.NewOptimizer().Optimize(stmts)
+
+		ctx := context.Background()
+		if *timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, *timeout)
+			defer cancel()
+		}
+
+		if err := interpreter.InterpretContext(ctx, stmts); err != nil {
+			var exitErr *lox.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.Code)
+			}
 
-		if err := interpreter.Interpret(stmts); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(70)
 		}
+
+	case "check":
+		parser := lox.NewParser(tokens)
+		stmts, err := parser.Parse()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(65)
+		}
+
+		if err := lox.NewResolver(lox.NewInterpreter()).Resolve(stmts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(66)
+		}
+
+		typeErrs := lox.NewTypeChecker().Check(stmts)
+		for _, err := range typeErrs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if len(typeErrs) > 0 {
+			os.Exit(65)
+		}
+
+	case "debug":
+		parser := lox.NewParser(tokens)
+		stmts, err := parser.Parse()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(65)
+		}
+
+		interpreter := lox.NewInterpreter(options...)
+		interpreter.SetScriptPath(filename)
+		interpreter.SetArgs(flags.Args()[1:])
+		interpreter.SetCapabilities(capabilities)
+		resolver := lox.NewResolver(interpreter)
+
+		if err := resolver.Resolve(stmts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(66)
+		}
+
+		cli := debug.NewCLI(filename, os.Stdin, os.Stdout)
+		if err := cli.Run(interpreter, stmts); err != nil {
+			var exitErr *lox.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.Code)
+			}
+
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(70)
+		}
+	}
+}
+
+// parse prints the syntax tree of a program, or of a file holding a single
+// expression, in outputFormat, and returns the exit status.
+func parse(tokens []lox.Token, outputFormat string) int {
+	if outputFormat != "text" && outputFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown format: %s\n", outputFormat)
+		return 1
+	}
+
+	var printer lox.AstPrinter
+	var data []byte
+	statements, err := lox.NewParser(tokens).Parse()
+	if err == nil {
+		if outputFormat == "text" {
+			printer.PrintProgram(statements)
+			return 0
+		}
+		data, err = lox.MarshalProgram(statements)
+	} else {
+		parser := lox.NewParser(tokens)
+		expr, exprErr := parser.ParseExpr()
+		if exprErr != nil || !parser.AtEnd() {
+			fmt.Fprintln(os.Stderr, err)
+			return 65
+		}
+		if outputFormat == "text" {
+			printer.Print(expr)
+			return 0
+		}
+		data, err = lox.MarshalExpr(expr)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 70
+	}
+
+	var indented bytes.Buffer
+	_ = json.Indent(&indented, data, "", "  ")
+	fmt.Println(indented.String())
+	return 0
+}
+
+// format formats each file, printing the result unless check or write is set,
+// and returns the exit status.
+func format(filenames []string, check, write bool) int {
+	status := 0
+	for _, filename := range filenames {
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+			return 1
+		}
+
+		formatted, err := lox.Format(string(source))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+			status = 65
+			continue
+		}
+
+		switch {
+		case check:
+			if formatted != string(source) {
+				fmt.Println(filename)
+				if status == 0 {
+					status = 1
+				}
+			}
+		case write:
+			if formatted != string(source) {
+				if err := os.WriteFile(filename, []byte(formatted), 0o644); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+					return 1
+				}
+			}
+		default:
+			fmt.Print(formatted)
+		}
+	}
+	return status
+}
+
+// newLinter returns a linter with the rules in enable, or all of them if it
+// is empty, less the rules in disable.
+func newLinter(enable, disable string) (*lox.Linter, error) {
+	linter := lox.NewLinter()
+	if enable != "" {
+		for _, rule := range lox.LintRules {
+			_ = linter.Disable(rule)
+		}
+		for _, rule := range strings.Split(enable, ",") {
+			if err := linter.Enable(strings.TrimSpace(rule)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if disable != "" {
+		for _, rule := range strings.Split(disable, ",") {
+			if err := linter.Disable(strings.TrimSpace(rule)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return linter, nil
+}
+
+// lintIssue is a lint issue as printed in JSON.
+type lintIssue struct {
+	File string `json:"file"`
+	lox.LintIssue
+}
+
+// lint checks each file, printing the issues in outputFormat, and returns the
+// exit status: 1 if there were issues, 65 if a file could not be checked.
+func lint(linter *lox.Linter, filenames []string, outputFormat string) int {
+	if outputFormat != "text" && outputFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown format: %s\n", outputFormat)
+		return 1
+	}
+
+	status := 0
+	issues := []lintIssue{}
+	for _, filename := range filenames {
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+			return 1
+		}
+
+		found, err := linter.Lint(string(source))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+			status = 65
+			continue
+		}
+
+		for _, issue := range found {
+			issues = append(issues, lintIssue{File: filename, LintIssue: issue})
+		}
+	}
+
+	if outputFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(issues)
+	} else {
+		for _, issue := range issues {
+			fmt.Printf("%s:%s\n", issue.File, issue.LintIssue)
+		}
+	}
+
+	if status == 0 && len(issues) > 0 {
+		status = 1
 	}
+	return status
 }
@@ -0,0 +1,201 @@
+package debug
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"interpreter/lox"
+)
+
+// ErrQuit stops a script when the user quits the debugger.
+var ErrQuit = errors.New("debugger quit")
+
+const cliHelp = `Commands:
+  break [file:]<line>   set a breakpoint (b)
+  delete [file:]<line>  remove a breakpoint (d)
+  breakpoints           list breakpoints
+  continue              run to the next breakpoint (c)
+  step                  step into calls (s)
+  next                  step over calls (n)
+  out                   step out of the current function (o)
+  print <expr>          evaluate an expression here (p)
+  locals                show local variables
+  backtrace             show the call stack (bt)
+  list                  show the source around this line (l)
+  quit                  stop the script (q)
+`
+
+// CLI is a command-line debugger. It stops before the first statement and
+// then reads commands each time the script pauses.
+type CLI struct {
+	path       string // the main script
+	in         *bufio.Scanner
+	out        io.Writer
+	controller *Controller
+	sources    map[string][]string // lines of each file, read when first shown
+}
+
+// NewCLI returns a debugger for the script at path, reading commands from in
+// and writing to out.
+func NewCLI(path string, in io.Reader, out io.Writer) *CLI {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	c := &CLI{
+		path:    path,
+		in:      bufio.NewScanner(in),
+		out:     out,
+		sources: make(map[string][]string),
+	}
+	c.controller = NewController(c.pause, true)
+	return c
+}
+
+// Run runs statements in interpreter under the debugger. Quitting the
+// debugger is not an error.
+func (c *CLI) Run(interpreter *lox.Interpreter, statements []lox.Stmt) error {
+	interpreter.SetDebugger(c.controller)
+	defer interpreter.SetDebugger(nil)
+
+	err := interpreter.Interpret(statements)
+	if errors.Is(err, ErrQuit) {
+		return nil
+	}
+	if err == nil {
+		fmt.Fprintln(c.out, "Script finished.")
+	}
+	return err
+}
+
+func (c *CLI) pause(state *lox.DebugState, reason string) (Mode, error) {
+	switch reason {
+	case ReasonBreakpoint:
+		fmt.Fprintf(c.out, "Breakpoint at %s:%d\n", filepath.Base(state.Path), state.Line)
+	default:
+		fmt.Fprintf(c.out, "Stopped at %s:%d\n", filepath.Base(state.Path), state.Line)
+	}
+	c.list(state.Path, state.Line, 0)
+
+	for {
+		fmt.Fprint(c.out, "(lox) ")
+		if !c.in.Scan() {
+			fmt.Fprintln(c.out)
+			return Continue, ErrQuit
+		}
+
+		command, arg, _ := strings.Cut(strings.TrimSpace(c.in.Text()), " ")
+		arg = strings.TrimSpace(arg)
+
+		switch command {
+		case "c", "continue":
+			return Continue, nil
+		case "s", "step":
+			return StepIn, nil
+		case "n", "next":
+			return StepOver, nil
+		case "o", "out", "finish":
+			return StepOut, nil
+		case "q", "quit":
+			return Continue, ErrQuit
+		case "b", "break":
+			if path, line, ok := c.location(state, arg); ok {
+				c.controller.SetBreakpoint(path, line)
+				fmt.Fprintf(c.out, "Breakpoint set at %s:%d\n", filepath.Base(path), line)
+			}
+		case "d", "delete":
+			if path, line, ok := c.location(state, arg); ok {
+				if !c.controller.ClearBreakpoint(path, line) {
+					fmt.Fprintf(c.out, "No breakpoint at %s:%d\n", filepath.Base(path), line)
+				}
+			}
+		case "breakpoints":
+			c.listBreakpoints()
+		case "p", "print":
+			value, err := state.Evaluate(arg)
+			if err != nil {
+				fmt.Fprintln(c.out, err)
+			} else {
+				fmt.Fprintln(c.out, lox.Stringify(value))
+			}
+		case "locals":
+			locals := state.Locals()
+			if len(locals) == 0 {
+				fmt.Fprintln(c.out, "No locals.")
+			}
+			for _, variable := range locals {
+				fmt.Fprintf(c.out, "%s = %s\n", variable.Name, lox.Stringify(variable.Value))
+			}
+		case "bt", "backtrace":
+			for idx, frame := range state.Backtrace() {
+				fmt.Fprintf(c.out, "#%d %s at %s:%d\n", idx, frame.Function, filepath.Base(frame.Path), frame.Line)
+			}
+		case "l", "list":
+			c.list(state.Path, state.Line, 5)
+		case "h", "help":
+			fmt.Fprint(c.out, cliHelp)
+		case "":
+		default:
+			fmt.Fprintf(c.out, "Unknown command '%s'. Type help for a list of commands.\n", command)
+		}
+	}
+}
+
+// location parses "line" or "file:line". A bare line is in the file where the
+// script is paused.
+func (c *CLI) location(state *lox.DebugState, arg string) (string, int, bool) {
+	path := state.Path
+	if file, line, ok := strings.Cut(arg, ":"); ok {
+		path = file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(c.path), path)
+		}
+		arg = line
+	}
+
+	line, err := strconv.Atoi(arg)
+	if err != nil || line < 1 {
+		fmt.Fprintln(c.out, "Expect a line number, optionally prefixed with 'file:'.")
+		return "", 0, false
+	}
+	return path, line, true
+}
+
+func (c *CLI) listBreakpoints() {
+	paths := c.controller.Files()
+	for _, path := range paths {
+		for _, line := range c.controller.Breakpoints(path) {
+			fmt.Fprintf(c.out, "%s:%d\n", filepath.Base(path), line)
+		}
+	}
+	if len(paths) == 0 {
+		fmt.Fprintln(c.out, "No breakpoints.")
+	}
+}
+
+// list shows the lines of the file at path within context lines of line,
+// marking line itself.
+func (c *CLI) list(path string, line, context int) {
+	lines, ok := c.sources[path]
+	if !ok {
+		source, err := os.ReadFile(path)
+		if err == nil {
+			lines = strings.Split(string(source), "\n")
+		}
+		c.sources[path] = lines
+	}
+
+	for n := max(line-context, 1); n <= min(line+context, len(lines)); n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(c.out, "%s %4d | %s\n", marker, n, lines[n-1])
+	}
+}
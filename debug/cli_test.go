@@ -0,0 +1,81 @@
+package debug
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"interpreter/lox"
+)
+
+func runCLI(t *testing.T, input string) (string, error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "main.lox")
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o644))
+
+	tokens, errs := lox.NewScanner(script).ScanTokens()
+	require.Empty(t, errs)
+	stmts, err := lox.NewParser(tokens).Parse()
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	interpreter := lox.NewInterpreter(lox.WithStdout(&out))
+	interpreter.SetScriptPath(path)
+	require.NoError(t, lox.NewResolver(interpreter).Resolve(stmts))
+
+	err = NewCLI(path, strings.NewReader(input), &out).Run(interpreter, stmts)
+	return out.String(), err
+}
+
+func TestCLI(t *testing.T) {
+	output, err := runCLI(t, strings.Join([]string{
+		"break 3",
+		"breakpoints",
+		"continue",
+		"locals",
+		"print sum * 10",
+		"print nope",
+		"backtrace",
+		"delete 3",
+		"next",
+		"frobnicate",
+		"c",
+	}, "\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, `Stopped at main.lox:1
+>    1 | fun add(a, b) {
+(lox) Breakpoint set at main.lox:3
+(lox) main.lox:3
+(lox) Breakpoint at main.lox:3
+>    3 |   return sum;
+(lox) a = 0
+b = 0
+sum = 0
+(lox) 0
+(lox) [line 1] Undefined variable 'nope'.
+(lox) #0 <fn add> at main.lox:3
+#1 <script> at main.lox:7
+(lox) (lox) Stopped at main.lox:6
+>    6 | for (var i = 0; i < 2; i = i + 1) {
+(lox) Unknown command 'frobnicate'. Type help for a list of commands.
+(lox) 1
+Script finished.
+`, output)
+}
+
+func TestCLIQuit(t *testing.T) {
+	output, err := runCLI(t, "step\nquit\n")
+	require.NoError(t, err)
+	assert.Equal(t, "Stopped at main.lox:1\n>    1 | fun add(a, b) {\n(lox) Stopped at main.lox:5\n>    5 | var total = 0;\n(lox) ", output)
+
+	output, err = runCLI(t, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Stopped at main.lox:1\n>    1 | fun add(a, b) {\n(lox) \n", output)
+}
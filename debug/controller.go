@@ -0,0 +1,196 @@
+// Package debug implements stepping and breakpoints on top of the
+// interpreter's debugger hook, and a command-line front end for them.
+package debug
+
+import (
+	"sort"
+	"sync"
+
+	"interpreter/lox"
+)
+
+// Mode says how a paused script should resume.
+type Mode int
+
+const (
+	Continue Mode = iota // run to the next breakpoint
+	StepIn               // stop at the next statement, entering calls
+	StepOver             // stop at the next statement in this function or its callers
+	StepOut              // stop after returning from this function
+)
+
+// Reasons passed to a PauseFunc.
+const (
+	ReasonEntry      = "entry"
+	ReasonStep       = "step"
+	ReasonBreakpoint = "breakpoint"
+	ReasonPause      = "pause"
+)
+
+// PauseFunc is called when the script pauses and returns how to resume. It
+// runs on the interpreter's goroutine, which stays blocked until it returns.
+// Returning an error stops the script.
+type PauseFunc func(state *lox.DebugState, reason string) (Mode, error)
+
+// Controller is a lox.Debugger that pauses the script at breakpoints and
+// after steps. Breakpoints may be changed from another goroutine while the
+// script runs.
+type Controller struct {
+	pause PauseFunc
+
+	mu          sync.Mutex
+	breakpoints map[string]map[int]bool // path to lines
+	pausing     bool                    // whether Pause was called while running
+
+	mode     Mode
+	entry    bool // whether the next statement is the first
+	stopPath string
+	stopLine int
+	depth    int // depth of the last stop
+
+	// Where the previous statement was, to notice when a breakpoint's line
+	// is entered rather than still running.
+	lastPath  string
+	lastLine  int
+	lastDepth int
+}
+
+var _ lox.Debugger = (*Controller)(nil)
+
+// NewController returns a controller that calls pause whenever the script
+// stops. With stopOnEntry it stops before the first statement.
+func NewController(pause PauseFunc, stopOnEntry bool) *Controller {
+	return &Controller{
+		pause:       pause,
+		breakpoints: make(map[string]map[int]bool),
+		entry:       stopOnEntry,
+	}
+}
+
+// SetBreakpoint adds a breakpoint on a line of the file at path.
+func (c *Controller) SetBreakpoint(path string, line int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.breakpoints[path] == nil {
+		c.breakpoints[path] = make(map[int]bool)
+	}
+	c.breakpoints[path][line] = true
+}
+
+// ClearBreakpoint removes a breakpoint and reports whether there was one.
+func (c *Controller) ClearBreakpoint(path string, line int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.breakpoints[path][line] {
+		return false
+	}
+	delete(c.breakpoints[path], line)
+	return true
+}
+
+// ClearBreakpoints removes every breakpoint in the file at path.
+func (c *Controller) ClearBreakpoints(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.breakpoints, path)
+}
+
+// Breakpoints returns the lines with breakpoints in the file at path, in
+// order.
+func (c *Controller) Breakpoints(path string) []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var lines []int
+	for line := range c.breakpoints[path] {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Pause asks a running script to stop at its next statement.
+func (c *Controller) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pausing = true
+}
+
+// Files returns the paths of the files with breakpoints, in order.
+func (c *Controller) Files() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var paths []string
+	for path, lines := range c.breakpoints {
+		if len(lines) > 0 {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (c *Controller) Statement(state *lox.DebugState) error {
+	reason := c.stopReason(state)
+	c.lastPath, c.lastLine, c.lastDepth = state.Path, state.Line, state.Depth()
+	if reason == "" {
+		return nil
+	}
+
+	mode, err := c.pause(state, reason)
+	if err != nil {
+		return err
+	}
+
+	c.mode = mode
+	c.stopPath, c.stopLine = state.Path, state.Line
+	c.depth = state.Depth()
+	return nil
+}
+
+func (c *Controller) stopReason(state *lox.DebugState) string {
+	if c.entry {
+		c.entry = false
+		return ReasonEntry
+	}
+
+	c.mu.Lock()
+	pausing := c.pausing
+	c.pausing = false
+	c.mu.Unlock()
+	if pausing {
+		return ReasonPause
+	}
+
+	depth := state.Depth()
+	moved := state.Path != c.stopPath || state.Line != c.stopLine
+
+	switch c.mode {
+	case StepIn:
+		if moved || depth != c.depth {
+			return ReasonStep
+		}
+	case StepOver:
+		if depth < c.depth || (depth == c.depth && moved) {
+			return ReasonStep
+		}
+	case StepOut:
+		if depth < c.depth {
+			return ReasonStep
+		}
+	}
+
+	entered := state.Path != c.lastPath || state.Line != c.lastLine || depth != c.lastDepth
+	c.mu.Lock()
+	onBreakpoint := c.breakpoints[state.Path][state.Line]
+	c.mu.Unlock()
+	if onBreakpoint && entered {
+		return ReasonBreakpoint
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+package debug
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"interpreter/lox"
+)
+
+const script = `fun add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+var total = 0;
+for (var i = 0; i < 2; i = i + 1) {
+  total = add(total, i);
+}
+print total;
+`
+
+// runScript runs script under a controller whose pause function resumes
+// with each of modes in turn, and returns where it stopped.
+func runScript(t *testing.T, breakpoints []int, stopOnEntry bool, modes ...Mode) []string {
+	t.Helper()
+
+	var stops []string
+	controller := NewController(func(state *lox.DebugState, reason string) (Mode, error) {
+		stops = append(stops, fmt.Sprintf("%s:%d", reason, state.Line))
+		if len(modes) == 0 {
+			return Continue, nil
+		}
+		mode := modes[0]
+		modes = modes[1:]
+		return mode, nil
+	}, stopOnEntry)
+	for _, line := range breakpoints {
+		controller.SetBreakpoint("", line)
+	}
+
+	tokens, errs := lox.NewScanner(script).ScanTokens()
+	require.Empty(t, errs)
+	stmts, err := lox.NewParser(tokens).Parse()
+	require.NoError(t, err)
+
+	interpreter := lox.NewInterpreter(lox.WithStdout(&bytes.Buffer{}))
+	require.NoError(t, lox.NewResolver(interpreter).Resolve(stmts))
+	interpreter.SetDebugger(controller)
+	require.NoError(t, interpreter.Interpret(stmts))
+	return stops
+}
+
+func TestControllerBreakpoints(t *testing.T) {
+	assert.Equal(t, []string{"breakpoint:2", "breakpoint:2", "breakpoint:9"}, runScript(t, []int{2, 9}, false))
+	assert.Equal(t, []string{"entry:1", "breakpoint:9"}, runScript(t, []int{9}, true))
+}
+
+func TestControllerStepping(t *testing.T) {
+	assert.Equal(t,
+		[]string{"entry:1", "step:5", "step:6", "step:7", "step:2", "step:3", "step:6"},
+		runScript(t, nil, true, StepIn, StepIn, StepIn, StepIn, StepIn, StepIn))
+
+	assert.Equal(t,
+		[]string{"entry:1", "step:5", "step:6", "step:7", "step:6", "step:7", "step:6"},
+		runScript(t, nil, true, StepOver, StepOver, StepOver, StepOver, StepOver, StepOver))
+
+	assert.Equal(t,
+		[]string{"breakpoint:2", "step:6", "breakpoint:2", "step:6"},
+		runScript(t, []int{2}, false, StepOut, Continue, StepOut))
+}
+
+func TestControllerClearBreakpoints(t *testing.T) {
+	controller := NewController(nil, false)
+	controller.SetBreakpoint("b.lox", 3)
+	controller.SetBreakpoint("a.lox", 7)
+	controller.SetBreakpoint("a.lox", 2)
+
+	assert.Equal(t, []string{"a.lox", "b.lox"}, controller.Files())
+	assert.Equal(t, []int{2, 7}, controller.Breakpoints("a.lox"))
+	assert.True(t, controller.ClearBreakpoint("a.lox", 2))
+	assert.False(t, controller.ClearBreakpoint("a.lox", 2))
+
+	controller.ClearBreakpoints("b.lox")
+	assert.Equal(t, []string{"a.lox"}, controller.Files())
+}
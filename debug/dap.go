@@ -0,0 +1,589 @@
+package debug
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"interpreter/lox"
+)
+
+// threadID is the only thread a Lox script has.
+const threadID = 1
+
+// DAPServer speaks the Debug Adapter Protocol, so that editors such as VS
+// Code can debug Lox scripts. It serves a single debug session: the client
+// launches a script, which then runs under a Controller until it finishes or
+// the client disconnects.
+type DAPServer struct {
+	in      *bufio.Reader
+	out     io.Writer
+	options []lox.Option
+
+	capabilities lox.Capabilities
+
+	writeMu sync.Mutex // guards out and seq
+	seq     int
+
+	controller  *Controller
+	interpreter *lox.Interpreter
+	statements  []lox.Stmt
+	path        string
+	cancel      context.CancelFunc
+	done        chan struct{} // closed when the script finishes
+
+	// While the script is paused, requests that inspect it are run on the
+	// script's goroutine by sending them to actions.
+	mu      sync.Mutex
+	paused  bool
+	actions chan dapAction
+	quit    chan struct{} // closed to stop the script
+
+	resuming *dapAction // sent after the response to a step is written
+
+	// Variable references handed out since the script last paused, indexed
+	// by reference minus one. They are only valid until it resumes.
+	references []any
+}
+
+// dapAction is either an inspection to run while paused or a way to resume.
+type dapAction struct {
+	inspect func(state *lox.DebugState)
+	done    chan struct{}
+
+	mode Mode
+	err  error
+}
+
+// dapScope is the variables reference of a frame's locals or globals.
+type dapScope struct {
+	globals bool
+}
+
+// NewDAPServer returns a server that reads requests from in and writes
+// responses and events to out. Scripts are run with the given options, but
+// their output is sent to the client instead of to standard output.
+func NewDAPServer(in io.Reader, out io.Writer, options ...lox.Option) *DAPServer {
+	return &DAPServer{
+		in:      bufio.NewReader(in),
+		out:     out,
+		options: options,
+		actions: make(chan dapAction),
+		quit:    make(chan struct{}),
+	}
+}
+
+// SetCapabilities sets what launched scripts may do beyond printing.
+func (s *DAPServer) SetCapabilities(capabilities lox.Capabilities) {
+	s.capabilities = capabilities
+}
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type dapResponse struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+// Serve handles requests until the client disconnects or closes the input.
+func (s *DAPServer) Serve() error {
+	defer s.stop()
+
+	for {
+		request, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		body, err := s.handle(request)
+		response := dapResponse{
+			Type:       "response",
+			RequestSeq: request.Seq,
+			Success:    err == nil,
+			Command:    request.Command,
+			Body:       body,
+		}
+		if err != nil {
+			response.Message = err.Error()
+		}
+		if err := s.send(&response); err != nil {
+			return err
+		}
+
+		// The client expects to hear that a step succeeded before it hears
+		// that the script stopped again.
+		if action := s.resuming; action != nil {
+			s.resuming = nil
+			s.actions <- *action
+		}
+
+		switch request.Command {
+		case "initialize":
+			if err := s.event("initialized", nil); err != nil {
+				return err
+			}
+		case "configurationDone":
+			s.start()
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+func (s *DAPServer) read() (*dapRequest, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(s.in, content); err != nil {
+		return nil, err
+	}
+
+	var request dapRequest
+	if err := json.Unmarshal(content, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// send writes a response or event, numbering it.
+func (s *DAPServer) send(message any) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.seq++
+	switch message := message.(type) {
+	case *dapResponse:
+		message.Seq = s.seq
+	case *dapEvent:
+		message.Seq = s.seq
+	}
+
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	return err
+}
+
+func (s *DAPServer) event(name string, body any) error {
+	return s.send(&dapEvent{Type: "event", Event: name, Body: body})
+}
+
+func (s *DAPServer) handle(request *dapRequest) (any, error) {
+	switch request.Command {
+	case "initialize":
+		return map[string]any{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+			"supportsTerminateRequest":         true,
+		}, nil
+
+	case "launch":
+		var args struct {
+			Program     string   `json:"program"`
+			Args        []string `json:"args"`
+			StopOnEntry bool     `json:"stopOnEntry"`
+		}
+		if err := json.Unmarshal(request.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.launch(args.Program, args.Args, args.StopOnEntry)
+
+	case "setBreakpoints":
+		var args struct {
+			Source struct {
+				Path string `json:"path"`
+			} `json:"source"`
+			Breakpoints []struct {
+				Line int `json:"line"`
+			} `json:"breakpoints"`
+		}
+		if err := json.Unmarshal(request.Arguments, &args); err != nil {
+			return nil, err
+		}
+		if s.controller == nil {
+			return nil, errors.New("Launch a script before setting breakpoints.")
+		}
+
+		path := absPath(args.Source.Path)
+		s.controller.ClearBreakpoints(path)
+		breakpoints := make([]map[string]any, len(args.Breakpoints))
+		for idx, breakpoint := range args.Breakpoints {
+			s.controller.SetBreakpoint(path, breakpoint.Line)
+			breakpoints[idx] = map[string]any{"verified": true, "line": breakpoint.Line}
+		}
+		return map[string]any{"breakpoints": breakpoints}, nil
+
+	case "configurationDone":
+		return nil, nil
+	case "disconnect", "terminate":
+		s.stop()
+		return nil, nil
+
+	case "threads":
+		return map[string]any{
+			"threads": []map[string]any{{"id": threadID, "name": "main"}},
+		}, nil
+
+	case "continue":
+		return map[string]any{"allThreadsContinued": true}, s.resume(Continue)
+	case "next":
+		return nil, s.resume(StepOver)
+	case "stepIn":
+		return nil, s.resume(StepIn)
+	case "stepOut":
+		return nil, s.resume(StepOut)
+	case "pause":
+		if s.controller == nil {
+			return nil, errors.New("No script is running.")
+		}
+		s.controller.Pause()
+		return nil, nil
+
+	case "stackTrace":
+		return s.stackTrace()
+
+	case "scopes":
+		var args struct {
+			FrameID int `json:"frameId"`
+		}
+		if err := json.Unmarshal(request.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.scopes(args.FrameID)
+
+	case "variables":
+		var args struct {
+			VariablesReference int `json:"variablesReference"`
+		}
+		if err := json.Unmarshal(request.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.variables(args.VariablesReference)
+
+	case "evaluate":
+		var args struct {
+			Expression string `json:"expression"`
+		}
+		if err := json.Unmarshal(request.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.evaluate(args.Expression)
+	}
+
+	return nil, fmt.Errorf("Unsupported request '%s'.", request.Command)
+}
+
+// launch loads the script at path. It does not run until the client has
+// finished configuring breakpoints.
+func (s *DAPServer) launch(path string, args []string, stopOnEntry bool) error {
+	if s.controller != nil {
+		return errors.New("A script has already been launched.")
+	}
+
+	path = absPath(path)
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	tokens, errs := lox.NewScanner(string(source)).ScanTokens()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	statements, err := lox.NewParser(tokens).Parse()
+	if err != nil {
+		return err
+	}
+
+	options := append(s.options[:len(s.options):len(s.options)], lox.WithStdout(&dapOutput{s, "stdout"}))
+	interpreter := lox.NewInterpreter(options...)
+	interpreter.SetScriptPath(path)
+	interpreter.SetArgs(args)
+	interpreter.SetCapabilities(s.capabilities)
+	if err := lox.NewResolver(interpreter).Resolve(statements); err != nil {
+		return err
+	}
+
+	s.controller = NewController(s.pause, stopOnEntry)
+	s.interpreter = interpreter
+	s.statements = statements
+	s.path = path
+	interpreter.SetDebugger(s.controller)
+	return nil
+}
+
+// start runs the launched script in the background.
+func (s *DAPServer) start() {
+	if s.interpreter == nil || s.done != nil {
+		return
+	}
+
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		err := s.interpreter.InterpretContext(ctx, s.statements)
+		code := 0
+		var exitErr *lox.ExitError
+		switch {
+		case err == nil, errors.Is(err, ErrQuit), errors.Is(err, context.Canceled):
+		case errors.As(err, &exitErr):
+			code = exitErr.Code
+		default:
+			code = 70
+			_, _ = (&dapOutput{s, "stderr"}).Write([]byte(err.Error() + "\n"))
+		}
+
+		_ = s.event("exited", map[string]any{"exitCode": code})
+		_ = s.event("terminated", nil)
+	}()
+}
+
+// stop ends the script, whether it is paused or running, and waits for it.
+func (s *DAPServer) stop() {
+	if s.done == nil {
+		return
+	}
+
+	s.cancel()
+	close(s.quit)
+	<-s.done
+	s.done = nil
+}
+
+// pause is the Controller's PauseFunc. It reports the stop to the client and
+// then serves inspections until it is told to resume.
+func (s *DAPServer) pause(state *lox.DebugState, reason string) (Mode, error) {
+	s.mu.Lock()
+	s.paused = true
+	s.mu.Unlock()
+
+	if err := s.event("stopped", map[string]any{
+		"reason":            reason,
+		"threadId":          threadID,
+		"allThreadsStopped": true,
+	}); err != nil {
+		return Continue, err
+	}
+
+	for {
+		select {
+		case action := <-s.actions:
+			if action.inspect == nil {
+				return action.mode, action.err
+			}
+			action.inspect(state)
+			close(action.done)
+		case <-s.quit:
+			return Continue, ErrQuit
+		}
+	}
+}
+
+func (s *DAPServer) resume(mode Mode) error {
+	s.mu.Lock()
+	paused := s.paused
+	s.paused = false
+	s.mu.Unlock()
+	if !paused {
+		return errors.New("The script is not paused.")
+	}
+
+	s.references = nil
+	s.resuming = &dapAction{mode: mode}
+	return nil
+}
+
+// inspect runs fn on the script's goroutine while it is paused.
+func (s *DAPServer) inspect(fn func(state *lox.DebugState)) error {
+	s.mu.Lock()
+	paused := s.paused
+	s.mu.Unlock()
+	if !paused {
+		return errors.New("The script is not paused.")
+	}
+
+	done := make(chan struct{})
+	s.actions <- dapAction{inspect: fn, done: done}
+	<-done
+	return nil
+}
+
+func (s *DAPServer) stackTrace() (any, error) {
+	var frames []map[string]any
+	err := s.inspect(func(state *lox.DebugState) {
+		for idx, frame := range state.Backtrace() {
+			path := frame.Path
+			if path == "" {
+				path = s.path
+			}
+			frames = append(frames, map[string]any{
+				"id":     idx,
+				"name":   frame.Function,
+				"line":   frame.Line,
+				"column": 1,
+				"source": map[string]any{"name": filepath.Base(path), "path": path},
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+// scopes returns the scopes of a frame. Only the innermost frame's
+// environment is reachable, so outer frames show just the globals.
+func (s *DAPServer) scopes(frameID int) (any, error) {
+	if err := s.inspect(func(*lox.DebugState) {}); err != nil {
+		return nil, err
+	}
+
+	var scopes []map[string]any
+	if frameID == 0 {
+		scopes = append(scopes, map[string]any{
+			"name":               "Locals",
+			"presentationHint":   "locals",
+			"variablesReference": s.reference(dapScope{}),
+		})
+	}
+	scopes = append(scopes, map[string]any{
+		"name":               "Globals",
+		"variablesReference": s.reference(dapScope{globals: true}),
+	})
+	return map[string]any{"scopes": scopes}, nil
+}
+
+func (s *DAPServer) variables(reference int) (any, error) {
+	if reference < 1 || reference > len(s.references) {
+		return nil, fmt.Errorf("Unknown variables reference %d.", reference)
+	}
+
+	var result []map[string]any
+	err := s.inspect(func(state *lox.DebugState) {
+		var variables []lox.DebugVariable
+		switch target := s.references[reference-1].(type) {
+		case dapScope:
+			if target.globals {
+				variables = state.Globals()
+			} else {
+				variables = state.Locals()
+			}
+		default:
+			variables = lox.DebugMembers(target)
+		}
+
+		result = make([]map[string]any, len(variables))
+		for idx, variable := range variables {
+			result[idx] = map[string]any{
+				"name":               variable.Name,
+				"value":              lox.Stringify(variable.Value),
+				"variablesReference": s.valueReference(variable.Value),
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"variables": result}, nil
+}
+
+func (s *DAPServer) evaluate(expression string) (any, error) {
+	var result map[string]any
+	var evalErr error
+	err := s.inspect(func(state *lox.DebugState) {
+		var value any
+		value, evalErr = state.Evaluate(expression)
+		result = map[string]any{
+			"result":             lox.Stringify(value),
+			"variablesReference": s.valueReference(value),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if evalErr != nil {
+		return nil, evalErr
+	}
+	return result, nil
+}
+
+// reference returns a new variables reference for target.
+func (s *DAPServer) reference(target any) int {
+	s.references = append(s.references, target)
+	return len(s.references)
+}
+
+// valueReference returns a reference to a value's members, or 0 if it has
+// none to expand.
+func (s *DAPServer) valueReference(value any) int {
+	if len(lox.DebugMembers(value)) == 0 {
+		return 0
+	}
+	return s.reference(value)
+}
+
+// dapOutput sends what the script writes to the client as output events.
+type dapOutput struct {
+	server   *DAPServer
+	category string
+}
+
+func (o *dapOutput) Write(p []byte) (int, error) {
+	err := o.server.event("output", map[string]any{"category": o.category, "output": string(p)})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(strings.TrimSpace(path))
+}
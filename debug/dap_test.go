@@ -0,0 +1,182 @@
+package debug
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dapClient drives a DAPServer the way an editor would.
+type dapClient struct {
+	t      *testing.T
+	in     io.Writer
+	out    *bufio.Reader
+	seq    int
+	output string // collected output events
+}
+
+type dapMessage struct {
+	Type    string         `json:"type"`
+	Command string         `json:"command"`
+	Event   string         `json:"event"`
+	Success bool           `json:"success"`
+	Message string         `json:"message"`
+	Body    map[string]any `json:"body"`
+}
+
+func newDAPClient(t *testing.T) *dapClient {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	done := make(chan error)
+	go func() {
+		done <- NewDAPServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+	}()
+	t.Cleanup(func() {
+		clientOut.Close()
+		assert.NoError(t, <-done)
+	})
+
+	return &dapClient{t: t, in: clientOut, out: bufio.NewReader(clientIn)}
+}
+
+// request sends a request and returns its response, failing the test if it
+// was not successful.
+func (c *dapClient) request(command string, arguments any) map[string]any {
+	c.t.Helper()
+
+	response := c.send(command, arguments)
+	require.True(c.t, response.Success, response.Message)
+	return response.Body
+}
+
+func (c *dapClient) send(command string, arguments any) dapMessage {
+	c.t.Helper()
+
+	c.seq++
+	content, err := json.Marshal(map[string]any{
+		"seq":       c.seq,
+		"type":      "request",
+		"command":   command,
+		"arguments": arguments,
+	})
+	require.NoError(c.t, err)
+	_, err = fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	require.NoError(c.t, err)
+
+	return c.await("response", command)
+}
+
+// await reads messages until one of the given type and name arrives,
+// collecting output events on the way.
+func (c *dapClient) await(kind, name string) dapMessage {
+	c.t.Helper()
+
+	for {
+		header, err := textproto.NewReader(c.out).ReadMIMEHeader()
+		require.NoError(c.t, err)
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		require.NoError(c.t, err)
+		content := make([]byte, length)
+		_, err = io.ReadFull(c.out, content)
+		require.NoError(c.t, err)
+
+		var message dapMessage
+		require.NoError(c.t, json.Unmarshal(content, &message))
+		if message.Event == "output" {
+			c.output += message.Body["output"].(string)
+		}
+		if message.Type == kind && (message.Command == name || message.Event == name) {
+			return message
+		}
+	}
+}
+
+func TestDAPSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.lox")
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o644))
+
+	client := newDAPClient(t)
+	capabilities := client.request("initialize", map[string]any{"adapterID": "lox"})
+	assert.Equal(t, true, capabilities["supportsConfigurationDoneRequest"])
+	client.await("event", "initialized")
+
+	client.request("launch", map[string]any{"program": path})
+	breakpoints := client.request("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": path},
+		"breakpoints": []map[string]any{{"line": 3}},
+	})
+	assert.Len(t, breakpoints["breakpoints"], 1)
+	client.request("configurationDone", nil)
+
+	stopped := client.await("event", "stopped")
+	assert.Equal(t, "breakpoint", stopped.Body["reason"])
+
+	trace := client.request("stackTrace", map[string]any{"threadId": threadID})
+	frames := trace["stackFrames"].([]any)
+	require.Len(t, frames, 2)
+	assert.Equal(t, "<fn add>", frames[0].(map[string]any)["name"])
+	assert.Equal(t, 3.0, frames[0].(map[string]any)["line"])
+	assert.Equal(t, 7.0, frames[1].(map[string]any)["line"])
+
+	scopes := client.request("scopes", map[string]any{"frameId": 0})["scopes"].([]any)
+	require.Len(t, scopes, 2)
+	locals := scopes[0].(map[string]any)
+	assert.Equal(t, "Locals", locals["name"])
+
+	variables := client.request("variables", map[string]any{"variablesReference": locals["variablesReference"]})
+	assert.Equal(t, []any{
+		map[string]any{"name": "a", "value": "0", "variablesReference": 0.0},
+		map[string]any{"name": "b", "value": "0", "variablesReference": 0.0},
+		map[string]any{"name": "sum", "value": "0", "variablesReference": 0.0},
+	}, variables["variables"])
+
+	result := client.request("evaluate", map[string]any{"expression": "sum + 40 + total"})
+	assert.Equal(t, "40", result["result"])
+	failed := client.send("evaluate", map[string]any{"expression": "nope"})
+	assert.False(t, failed.Success)
+	assert.Equal(t, "[line 1] Undefined variable 'nope'.", failed.Message)
+
+	client.request("next", map[string]any{"threadId": threadID})
+	client.await("event", "stopped")
+	trace = client.request("stackTrace", map[string]any{"threadId": threadID})
+	assert.Equal(t, 6.0, trace["stackFrames"].([]any)[0].(map[string]any)["line"])
+
+	client.request("setBreakpoints", map[string]any{"source": map[string]any{"path": path}, "breakpoints": []any{}})
+	client.request("continue", map[string]any{"threadId": threadID})
+	exited := client.await("event", "exited")
+	assert.Equal(t, 0.0, exited.Body["exitCode"])
+	client.await("event", "terminated")
+	assert.Equal(t, "1\n", client.output)
+
+	client.request("disconnect", nil)
+}
+
+func TestDAPDisconnectWhilePaused(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.lox")
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o644))
+
+	client := newDAPClient(t)
+	client.request("initialize", nil)
+	client.await("event", "initialized")
+	client.request("launch", map[string]any{"program": path, "stopOnEntry": true})
+	client.request("configurationDone", nil)
+
+	stopped := client.await("event", "stopped")
+	assert.Equal(t, "entry", stopped.Body["reason"])
+
+	failed := client.send("launch", map[string]any{"program": path})
+	assert.False(t, failed.Success)
+
+	client.request("disconnect", nil)
+}
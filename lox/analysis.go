@@ -0,0 +1,216 @@
+package lox
+
+import (
+	"errors"
+	"sort"
+)
+
+// SymbolKind says what declared a symbol.
+type SymbolKind int
+
+const (
+	SymbolVariable SymbolKind = iota
+	SymbolFunction
+	SymbolParameter
+	SymbolImport
+)
+
+// Symbol is a name declared in a script: a variable, function, parameter or
+// imported name.
+type Symbol struct {
+	Name Token
+	Kind SymbolKind
+
+	// Parameters holds the parameters of a function, which includes a
+	// variable initialized with a function expression.
+	Parameters []Token
+
+	Global    bool
+	Container *Symbol // the function declaring the symbol, if any
+
+	// References holds every other mention of the symbol, reads and
+	// assignments alike, in source order. A global declared again counts as
+	// a mention of the first declaration.
+	References []Token
+
+	scope map[string]*Symbol // the names declared alongside the symbol
+}
+
+// Sibling returns the symbol named name that is declared in the same scope as
+// s, if there is one other than s itself. Renaming s to name would clash.
+func (s *Symbol) Sibling(name string) *Symbol {
+	if other := s.scope[name]; other != s {
+		return other
+	}
+	return nil
+}
+
+// Diagnostic is an error found without running the script.
+type Diagnostic struct {
+	Line    int
+	Column  int
+	Length  int // in bytes; 0 at the end of the source
+	Message string
+}
+
+// Analysis is what can be learned about a script without running it, for
+// tools such as the language server. It is built even for code with errors.
+type Analysis struct {
+	Statements  []Stmt
+	Symbols     []*Symbol // in the order they are declared
+	Diagnostics []Diagnostic
+
+	// Resolution state. scopes mirrors the resolver's scopes, and a use of a
+	// name that is not in any of them waits in globalUses until every global
+	// has been declared.
+	scopes     []map[string]*Symbol
+	globals    map[string]*Symbol
+	globalUses []Token
+	container  *Symbol
+}
+
+// Analyze scans, parses and resolves source, carrying on past errors.
+func Analyze(source string) *Analysis {
+	a := &Analysis{globals: make(map[string]*Symbol)}
+
+	scanned, errs := NewScanner(source).ScanTokens()
+	var tokens []Token
+	for _, token := range scanned {
+		// The scanner leaves an empty token wherever it failed.
+		if token.Lexeme != "" || token.Type == EOF {
+			tokens = append(tokens, token)
+		}
+	}
+
+	statements, parseErrs := NewParser(tokens).ParseTolerant()
+	errs = append(errs, parseErrs...)
+	a.Statements = statements
+
+	resolver := newResolver(make(map[Expr]int))
+	resolver.analysis = a
+	_ = resolver.Resolve(statements)
+	errs = append(errs, resolver.errs...)
+	errs = append(errs, NewTypeChecker().Check(statements)...)
+
+	for _, name := range a.globalUses {
+		if symbol, ok := a.globals[name.Lexeme]; ok {
+			symbol.References = append(symbol.References, name)
+		}
+	}
+	for _, symbol := range a.Symbols {
+		symbol.References = sortMentions(symbol.References)
+	}
+
+	for _, err := range errs {
+		a.Diagnostics = append(a.Diagnostics, diagnose(err))
+	}
+	return a
+}
+
+// SymbolAt returns the symbol declared or used at a line and column, if any.
+func (a *Analysis) SymbolAt(line, column int) *Symbol {
+	for _, symbol := range a.Symbols {
+		if covers(symbol.Name, line, column) {
+			return symbol
+		}
+		for _, reference := range symbol.References {
+			if covers(reference, line, column) {
+				return symbol
+			}
+		}
+	}
+	return nil
+}
+
+func covers(token Token, line, column int) bool {
+	return token.Line == line && column >= token.Column && column <= token.Column+len(token.Lexeme)
+}
+
+func (a *Analysis) beginScope() {
+	a.scopes = append(a.scopes, make(map[string]*Symbol))
+}
+
+func (a *Analysis) endScope() {
+	a.scopes = a.scopes[:len(a.scopes)-1]
+}
+
+// declare records a symbol in the innermost scope, or as a global. A global
+// declared twice keeps its first declaration.
+func (a *Analysis) declare(name Token) {
+	symbol := &Symbol{Name: name, Container: a.container}
+	if len(a.scopes) == 0 {
+		symbol.Global = true
+		if first, ok := a.globals[name.Lexeme]; ok {
+			first.References = append(first.References, name)
+			return
+		}
+		a.globals[name.Lexeme] = symbol
+		symbol.scope = a.globals
+	} else {
+		symbol.scope = a.scopes[len(a.scopes)-1]
+		symbol.scope[name.Lexeme] = symbol
+	}
+	a.Symbols = append(a.Symbols, symbol)
+}
+
+// describe sets the kind of the symbol just declared for name.
+func (a *Analysis) describe(name Token, kind SymbolKind, parameters []Token) {
+	symbol := a.lookUp(name, len(a.scopes)-1)
+	if symbol != nil && symbol.Name == name {
+		symbol.Kind = kind
+		symbol.Parameters = parameters
+	}
+}
+
+// use records a use of name, which the resolver found in the scope at index
+// scope, or -1 for a global.
+func (a *Analysis) use(name Token, scope int) {
+	if scope < 0 {
+		a.globalUses = append(a.globalUses, name)
+		return
+	}
+
+	if symbol := a.scopes[scope][name.Lexeme]; symbol != nil {
+		symbol.References = append(symbol.References, name)
+	}
+}
+
+func (a *Analysis) lookUp(name Token, scope int) *Symbol {
+	if scope < 0 {
+		return a.globals[name.Lexeme]
+	}
+	return a.scopes[scope][name.Lexeme]
+}
+
+func diagnose(err error) Diagnostic {
+	var scanErr *ScanError
+	var parseErr *ParseError
+	var typeErr *TypeError
+	var runtimeErr *RuntimeError
+
+	switch {
+	case errors.As(err, &scanErr):
+		return Diagnostic{Line: scanErr.Line, Column: scanErr.Column, Length: 1, Message: scanErr.message}
+	case errors.As(err, &parseErr):
+		return tokenDiagnostic(parseErr.token, parseErr.message)
+	case errors.As(err, &typeErr):
+		return Diagnostic{Line: typeErr.Line, Column: typeErr.Column, Length: typeErr.Length, Message: typeErr.message}
+	case errors.As(err, &runtimeErr):
+		return tokenDiagnostic(runtimeErr.token, runtimeErr.message)
+	}
+	return Diagnostic{Line: 1, Column: 1, Message: err.Error()}
+}
+
+func tokenDiagnostic(token Token, message string) Diagnostic {
+	return Diagnostic{Line: token.Line, Column: token.Column, Length: len(token.Lexeme), Message: message}
+}
+
+// sortMentions sorts tokens into source order.
+func sortMentions(tokens []Token) []Token {
+	sort.Slice(tokens, func(i, j int) bool { return before(tokens[i], tokens[j]) })
+	return tokens
+}
+
+func before(a, b Token) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
@@ -0,0 +1,97 @@
+package lox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeSymbols(t *testing.T) {
+	analysis := Analyze(`var total = 0;
+fun add(a, b) {
+  var sum = a + b;
+  total += sum;
+  return sum;
+}
+var twice = fun (x) { return add(x, x); };
+print add(1, 2) + twice(total);
+`)
+	require.Empty(t, analysis.Diagnostics)
+
+	names := make(map[string]*Symbol)
+	for _, symbol := range analysis.Symbols {
+		names[symbol.Name.Lexeme] = symbol
+	}
+
+	total := names["total"]
+	assert.Equal(t, SymbolVariable, total.Kind)
+	assert.True(t, total.Global)
+	assert.Equal(t, [][2]int{{4, 3}, {8, 25}}, positions(total.References))
+
+	add := names["add"]
+	assert.Equal(t, SymbolFunction, add.Kind)
+	assert.Len(t, add.Parameters, 2)
+	assert.Equal(t, [][2]int{{7, 30}, {8, 7}}, positions(add.References))
+
+	sum := names["sum"]
+	assert.False(t, sum.Global)
+	assert.Same(t, add, sum.Container)
+	assert.Equal(t, [][2]int{{4, 12}, {5, 10}}, positions(sum.References))
+
+	assert.Equal(t, SymbolParameter, names["a"].Kind)
+	assert.Equal(t, SymbolFunction, names["twice"].Kind)
+	assert.Len(t, names["twice"].Parameters, 1)
+
+	assert.Same(t, sum, analysis.SymbolAt(5, 12))
+	assert.Same(t, add, analysis.SymbolAt(2, 5))
+	assert.Nil(t, analysis.SymbolAt(8, 1))
+}
+
+func TestAnalyzeToleratesErrors(t *testing.T) {
+	analysis := Analyze(`fun f(a) {
+  var x = a.;
+  return x;
+}
+print @;
+var y = 1;
+{
+  var y = y;
+}
+fun g() {
+  print f(
+`)
+
+	var messages []string
+	for _, diagnostic := range analysis.Diagnostics {
+		messages = append(messages, diagnostic.Message)
+	}
+	assert.Equal(t, []string{
+		"Unexpected character: @",
+		"Expect property name after '.'.",
+		"Expect expression.",
+		"Expect expression.",
+		"Expect '}' after block.",
+		"Can't read local variable in its own initializer.",
+	}, messages)
+	assert.Equal(t, Diagnostic{Line: 2, Column: 13, Length: 1, Message: "Expect property name after '.'."}, analysis.Diagnostics[1])
+
+	var names []string
+	for _, symbol := range analysis.Symbols {
+		names = append(names, symbol.Name.Lexeme)
+	}
+	assert.Equal(t, []string{"f", "a", "y", "y", "g"}, names)
+}
+
+func TestAnalyzeReportsTypeErrors(t *testing.T) {
+	analysis := Analyze("var n: number = 1;\nprint n - \"a\";")
+	assert.Equal(t, []Diagnostic{{Line: 2, Column: 9, Length: 1, Message: "Operands must be numbers."}}, analysis.Diagnostics)
+}
+
+func positions(tokens []Token) [][2]int {
+	var result [][2]int
+	for _, token := range tokens {
+		result = append(result, [2]int{token.Line, token.Column})
+	}
+	return result
+}
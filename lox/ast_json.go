@@ -0,0 +1,867 @@
+package lox
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MarshalProgram encodes a program's syntax tree as JSON for tools. Every
+// node is an object with its "type", such as "BinaryExpr", the "span" of
+// source its tokens cover, and its fields: child nodes, arrays of them, or
+// tokens. The program itself is a node of type "Program". UnmarshalProgram
+// rebuilds the tree.
+func MarshalProgram(statements []Stmt) ([]byte, error) {
+	var e astEncoder
+	program := newJSONNode("Program")
+	program.set("statements", e.stmts(program, statements))
+	return json.Marshal(program)
+}
+
+// MarshalExpr encodes the syntax tree of a single expression as JSON, in the
+// form MarshalProgram uses for expressions.
+func MarshalExpr(expr Expr) ([]byte, error) {
+	var e astEncoder
+	return json.Marshal(e.expr(expr))
+}
+
+// UnmarshalProgram rebuilds a program from the JSON MarshalProgram produces.
+// Spans are ignored; tokens keep their own positions.
+func UnmarshalProgram(data []byte) ([]Stmt, error) {
+	var d astDecoder
+	program := d.node(data, "program")
+	if d.err == nil && (program == nil || program.typ != "Program") {
+		d.fail("Expected a Program.")
+	}
+	statements := d.stmts(program, "statements")
+	if d.err != nil {
+		return nil, d.err
+	}
+	return statements, nil
+}
+
+// UnmarshalExpr rebuilds an expression from the JSON MarshalExpr produces.
+func UnmarshalExpr(data []byte) (Expr, error) {
+	var d astDecoder
+	expr := d.exprNode(d.node(data, "expression"))
+	if d.err == nil && expr == nil {
+		d.fail("Expected an expression.")
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return expr, nil
+}
+
+// jsonPosition is a place in the source. Lines and columns count from 1.
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (p jsonPosition) before(other jsonPosition) bool {
+	return p.Line < other.Line || p.Line == other.Line && p.Column < other.Column
+}
+
+// jsonSpan is the source a node covers. End is the position just past its
+// last character.
+type jsonSpan struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+// tokenSpan returns the source a token covers, or nil for a token the parser
+// made up rather than scanned.
+func tokenSpan(token Token) *jsonSpan {
+	if token.Line == 0 {
+		return nil
+	}
+
+	lines := strings.Count(token.Lexeme, "\n")
+	span := &jsonSpan{
+		Start: jsonPosition{Line: token.Line - lines, Column: token.Column},
+		End:   jsonPosition{Line: token.Line, Column: token.Column + len(token.Lexeme)},
+	}
+	if lines > 0 {
+		span.End.Column = len(token.Lexeme) - strings.LastIndex(token.Lexeme, "\n")
+	}
+	return span
+}
+
+type jsonToken struct {
+	Type    string `json:"type"`
+	Lexeme  string `json:"lexeme"`
+	Literal any    `json:"literal,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+// jsonNode is a node of the syntax tree as encoded, keeping its fields in the
+// order they were set.
+type jsonNode struct {
+	typ    string
+	span   *jsonSpan
+	keys   []string
+	values []any
+}
+
+func newJSONNode(typ string) *jsonNode {
+	return &jsonNode{typ: typ}
+}
+
+func (n *jsonNode) set(key string, value any) {
+	n.keys = append(n.keys, key)
+	n.values = append(n.values, value)
+}
+
+// cover widens the node's span to take in span.
+func (n *jsonNode) cover(span *jsonSpan) {
+	if span == nil {
+		return
+	}
+	if n.span == nil {
+		covered := *span
+		n.span = &covered
+		return
+	}
+	if span.Start.before(n.span.Start) {
+		n.span.Start = span.Start
+	}
+	if n.span.End.before(span.End) {
+		n.span.End = span.End
+	}
+}
+
+func (n *jsonNode) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":`)
+	typ, _ := json.Marshal(n.typ)
+	buf.Write(typ)
+
+	if n.span != nil {
+		span, _ := json.Marshal(n.span)
+		buf.WriteString(`,"span":`)
+		buf.Write(span)
+	}
+
+	for i, key := range n.keys {
+		value, err := json.Marshal(n.values[i])
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, ",%q:", key)
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// astEncoder turns a syntax tree into jsonNodes.
+type astEncoder struct{}
+
+var (
+	_ ExprVisitor = (*astEncoder)(nil)
+	_ StmtVisitor = (*astEncoder)(nil)
+)
+
+// expr encodes an expression, returning nil for a nil one so that it encodes
+// as null.
+func (e *astEncoder) expr(expr Expr) *jsonNode {
+	if expr == nil {
+		return nil
+	}
+	node, _ := expr.Accept(e)
+	return node.(*jsonNode)
+}
+
+func (e *astEncoder) stmt(stmt Stmt) *jsonNode {
+	if stmt == nil {
+		return nil
+	}
+	node, _ := stmt.Accept(e)
+	return node.(*jsonNode)
+}
+
+// child sets key on parent to the encoded child and widens the parent's span
+// to cover it.
+func (e *astEncoder) child(parent *jsonNode, key string, child *jsonNode) {
+	if child != nil {
+		parent.cover(child.span)
+	}
+	parent.set(key, child)
+}
+
+func (e *astEncoder) exprs(parent *jsonNode, exprs []Expr) []*jsonNode {
+	nodes := make([]*jsonNode, len(exprs))
+	for i, expr := range exprs {
+		nodes[i] = e.expr(expr)
+		parent.cover(nodes[i].span)
+	}
+	return nodes
+}
+
+func (e *astEncoder) stmts(parent *jsonNode, stmts []Stmt) []*jsonNode {
+	nodes := make([]*jsonNode, len(stmts))
+	for i, stmt := range stmts {
+		nodes[i] = e.stmt(stmt)
+		parent.cover(nodes[i].span)
+	}
+	return nodes
+}
+
+func (e *astEncoder) token(parent *jsonNode, key string, token Token) {
+	parent.cover(tokenSpan(token))
+	parent.set(key, encodeToken(token))
+}
+
+func (e *astEncoder) optionalToken(parent *jsonNode, key string, token *Token) {
+	if token == nil {
+		parent.set(key, nil)
+		return
+	}
+	e.token(parent, key, *token)
+}
+
+func (e *astEncoder) tokens(parent *jsonNode, key string, tokens []Token) {
+	if tokens == nil {
+		parent.set(key, nil)
+		return
+	}
+	encoded := make([]jsonToken, len(tokens))
+	for i, token := range tokens {
+		parent.cover(tokenSpan(token))
+		encoded[i] = encodeToken(token)
+	}
+	parent.set(key, encoded)
+}
+
+// semicolon encodes the token that ends a statement, which is null for a
+// statement the parser made up.
+func (e *astEncoder) semicolon(parent *jsonNode, token Token) {
+	if token.Line == 0 {
+		parent.set("semicolon", nil)
+		return
+	}
+	e.token(parent, "semicolon", token)
+}
+
+func encodeToken(token Token) jsonToken {
+	return jsonToken{
+		Type:    token.Type.String(),
+		Lexeme:  token.Lexeme,
+		Literal: token.Literal.Value,
+		Line:    token.Line,
+		Column:  token.Column,
+	}
+}
+
+func (e *astEncoder) annotation(annotation *TypeAnnotation) *jsonNode {
+	if annotation == nil {
+		return nil
+	}
+	node := newJSONNode("TypeAnnotation")
+	e.token(node, "name", annotation.Name)
+	return node
+}
+
+func (e *astEncoder) functionTypes(parent *jsonNode, types *FunctionTypes) {
+	if types == nil {
+		parent.set("types", nil)
+		return
+	}
+
+	node := newJSONNode("FunctionTypes")
+	parameters := make([]*jsonNode, len(types.Parameters))
+	for i, parameter := range types.Parameters {
+		parameters[i] = e.annotation(parameter)
+		if parameters[i] != nil {
+			node.cover(parameters[i].span)
+		}
+	}
+	node.set("parameters", parameters)
+	e.child(node, "return", e.annotation(types.Return))
+	e.child(parent, "types", node)
+}
+
+func (e *astEncoder) VisitBinaryExpr(expr *BinaryExpr) (any, error) {
+	node := newJSONNode("BinaryExpr")
+	e.child(node, "left", e.expr(expr.Left))
+	e.token(node, "operator", expr.Operator)
+	e.child(node, "right", e.expr(expr.Right))
+	return node, nil
+}
+
+func (e *astEncoder) VisitGroupingExpr(expr *GroupingExpr) (any, error) {
+	node := newJSONNode("GroupingExpr")
+	e.child(node, "expression", e.expr(expr.Expression))
+	return node, nil
+}
+
+func (e *astEncoder) VisitLiteralExpr(expr *LiteralExpr) (any, error) {
+	node := newJSONNode("LiteralExpr")
+	if expr.Token.Line == 0 {
+		node.set("token", nil)
+	} else {
+		e.token(node, "token", expr.Token)
+	}
+	node.set("value", expr.Value.Value)
+	return node, nil
+}
+
+func (e *astEncoder) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
+	node := newJSONNode("UnaryExpr")
+	e.token(node, "operator", expr.Operator)
+	e.child(node, "right", e.expr(expr.Right))
+	return node, nil
+}
+
+func (e *astEncoder) VisitVariableExpr(expr *VariableExpr) (any, error) {
+	node := newJSONNode("VariableExpr")
+	e.token(node, "name", expr.Name)
+	return node, nil
+}
+
+func (e *astEncoder) VisitAssignExpr(expr *AssignExpr) (any, error) {
+	node := newJSONNode("AssignExpr")
+	e.token(node, "name", expr.Name)
+	e.optionalToken(node, "operator", expr.Operator)
+	e.child(node, "value", e.expr(expr.Value))
+	return node, nil
+}
+
+func (e *astEncoder) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
+	node := newJSONNode("LogicalExpr")
+	e.child(node, "left", e.expr(expr.Left))
+	e.token(node, "operator", expr.Operator)
+	e.child(node, "right", e.expr(expr.Right))
+	return node, nil
+}
+
+func (e *astEncoder) VisitCallExpr(expr *CallExpr) (any, error) {
+	node := newJSONNode("CallExpr")
+	e.child(node, "callee", e.expr(expr.Callee))
+	e.token(node, "paren", expr.Paren)
+	node.set("arguments", e.exprs(node, expr.Arguments))
+	return node, nil
+}
+
+func (e *astEncoder) VisitFunctionExpr(expr *FunctionExpr) (any, error) {
+	node := newJSONNode("FunctionExpr")
+	e.token(node, "keyword", expr.Keyword)
+	e.tokens(node, "parameters", expr.Parameters)
+	e.functionTypes(node, expr.Types)
+	node.set("body", e.stmts(node, expr.Body))
+	return node, nil
+}
+
+func (e *astEncoder) VisitGetExpr(expr *GetExpr) (any, error) {
+	node := newJSONNode("GetExpr")
+	e.child(node, "object", e.expr(expr.Object))
+	e.token(node, "name", expr.Name)
+	return node, nil
+}
+
+func (e *astEncoder) VisitSetExpr(expr *SetExpr) (any, error) {
+	node := newJSONNode("SetExpr")
+	e.child(node, "object", e.expr(expr.Object))
+	e.token(node, "name", expr.Name)
+	e.optionalToken(node, "operator", expr.Operator)
+	e.child(node, "value", e.expr(expr.Value))
+	return node, nil
+}
+
+func (e *astEncoder) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	node := newJSONNode("IndexExpr")
+	e.child(node, "object", e.expr(expr.Object))
+	e.child(node, "index", e.expr(expr.Index))
+	e.token(node, "bracket", expr.Bracket)
+	return node, nil
+}
+
+func (e *astEncoder) VisitIndexSetExpr(expr *IndexSetExpr) (any, error) {
+	node := newJSONNode("IndexSetExpr")
+	e.child(node, "object", e.expr(expr.Object))
+	e.child(node, "index", e.expr(expr.Index))
+	e.token(node, "bracket", expr.Bracket)
+	e.optionalToken(node, "operator", expr.Operator)
+	e.child(node, "value", e.expr(expr.Value))
+	return node, nil
+}
+
+func (e *astEncoder) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	node := newJSONNode("ConditionalExpr")
+	e.child(node, "condition", e.expr(expr.Condition))
+	e.child(node, "thenBranch", e.expr(expr.ThenBranch))
+	e.child(node, "elseBranch", e.expr(expr.ElseBranch))
+	return node, nil
+}
+
+func (e *astEncoder) VisitStringifyExpr(expr *StringifyExpr) (any, error) {
+	node := newJSONNode("StringifyExpr")
+	e.child(node, "expression", e.expr(expr.Expression))
+	return node, nil
+}
+
+func (e *astEncoder) VisitExprStmt(stmt *ExprStmt) (any, error) {
+	node := newJSONNode("ExprStmt")
+	e.child(node, "expression", e.expr(stmt.Expression))
+	e.semicolon(node, stmt.Semicolon)
+	return node, nil
+}
+
+func (e *astEncoder) VisitPrintStmt(stmt *PrintStmt) (any, error) {
+	node := newJSONNode("PrintStmt")
+	e.token(node, "keyword", stmt.Keyword)
+	e.child(node, "expression", e.expr(stmt.Expression))
+	e.semicolon(node, stmt.Semicolon)
+	return node, nil
+}
+
+func (e *astEncoder) VisitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
+	node := newJSONNode("VarDeclStmt")
+	e.token(node, "keyword", stmt.Keyword)
+	e.token(node, "name", stmt.Name)
+	e.child(node, "annotation", e.annotation(stmt.Type))
+	e.child(node, "initializer", e.expr(stmt.Initializer))
+	e.semicolon(node, stmt.Semicolon)
+	return node, nil
+}
+
+func (e *astEncoder) VisitBlockStmt(stmt *BlockStmt) (any, error) {
+	node := newJSONNode("BlockStmt")
+	node.set("statements", e.stmts(node, stmt.Statements))
+	return node, nil
+}
+
+func (e *astEncoder) VisitIfStmt(stmt *IfStmt) (any, error) {
+	node := newJSONNode("IfStmt")
+	e.token(node, "keyword", stmt.Keyword)
+	e.child(node, "condition", e.expr(stmt.Condition))
+	e.child(node, "thenBranch", e.stmt(stmt.ThenBranch))
+	e.child(node, "elseBranch", e.stmt(stmt.ElseBranch))
+	return node, nil
+}
+
+func (e *astEncoder) VisitWhileStmt(stmt *WhileStmt) (any, error) {
+	node := newJSONNode("WhileStmt")
+	e.token(node, "keyword", stmt.Keyword)
+	e.child(node, "condition", e.expr(stmt.Condition))
+	e.child(node, "body", e.stmt(stmt.Body))
+
+	if stmt.For == nil {
+		node.set("for", nil)
+		return node, nil
+	}
+	clauses := newJSONNode("ForClauses")
+	e.child(clauses, "initializer", e.stmt(stmt.For.Initializer))
+	e.child(clauses, "condition", e.expr(stmt.For.Condition))
+	e.child(clauses, "increment", e.expr(stmt.For.Increment))
+	e.child(node, "for", clauses)
+	return node, nil
+}
+
+func (e *astEncoder) VisitForInStmt(stmt *ForInStmt) (any, error) {
+	node := newJSONNode("ForInStmt")
+	e.token(node, "name", stmt.Name)
+	e.token(node, "keyword", stmt.Keyword)
+	e.child(node, "iterable", e.expr(stmt.Iterable))
+	e.child(node, "body", e.stmt(stmt.Body))
+	return node, nil
+}
+
+func (e *astEncoder) VisitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error) {
+	node := newJSONNode("FunctionDeclStmt")
+	e.token(node, "name", stmt.Name)
+	e.tokens(node, "parameters", stmt.Parameters)
+	e.functionTypes(node, stmt.Types)
+	node.set("body", e.stmts(node, stmt.Body))
+	return node, nil
+}
+
+func (e *astEncoder) VisitReturnStmt(stmt *ReturnStmt) (any, error) {
+	node := newJSONNode("ReturnStmt")
+	e.token(node, "keyword", stmt.Keyword)
+	e.child(node, "value", e.expr(stmt.Value))
+	e.semicolon(node, stmt.Semicolon)
+	return node, nil
+}
+
+func (e *astEncoder) VisitThrowStmt(stmt *ThrowStmt) (any, error) {
+	node := newJSONNode("ThrowStmt")
+	e.token(node, "keyword", stmt.Keyword)
+	e.child(node, "value", e.expr(stmt.Value))
+	e.semicolon(node, stmt.Semicolon)
+	return node, nil
+}
+
+func (e *astEncoder) VisitTryStmt(stmt *TryStmt) (any, error) {
+	node := newJSONNode("TryStmt")
+	e.token(node, "keyword", stmt.Keyword)
+	node.set("body", e.stmts(node, stmt.Body))
+	e.optionalToken(node, "catchName", stmt.CatchName)
+	node.set("catchBody", e.stmts(node, stmt.CatchBody))
+	node.set("finallyBody", e.stmts(node, stmt.FinallyBody))
+	return node, nil
+}
+
+func (e *astEncoder) VisitImportStmt(stmt *ImportStmt) (any, error) {
+	node := newJSONNode("ImportStmt")
+	e.token(node, "keyword", stmt.Keyword)
+	e.token(node, "path", stmt.Path)
+	e.optionalToken(node, "alias", stmt.Alias)
+	e.tokens(node, "names", stmt.Names)
+	e.semicolon(node, stmt.Semicolon)
+	return node, nil
+}
+
+func (e *astEncoder) VisitExportStmt(stmt *ExportStmt) (any, error) {
+	node := newJSONNode("ExportStmt")
+	e.token(node, "keyword", stmt.Keyword)
+	e.child(node, "declaration", e.stmt(stmt.Declaration))
+	return node, nil
+}
+
+// tokenTypes maps the names of token types back to them.
+var tokenTypes = func() map[string]TokenType {
+	types := make(map[string]TokenType)
+	for t := LEFT_PAREN; t <= EOF; t++ {
+		types[t.String()] = t
+	}
+	return types
+}()
+
+// decodedNode is an encoded node whose fields are still to be decoded.
+type decodedNode struct {
+	typ    string
+	fields map[string]json.RawMessage
+}
+
+// astDecoder rebuilds a syntax tree from JSON. It stops at the first error,
+// after which its methods return zero values.
+type astDecoder struct {
+	err error
+}
+
+func (d *astDecoder) fail(format string, args ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+// node decodes the object in data, returning nil if it is null. what names
+// the node for error messages.
+func (d *astDecoder) node(data json.RawMessage, what string) *decodedNode {
+	if d.err != nil || isNull(data) {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		d.fail("Invalid %s: %v.", what, err)
+		return nil
+	}
+	node := &decodedNode{fields: fields}
+	if err := json.Unmarshal(fields["type"], &node.typ); err != nil || node.typ == "" {
+		d.fail("Expected a type for the %s.", what)
+		return nil
+	}
+	return node
+}
+
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == "null"
+}
+
+// field returns the encoded field key of node, failing if it is missing and
+// required is set. It returns nil when the field is null.
+func (d *astDecoder) field(node *decodedNode, key string, required bool) json.RawMessage {
+	if d.err != nil || node == nil {
+		return nil
+	}
+	data := node.fields[key]
+	if required && isNull(data) {
+		d.fail("Expected '%s' in %s.", key, node.typ)
+	}
+	if isNull(data) {
+		return nil
+	}
+	return data
+}
+
+func (d *astDecoder) expr(node *decodedNode, key string) Expr {
+	return d.exprNode(d.node(d.field(node, key, true), key))
+}
+
+func (d *astDecoder) optionalExpr(node *decodedNode, key string) Expr {
+	return d.exprNode(d.node(d.field(node, key, false), key))
+}
+
+func (d *astDecoder) stmt(node *decodedNode, key string) Stmt {
+	return d.stmtNode(d.node(d.field(node, key, true), key))
+}
+
+func (d *astDecoder) optionalStmt(node *decodedNode, key string) Stmt {
+	return d.stmtNode(d.node(d.field(node, key, false), key))
+}
+
+func (d *astDecoder) array(node *decodedNode, key string) []json.RawMessage {
+	data := d.field(node, key, false)
+	if data == nil {
+		return nil
+	}
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		d.fail("Expected an array for '%s' in %s.", key, node.typ)
+	}
+	return elements
+}
+
+func (d *astDecoder) exprs(node *decodedNode, key string) []Expr {
+	var exprs []Expr
+	for _, element := range d.array(node, key) {
+		expr := d.exprNode(d.node(element, key))
+		if expr == nil {
+			d.fail("Expected an expression in '%s' in %s.", key, node.typ)
+		}
+		exprs = append(exprs, expr)
+	}
+	return exprs
+}
+
+func (d *astDecoder) stmts(node *decodedNode, key string) []Stmt {
+	var stmts []Stmt
+	for _, element := range d.array(node, key) {
+		stmt := d.stmtNode(d.node(element, key))
+		if stmt == nil {
+			d.fail("Expected a statement in '%s' in %s.", key, node.typ)
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
+
+func (d *astDecoder) token(node *decodedNode, key string) Token {
+	return d.decodeToken(d.field(node, key, true), node, key)
+}
+
+func (d *astDecoder) optionalToken(node *decodedNode, key string) *Token {
+	data := d.field(node, key, false)
+	if data == nil {
+		return nil
+	}
+	token := d.decodeToken(data, node, key)
+	return &token
+}
+
+// semicolon decodes the token that ends a statement, which is zero for a
+// statement the parser made up.
+func (d *astDecoder) semicolon(node *decodedNode) Token {
+	if token := d.optionalToken(node, "semicolon"); token != nil {
+		return *token
+	}
+	return Token{}
+}
+
+func (d *astDecoder) tokens(node *decodedNode, key string) []Token {
+	elements := d.array(node, key)
+	if elements == nil {
+		return nil
+	}
+	tokens := make([]Token, len(elements))
+	for i, element := range elements {
+		tokens[i] = d.decodeToken(element, node, key)
+	}
+	return tokens
+}
+
+func (d *astDecoder) decodeToken(data json.RawMessage, node *decodedNode, key string) Token {
+	if d.err != nil {
+		return Token{}
+	}
+
+	var token jsonToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		d.fail("Expected a token for '%s' in %s.", key, node.typ)
+		return Token{}
+	}
+	typ, ok := tokenTypes[token.Type]
+	if !ok {
+		d.fail("Unknown token type '%s'.", token.Type)
+	}
+	return Token{Type: typ, Lexeme: token.Lexeme, Literal: NewLiteral(token.Literal), Line: token.Line, Column: token.Column}
+}
+
+func (d *astDecoder) value(node *decodedNode, key string) Literal {
+	var value any
+	if data := d.field(node, key, false); data != nil {
+		if err := json.Unmarshal(data, &value); err != nil {
+			d.fail("Invalid '%s' in %s.", key, node.typ)
+		}
+	}
+	return NewLiteral(value)
+}
+
+func (d *astDecoder) annotation(data json.RawMessage, key string) *TypeAnnotation {
+	node := d.node(data, key)
+	if node == nil {
+		return nil
+	}
+	if node.typ != "TypeAnnotation" {
+		d.fail("Expected a TypeAnnotation for '%s' but got '%s'.", key, node.typ)
+		return nil
+	}
+	return &TypeAnnotation{Name: d.token(node, "name")}
+}
+
+func (d *astDecoder) functionTypes(parent *decodedNode) *FunctionTypes {
+	node := d.node(d.field(parent, "types", false), "types")
+	if node == nil {
+		return nil
+	}
+
+	types := &FunctionTypes{Return: d.annotation(d.field(node, "return", false), "return")}
+	for _, element := range d.array(node, "parameters") {
+		types.Parameters = append(types.Parameters, d.annotation(element, "parameters"))
+	}
+	return types
+}
+
+func (d *astDecoder) exprNode(node *decodedNode) Expr {
+	if node == nil {
+		return nil
+	}
+
+	switch node.typ {
+	case "BinaryExpr":
+		return NewBinaryExpr(d.expr(node, "left"), d.token(node, "operator"), d.expr(node, "right"))
+	case "GroupingExpr":
+		return NewGroupingExpr(d.expr(node, "expression"))
+	case "LiteralExpr":
+		var token Token
+		if t := d.optionalToken(node, "token"); t != nil {
+			token = *t
+		}
+		return NewLiteralExpr(token, d.value(node, "value"))
+	case "UnaryExpr":
+		return NewUnaryExpr(d.token(node, "operator"), d.expr(node, "right"))
+	case "VariableExpr":
+		return NewVariableExpr(d.token(node, "name"))
+	case "AssignExpr":
+		return NewAssignExpr(d.token(node, "name"), d.optionalToken(node, "operator"), d.expr(node, "value"))
+	case "LogicalExpr":
+		return NewLogicalExpr(d.expr(node, "left"), d.token(node, "operator"), d.expr(node, "right"))
+	case "CallExpr":
+		return NewCallExpr(d.expr(node, "callee"), d.token(node, "paren"), d.exprs(node, "arguments"))
+	case "FunctionExpr":
+		expr := NewFunctionExpr(d.token(node, "keyword"), d.tokens(node, "parameters"), d.stmts(node, "body"))
+		expr.Types = d.functionTypes(node)
+		return expr
+	case "GetExpr":
+		return NewGetExpr(d.expr(node, "object"), d.token(node, "name"))
+	case "SetExpr":
+		return NewSetExpr(d.expr(node, "object"), d.token(node, "name"), d.optionalToken(node, "operator"), d.expr(node, "value"))
+	case "IndexExpr":
+		return NewIndexExpr(d.expr(node, "object"), d.token(node, "bracket"), d.expr(node, "index"))
+	case "IndexSetExpr":
+		return NewIndexSetExpr(d.expr(node, "object"), d.token(node, "bracket"), d.expr(node, "index"), d.optionalToken(node, "operator"), d.expr(node, "value"))
+	case "ConditionalExpr":
+		return NewConditionalExpr(d.expr(node, "condition"), d.expr(node, "thenBranch"), d.expr(node, "elseBranch"))
+	case "StringifyExpr":
+		return NewStringifyExpr(d.expr(node, "expression"))
+	}
+
+	d.fail("Unknown expression type '%s'.", node.typ)
+	return nil
+}
+
+func (d *astDecoder) stmtNode(node *decodedNode) Stmt {
+	if node == nil {
+		return nil
+	}
+
+	switch node.typ {
+	case "ExprStmt":
+		stmt := NewExprStmt(d.expr(node, "expression"))
+		stmt.Semicolon = d.semicolon(node)
+		return stmt
+	case "PrintStmt":
+		stmt := NewPrintStmt(d.token(node, "keyword"), d.expr(node, "expression"))
+		stmt.Semicolon = d.semicolon(node)
+		return stmt
+	case "VarDeclStmt":
+		stmt := NewVarDeclStmt(d.token(node, "keyword"), d.token(node, "name"), d.optionalExpr(node, "initializer"))
+		stmt.Type = d.annotation(d.field(node, "annotation", false), "annotation")
+		stmt.Semicolon = d.semicolon(node)
+		return stmt
+	case "BlockStmt":
+		return d.block(node)
+	case "IfStmt":
+		return NewIfStmt(d.token(node, "keyword"), d.expr(node, "condition"), d.stmt(node, "thenBranch"), d.optionalStmt(node, "elseBranch"))
+	case "WhileStmt":
+		return d.while(node)
+	case "ForInStmt":
+		return NewForInStmt(d.token(node, "name"), d.token(node, "keyword"), d.expr(node, "iterable"), d.stmt(node, "body"))
+	case "FunctionDeclStmt":
+		stmt := NewFunctionDeclStmt(d.token(node, "name"), d.tokens(node, "parameters"), d.stmts(node, "body"))
+		stmt.Types = d.functionTypes(node)
+		return stmt
+	case "ReturnStmt":
+		stmt := NewReturnStmt(d.token(node, "keyword"), d.optionalExpr(node, "value"))
+		stmt.Semicolon = d.semicolon(node)
+		return stmt
+	case "ThrowStmt":
+		stmt := NewThrowStmt(d.token(node, "keyword"), d.expr(node, "value"))
+		stmt.Semicolon = d.semicolon(node)
+		return stmt
+	case "TryStmt":
+		return NewTryStmt(d.token(node, "keyword"), d.stmts(node, "body"), d.optionalToken(node, "catchName"), d.stmts(node, "catchBody"), d.stmts(node, "finallyBody"))
+	case "ImportStmt":
+		stmt := NewImportStmt(d.token(node, "keyword"), d.token(node, "path"), d.optionalToken(node, "alias"), d.tokens(node, "names"))
+		stmt.Semicolon = d.semicolon(node)
+		return stmt
+	case "ExportStmt":
+		return NewExportStmt(d.token(node, "keyword"), d.stmt(node, "declaration"))
+	}
+
+	d.fail("Unknown statement type '%s'.", node.typ)
+	return nil
+}
+
+// The parser shares the nodes of a for loop's clauses with the statements it
+// desugars the loop into, and the formatter and linter rely on that, so the
+// decoder links them up again rather than keeping the encoded copies.
+
+func (d *astDecoder) block(node *decodedNode) Stmt {
+	block := NewBlockStmt(d.stmts(node, "statements"))
+	if len(block.Statements) == 2 {
+		loop, ok := block.Statements[1].(*WhileStmt)
+		if ok && loop.For != nil && loop.For.Initializer != nil {
+			loop.For.Initializer = block.Statements[0]
+		}
+	}
+	return block
+}
+
+func (d *astDecoder) while(node *decodedNode) Stmt {
+	loop := NewWhileStmt(d.token(node, "keyword"), d.expr(node, "condition"), d.stmt(node, "body"))
+
+	clauses := d.node(d.field(node, "for", false), "for")
+	if clauses == nil {
+		return loop
+	}
+	loop.For = &ForClauses{
+		Initializer: d.optionalStmt(clauses, "initializer"),
+		Condition:   d.optionalExpr(clauses, "condition"),
+		Increment:   d.optionalExpr(clauses, "increment"),
+	}
+	if loop.For.Condition != nil {
+		loop.For.Condition = loop.Condition
+	}
+	if body, ok := loop.Body.(*BlockStmt); ok && loop.For.Increment != nil && len(body.Statements) == 2 {
+		if increment, ok := body.Statements[1].(*ExprStmt); ok {
+			loop.For.Increment = increment.Expression
+		}
+	}
+	return loop
+}
@@ -0,0 +1,195 @@
+package lox
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseSource(t *testing.T, source string) []Stmt {
+	t.Helper()
+
+	tokens, errs := NewScanner(source).ScanTokens()
+	require.Empty(t, errs)
+	statements, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+	return statements
+}
+
+// TestASTJSONRoundTrip encodes each script in testdata/format, rebuilds it
+// from the JSON and checks that the rebuilt program is the one parsed and
+// runs the same.
+func TestASTJSONRoundTrip(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "format", "*.lox"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			require.NoError(t, err)
+			statements := parseSource(t, string(source))
+
+			data, err := MarshalProgram(statements)
+			require.NoError(t, err)
+			rebuilt, err := UnmarshalProgram(data)
+			require.NoError(t, err)
+			assert.Equal(t, statements, rebuilt)
+
+			again, err := MarshalProgram(rebuilt)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(data), string(again))
+
+			var output bytes.Buffer
+			interpreter := NewInterpreter(WithStdout(&output))
+			require.NoError(t, NewResolver(interpreter).Resolve(rebuilt))
+			require.NoError(t, interpreter.Interpret(rebuilt))
+			assert.Equal(t, runSource(t, string(source)), output.String())
+		})
+	}
+}
+
+func TestMarshalProgram(t *testing.T) {
+	data, err := MarshalProgram(parseSource(t, "var x: number = 1;\nprint \"a\n b\" + x;"))
+	require.NoError(t, err)
+
+	var program struct {
+		Type       string
+		Span       jsonSpan
+		Statements []map[string]json.RawMessage
+	}
+	require.NoError(t, json.Unmarshal(data, &program))
+	assert.Equal(t, "Program", program.Type)
+	assert.Equal(t, jsonSpan{Start: jsonPosition{1, 1}, End: jsonPosition{3, 9}}, program.Span)
+	require.Len(t, program.Statements, 2)
+
+	assert.JSONEq(t, `{
+		"type": "VarDeclStmt",
+		"span": {"start": {"line": 1, "column": 1}, "end": {"line": 1, "column": 19}},
+		"keyword": {"type": "VAR", "lexeme": "var", "line": 1, "column": 1},
+		"name": {"type": "IDENTIFIER", "lexeme": "x", "line": 1, "column": 5},
+		"annotation": {
+			"type": "TypeAnnotation",
+			"span": {"start": {"line": 1, "column": 8}, "end": {"line": 1, "column": 14}},
+			"name": {"type": "IDENTIFIER", "lexeme": "number", "line": 1, "column": 8}
+		},
+		"initializer": {
+			"type": "LiteralExpr",
+			"span": {"start": {"line": 1, "column": 17}, "end": {"line": 1, "column": 18}},
+			"token": {"type": "NUMBER", "lexeme": "1", "literal": 1, "line": 1, "column": 17},
+			"value": 1
+		},
+		"semicolon": {"type": "SEMICOLON", "lexeme": ";", "line": 1, "column": 18}
+	}`, string(mustMarshal(t, program.Statements[0])))
+
+	var print struct {
+		Span       jsonSpan
+		Expression struct {
+			Left struct{ Span jsonSpan }
+		}
+	}
+	require.NoError(t, json.Unmarshal(mustMarshal(t, program.Statements[1]), &print))
+	assert.Equal(t, jsonSpan{Start: jsonPosition{2, 1}, End: jsonPosition{3, 9}}, print.Span)
+	assert.Equal(t, jsonSpan{Start: jsonPosition{2, 7}, End: jsonPosition{3, 4}}, print.Expression.Left.Span)
+}
+
+// TestMarshalStatementSpans checks that a statement's span runs from its
+// first token to its last, keywords and semicolons included.
+func TestMarshalStatementSpans(t *testing.T) {
+	tests := []struct {
+		source string
+		span   jsonSpan
+	}{
+		{"var a = 1;", jsonSpan{Start: jsonPosition{1, 1}, End: jsonPosition{1, 11}}},
+		{"var a;", jsonSpan{Start: jsonPosition{1, 1}, End: jsonPosition{1, 7}}},
+		{"a = 1 ;", jsonSpan{Start: jsonPosition{1, 1}, End: jsonPosition{1, 8}}},
+		{"print a;", jsonSpan{Start: jsonPosition{1, 1}, End: jsonPosition{1, 9}}},
+		{"throw a;", jsonSpan{Start: jsonPosition{1, 1}, End: jsonPosition{1, 9}}},
+		{"import \"m.lox\" as m;", jsonSpan{Start: jsonPosition{1, 1}, End: jsonPosition{1, 21}}},
+		{"export var a = 1;", jsonSpan{Start: jsonPosition{1, 1}, End: jsonPosition{1, 18}}},
+		{"for (var i = 0; i < 1; i = i + 1) a;", jsonSpan{Start: jsonPosition{1, 1}, End: jsonPosition{1, 37}}},
+	}
+
+	for _, test := range tests {
+		data, err := MarshalProgram(parseSource(t, test.source))
+		require.NoError(t, err)
+
+		var program struct {
+			Statements []struct{ Span jsonSpan }
+		}
+		require.NoError(t, json.Unmarshal(data, &program))
+		require.Len(t, program.Statements, 1, test.source)
+		assert.Equal(t, test.span, program.Statements[0].Span, test.source)
+	}
+}
+
+func TestMarshalExpr(t *testing.T) {
+	tokens, errs := NewScanner("-(1 + a)").ScanTokens()
+	require.Empty(t, errs)
+	expr, err := NewParser(tokens).ParseExpr()
+	require.NoError(t, err)
+
+	data, err := MarshalExpr(expr)
+	require.NoError(t, err)
+	rebuilt, err := UnmarshalExpr(data)
+	require.NoError(t, err)
+	assert.Equal(t, expr, rebuilt)
+}
+
+func TestUnmarshalProgramErrors(t *testing.T) {
+	tests := []struct {
+		json string
+		err  string
+	}{
+		{`null`, "Expected a Program."},
+		{`{"type": "BlockStmt"}`, "Expected a Program."},
+		{`{"type": "Program", "statements": [{"type": "Nope"}]}`, "Unknown statement type 'Nope'."},
+		{`{"type": "Program", "statements": [{"type": "PrintStmt", "keyword": {"type": "PRINT"}}]}`, "Expected 'expression' in PrintStmt."},
+		{`{"type": "Program", "statements": [{"type": "ExprStmt", "expression": {"type": "VariableExpr", "name": {"type": "?"}}}]}`, "Unknown token type '?'."},
+		{`{"type": "Program", "statements": [null]}`, "Expected a statement in 'statements' in Program."},
+	}
+
+	for _, test := range tests {
+		_, err := UnmarshalProgram([]byte(test.json))
+		assert.EqualError(t, err, test.err, test.json)
+	}
+}
+
+func TestAstPrinter(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"var x: number = 1;", "(var x: number 1.0)"},
+		{"if (a) print 1; else { b; }", "(if a (print 1.0) (block (; b)))"},
+		{"while (a < 2) a += 1;", "(while (< a 2.0) (; (a += 1.0)))"},
+		{"a = b.c *= 2;", "(; (a = (*= (. b c) 2.0)))"},
+		{"a[0] -= b[c][1];", "(; (-= ([] a 0.0) ([] ([] b c) 1.0)))"},
+		{"for (var x in xs) print x;", "(for-in x xs (print x))"},
+		{"fun f(a, b: string): nil { return; }", "(fun f (a b: string): nil (return))"},
+		{"try { throw 1; } catch (e) {} finally { print 2; }", "(try (block (throw 1.0)) (catch e) (finally (print 2.0)))"},
+		{"import \"m.lox\" as m;", "(import \"m.lox\" as m)"},
+		{"import { a, b } from \"m.lox\";", "(import \"m.lox\" (a b))"},
+		{"export var v = (n) => n;", "(export (var v (fun (n) (return n))))"},
+	}
+
+	var printer AstPrinter
+	for _, test := range tests {
+		statements := parseSource(t, test.source)
+		require.Len(t, statements, 1, test.source)
+		assert.Equal(t, test.expected, printer.stmt(statements[0]), test.source)
+	}
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
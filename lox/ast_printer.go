@@ -5,59 +5,236 @@ import (
 	"strings"
 )
 
+// AstPrinter prints syntax trees as S-expressions, one line per statement.
 type AstPrinter struct{}
 
 func (p *AstPrinter) Print(expr Expr) {
-	s, _ := expr.accept(p)
-	fmt.Println(s.(string))
+	fmt.Println(p.expr(expr))
 }
 
-var _ exprVisitor = (*AstPrinter)(nil)
+func (p *AstPrinter) PrintProgram(statements []Stmt) {
+	for _, stmt := range statements {
+		fmt.Println(p.stmt(stmt))
+	}
+}
+
+func (p *AstPrinter) expr(expr Expr) string {
+	s, _ := expr.Accept(p)
+	return s.(string)
+}
+
+func (p *AstPrinter) stmt(stmt Stmt) string {
+	s, _ := stmt.Accept(p)
+	return s.(string)
+}
+
+var (
+	_ ExprVisitor = (*AstPrinter)(nil)
+	_ StmtVisitor = (*AstPrinter)(nil)
+)
 
-func (p *AstPrinter) visitBinaryExpr(expr *BinaryExpr) (any, error) {
+func (p *AstPrinter) VisitBinaryExpr(expr *BinaryExpr) (any, error) {
 	return p.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right), nil
 }
 
-func (p *AstPrinter) visitGroupingExpr(expr *GroupingExpr) (any, error) {
+func (p *AstPrinter) VisitGroupingExpr(expr *GroupingExpr) (any, error) {
 	return p.parenthesize("group", expr.Expression), nil
 }
 
-func (p *AstPrinter) visitLiteralExpr(expr *LiteralExpr) (any, error) {
+func (p *AstPrinter) VisitLiteralExpr(expr *LiteralExpr) (any, error) {
 	if expr.Value.Value == nil {
 		return "nil", nil
 	}
 	return expr.Value.String(), nil
 }
 
-func (p *AstPrinter) visitUnaryExpr(expr *UnaryExpr) (any, error) {
+func (p *AstPrinter) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
 	return p.parenthesize(expr.Operator.Lexeme, expr.Right), nil
 }
 
-func (p *AstPrinter) visitVariableExpr(expr *VariableExpr) (any, error) {
+func (p *AstPrinter) VisitVariableExpr(expr *VariableExpr) (any, error) {
 	return expr.Name.Lexeme, nil
 }
 
-func (p *AstPrinter) visitAssignExpr(expr *AssignExpr) (any, error) {
-	return p.parenthesize(expr.Name.Lexeme+" =", expr.Value), nil
+func (p *AstPrinter) VisitAssignExpr(expr *AssignExpr) (any, error) {
+	operator := "="
+	if expr.Operator != nil {
+		operator = expr.Operator.Lexeme + "="
+	}
+	return p.parenthesize(expr.Name.Lexeme+" "+operator, expr.Value), nil
 }
 
-func (p *AstPrinter) visitLogicalExpr(expr *LogicalExpr) (any, error) {
+func (p *AstPrinter) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
 	return p.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right), nil
 }
 
-func (p *AstPrinter) visitCallExpr(expr *CallExpr) (any, error) {
+func (p *AstPrinter) VisitCallExpr(expr *CallExpr) (any, error) {
 	return p.parenthesize("call", append([]Expr{expr.Callee}, expr.Arguments...)...), nil
 }
 
+func (p *AstPrinter) VisitFunctionExpr(expr *FunctionExpr) (any, error) {
+	return p.function("fun", expr.Parameters, expr.Types, expr.Body), nil
+}
+
+func (p *AstPrinter) VisitGetExpr(expr *GetExpr) (any, error) {
+	return "(. " + p.expr(expr.Object) + " " + expr.Name.Lexeme + ")", nil
+}
+
+func (p *AstPrinter) VisitSetExpr(expr *SetExpr) (any, error) {
+	operator := "="
+	if expr.Operator != nil {
+		operator = expr.Operator.Lexeme + "="
+	}
+
+	target, _ := p.VisitGetExpr(NewGetExpr(expr.Object, expr.Name))
+	return "(" + operator + " " + target.(string) + " " + p.expr(expr.Value) + ")", nil
+}
+
+func (p *AstPrinter) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	return p.parenthesize("[]", expr.Object, expr.Index), nil
+}
+
+func (p *AstPrinter) VisitIndexSetExpr(expr *IndexSetExpr) (any, error) {
+	operator := "="
+	if expr.Operator != nil {
+		operator = expr.Operator.Lexeme + "="
+	}
+
+	target, _ := p.VisitIndexExpr(NewIndexExpr(expr.Object, expr.Bracket, expr.Index))
+	return "(" + operator + " " + target.(string) + " " + p.expr(expr.Value) + ")", nil
+}
+
+func (p *AstPrinter) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	return p.parenthesize("?:", expr.Condition, expr.ThenBranch, expr.ElseBranch), nil
+}
+
+func (p *AstPrinter) VisitStringifyExpr(expr *StringifyExpr) (any, error) {
+	return p.parenthesize("str", expr.Expression), nil
+}
+
+func (p *AstPrinter) VisitExprStmt(stmt *ExprStmt) (any, error) {
+	return p.parenthesize(";", stmt.Expression), nil
+}
+
+func (p *AstPrinter) VisitPrintStmt(stmt *PrintStmt) (any, error) {
+	return p.parenthesize("print", stmt.Expression), nil
+}
+
+func (p *AstPrinter) VisitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
+	name := "var " + stmt.Name.Lexeme + annotation(stmt.Type)
+	if stmt.Initializer == nil {
+		return p.parenthesize(name), nil
+	}
+	return p.parenthesize(name, stmt.Initializer), nil
+}
+
+func (p *AstPrinter) VisitBlockStmt(stmt *BlockStmt) (any, error) {
+	return p.block("block", stmt.Statements), nil
+}
+
+func (p *AstPrinter) VisitIfStmt(stmt *IfStmt) (any, error) {
+	s := "(if " + p.expr(stmt.Condition) + " " + p.stmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		s += " " + p.stmt(stmt.ElseBranch)
+	}
+	return s + ")", nil
+}
+
+func (p *AstPrinter) VisitWhileStmt(stmt *WhileStmt) (any, error) {
+	return "(while " + p.expr(stmt.Condition) + " " + p.stmt(stmt.Body) + ")", nil
+}
+
+func (p *AstPrinter) VisitForInStmt(stmt *ForInStmt) (any, error) {
+	return "(for-in " + stmt.Name.Lexeme + " " + p.expr(stmt.Iterable) + " " + p.stmt(stmt.Body) + ")", nil
+}
+
+func (p *AstPrinter) VisitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error) {
+	return p.function("fun "+stmt.Name.Lexeme, stmt.Parameters, stmt.Types, stmt.Body), nil
+}
+
+func (p *AstPrinter) VisitReturnStmt(stmt *ReturnStmt) (any, error) {
+	if stmt.Value == nil {
+		return p.parenthesize("return"), nil
+	}
+	return p.parenthesize("return", stmt.Value), nil
+}
+
+func (p *AstPrinter) VisitThrowStmt(stmt *ThrowStmt) (any, error) {
+	return p.parenthesize("throw", stmt.Value), nil
+}
+
+func (p *AstPrinter) VisitTryStmt(stmt *TryStmt) (any, error) {
+	s := "(try " + p.block("block", stmt.Body)
+	if stmt.CatchName != nil {
+		s += " " + p.block("catch "+stmt.CatchName.Lexeme, stmt.CatchBody)
+	}
+	if stmt.FinallyBody != nil {
+		s += " " + p.block("finally", stmt.FinallyBody)
+	}
+	return s + ")", nil
+}
+
+func (p *AstPrinter) VisitImportStmt(stmt *ImportStmt) (any, error) {
+	s := "(import " + stmt.Path.Lexeme
+	if stmt.Alias != nil {
+		s += " as " + stmt.Alias.Lexeme
+	}
+	if stmt.Names != nil {
+		s += " (" + lexemes(stmt.Names) + ")"
+	}
+	return s + ")", nil
+}
+
+func (p *AstPrinter) VisitExportStmt(stmt *ExportStmt) (any, error) {
+	return "(export " + p.stmt(stmt.Declaration) + ")", nil
+}
+
+// function prints a function as (name (params) body...), with any type
+// annotations after the names they belong to.
+func (p *AstPrinter) function(name string, parameters []Token, types *FunctionTypes, body []Stmt) string {
+	params := make([]string, len(parameters))
+	for i, param := range parameters {
+		params[i] = param.Lexeme
+		if types != nil {
+			params[i] += annotation(types.Parameters[i])
+		}
+	}
+
+	signature := "(" + strings.Join(params, " ") + ")"
+	if types != nil {
+		signature += annotation(types.Return)
+	}
+	return p.block(name+" "+signature, body)
+}
+
+func (p *AstPrinter) block(name string, statements []Stmt) string {
+	var builder strings.Builder
+	builder.WriteString("(")
+	builder.WriteString(name)
+	for _, stmt := range statements {
+		builder.WriteString(" ")
+		builder.WriteString(p.stmt(stmt))
+	}
+	builder.WriteString(")")
+	return builder.String()
+}
+
 func (p *AstPrinter) parenthesize(name string, exprs ...Expr) any {
 	var builder strings.Builder
 	builder.WriteString("(")
 	builder.WriteString(name)
 	for _, expr := range exprs {
 		builder.WriteString(" ")
-		s, _ := expr.accept(p)
-		builder.WriteString(s.(string))
+		builder.WriteString(p.expr(expr))
 	}
 	builder.WriteString(")")
 	return builder.String()
 }
+
+func lexemes(tokens []Token) string {
+	names := make([]string, len(tokens))
+	for i, token := range tokens {
+		names[i] = token.Lexeme
+	}
+	return strings.Join(names, " ")
+}
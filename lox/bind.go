@@ -0,0 +1,420 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Bind exposes a Go value to scripts as the global name. Exported struct fields
+// become properties and exported methods become callable methods. Values are
+// converted between Go and Lox as they cross: numbers to and from float64,
+// strings and bools as is, slices and arrays as lists, and maps with string
+// keys as maps. Bind a pointer to a struct to let scripts assign its fields.
+func (i *Interpreter) Bind(name string, value any) error {
+	converted, err := i.toLox(reflect.ValueOf(value))
+	if err != nil {
+		return fmt.Errorf("bind %s: value %w", name, err)
+	}
+
+	i.globals.Define(name, converted)
+	return nil
+}
+
+// goObject is a Go struct seen from Lox.
+type goObject struct {
+	interpreter *Interpreter
+	value       reflect.Value // a struct, or a pointer to one
+}
+
+var _ MutableObject = (*goObject)(nil)
+
+func (o *goObject) Get(name Token) (any, error) {
+	if field, ok := o.field(name.Lexeme); ok {
+		value, err := o.interpreter.toLox(field)
+		if err != nil {
+			return nil, NewRuntimeError(name, fmt.Sprintf("Field '%s' %s.", name.Lexeme, err))
+		}
+		return value, nil
+	}
+
+	if method := o.value.MethodByName(name.Lexeme); method.IsValid() {
+		return &goFunction{interpreter: o.interpreter, name: name.Lexeme, fn: method}, nil
+	}
+
+	return nil, NewRuntimeError(name, "Undefined property '"+name.Lexeme+"'.")
+}
+
+func (o *goObject) Set(name Token, value any) error {
+	field, ok := o.field(name.Lexeme)
+	if !ok {
+		return NewRuntimeError(name, "Undefined field '"+name.Lexeme+"'.")
+	}
+
+	if !field.CanSet() {
+		return NewRuntimeError(name, "Field '"+name.Lexeme+"' is read-only. Bind a pointer to make fields assignable.")
+	}
+
+	converted, err := o.interpreter.fromLox(value, field.Type())
+	if err != nil {
+		return NewRuntimeError(name, fmt.Sprintf("Cannot assign to field '%s': %s.", name.Lexeme, err))
+	}
+
+	field.Set(converted)
+	return nil
+}
+
+func (o *goObject) properties() []string {
+	var names []string
+
+	s := o.value
+	if s.Kind() == reflect.Pointer {
+		s = s.Elem()
+	}
+	for _, f := range reflect.VisibleFields(s.Type()) {
+		if f.IsExported() && !f.Anonymous {
+			names = append(names, f.Name)
+		}
+	}
+
+	for idx := 0; idx < o.value.NumMethod(); idx++ {
+		names = append(names, o.value.Type().Method(idx).Name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (o *goObject) String() string {
+	return "<go " + o.value.Type().String() + ">"
+}
+
+// field finds an exported field by name.
+func (o *goObject) field(name string) (reflect.Value, bool) {
+	s := o.value
+	if s.Kind() == reflect.Pointer {
+		s = s.Elem()
+	}
+
+	f, ok := s.Type().FieldByName(name)
+	if !ok || !f.IsExported() {
+		return reflect.Value{}, false
+	}
+	field, err := s.FieldByIndexErr(f.Index)
+	if err != nil {
+		// promoted through a nil embedded pointer
+		return reflect.Value{}, false
+	}
+	return field, true
+}
+
+// goFunction is a Go function or bound method seen from Lox.
+type goFunction struct {
+	interpreter *Interpreter
+	name        string
+	fn          reflect.Value
+}
+
+var _ Callable = (*goFunction)(nil)
+
+func (f *goFunction) Arity() int {
+	return f.fn.Type().NumIn()
+}
+
+// Call converts the arguments to the parameter types and the results back. A
+// trailing error result becomes a runtime error; otherwise no results give
+// nil, one gives its value and several give a list. A variadic parameter takes
+// a list.
+func (f *goFunction) Call(_ *Interpreter, arguments []any) (any, error) {
+	t := f.fn.Type()
+
+	in := make([]reflect.Value, len(arguments))
+	for idx, argument := range arguments {
+		value, err := f.interpreter.fromLox(argument, t.In(idx))
+		if err != nil {
+			return nil, nativeErrorf("Argument %d to '%s' %s.", idx+1, f.name, err)
+		}
+		in[idx] = value
+	}
+
+	var out []reflect.Value
+	if t.IsVariadic() {
+		out = f.fn.CallSlice(in)
+	} else {
+		out = f.fn.Call(in)
+	}
+
+	if len(out) > 0 && t.Out(len(out)-1) == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return nil, hostError(err)
+		}
+		out = out[:len(out)-1]
+	}
+
+	results := make([]any, len(out))
+	for idx, value := range out {
+		converted, err := f.interpreter.toLox(value)
+		if err != nil {
+			return nil, nativeErrorf("Result of '%s' %s.", f.name, err)
+		}
+		results[idx] = converted
+	}
+
+	switch len(results) {
+	case 0:
+		return nil, nil
+	case 1:
+		return results[0], nil
+	default:
+		return NewList(results), nil
+	}
+}
+
+func (f *goFunction) String() string {
+	return "<native fn " + f.name + ">"
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// toLox converts a Go value to the Lox value scripts see.
+func (i *Interpreter) toLox(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	// Lox values that went out to Go and came back stay as they are.
+	if v.CanInterface() {
+		switch value := v.Interface().(type) {
+		case Callable, Object, Iterable:
+			return value, nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		elements := make([]any, v.Len())
+		for idx := range elements {
+			element, err := i.toLox(v.Index(idx))
+			if err != nil {
+				return nil, err
+			}
+			elements[idx] = element
+		}
+		return NewList(elements), nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("has unsupported type %s: map keys must be strings", v.Type())
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+		entries := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			value, err := i.toLox(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			entries[iter.Key().String()] = value
+		}
+		return NewMap(entries), nil
+	case reflect.Struct:
+		if v.CanAddr() {
+			return &goObject{interpreter: i, value: v.Addr()}, nil
+		}
+		return &goObject{interpreter: i, value: v}, nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			return &goObject{interpreter: i, value: v}, nil
+		}
+		return i.toLox(v.Elem())
+	case reflect.Interface:
+		return i.toLox(v.Elem())
+	case reflect.Func:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return &goFunction{interpreter: i, name: v.Type().String(), fn: v}, nil
+	default:
+		return nil, fmt.Errorf("has unsupported type %s", v.Type())
+	}
+}
+
+// fromLox converts a Lox value to a Go value of type t. The error describes
+// what was expected, to be completed by the caller, e.g. "must be a string".
+func (i *Interpreter) fromLox(value any, t reflect.Type) (reflect.Value, error) {
+	if t.Kind() == reflect.Interface {
+		if value == nil {
+			return reflect.Zero(t), nil
+		}
+		v := reflect.ValueOf(toGo(value))
+		if !v.Type().Implements(t) {
+			return reflect.Value{}, fmt.Errorf("must implement %s", t)
+		}
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			return reflect.ValueOf(b).Convert(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("must be a bool")
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			return reflect.ValueOf(s).Convert(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("must be a string")
+	case reflect.Float32, reflect.Float64:
+		if n, ok := value.(float64); ok {
+			return reflect.ValueOf(n).Convert(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("must be a number")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 || reflect.Zero(t).OverflowInt(int64(n)) {
+			return reflect.Value{}, fmt.Errorf("must be an integer that fits in %s", t)
+		}
+		return reflect.ValueOf(int64(n)).Convert(t), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := value.(float64)
+		if !ok || n < 0 || n != math.Trunc(n) || n >= math.MaxUint64 || reflect.Zero(t).OverflowUint(uint64(n)) {
+			return reflect.Value{}, fmt.Errorf("must be a non-negative integer that fits in %s", t)
+		}
+		return reflect.ValueOf(uint64(n)).Convert(t), nil
+	case reflect.Slice:
+		list, ok := value.(*List)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("must be a list")
+		}
+		slice := reflect.MakeSlice(t, len(list.Elements), len(list.Elements))
+		for idx, element := range list.Elements {
+			converted, err := i.fromLox(element, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d %w", idx, err)
+			}
+			slice.Index(idx).Set(converted)
+		}
+		return slice, nil
+	case reflect.Map:
+		m, ok := value.(*Map)
+		if !ok || t.Key().Kind() != reflect.String {
+			return reflect.Value{}, fmt.Errorf("must be a map")
+		}
+		result := reflect.MakeMapWithSize(t, len(m.Entries))
+		for key, entry := range m.Entries {
+			converted, err := i.fromLox(entry, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("entry '%s' %w", key, err)
+			}
+			result.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), converted)
+		}
+		return result, nil
+	case reflect.Pointer, reflect.Struct:
+		if object, ok := value.(*goObject); ok {
+			if object.value.Type() == t {
+				return object.value, nil
+			}
+			if t.Kind() == reflect.Struct && object.value.Kind() == reflect.Pointer && object.value.Elem().Type() == t {
+				return object.value.Elem(), nil
+			}
+		}
+		if value == nil && t.Kind() == reflect.Pointer {
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("must be a %s", t)
+	case reflect.Func:
+		return i.callbackFromLox(value, t)
+	default:
+		return reflect.Value{}, fmt.Errorf("has unsupported type %s", t)
+	}
+}
+
+// callbackFromLox wraps a Lox function as a Go function of type t. The Go
+// function must return an error last so failures in the callback can be
+// reported.
+func (i *Interpreter) callbackFromLox(value any, t reflect.Type) (reflect.Value, error) {
+	callable, ok := value.(Callable)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("must be a function")
+	}
+	if t.NumOut() == 0 || t.Out(t.NumOut()-1) != errorType || t.NumOut() > 2 || t.IsVariadic() {
+		return reflect.Value{}, fmt.Errorf("has unsupported function type %s", t)
+	}
+	if callable.Arity() != t.NumIn() {
+		return reflect.Value{}, fmt.Errorf("must take %d arguments", t.NumIn())
+	}
+
+	fn := reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		out := make([]reflect.Value, t.NumOut())
+		for idx := range out {
+			out[idx] = reflect.Zero(t.Out(idx))
+		}
+		fail := func(err error) []reflect.Value {
+			out[len(out)-1] = reflect.ValueOf(&err).Elem()
+			return out
+		}
+
+		arguments := make([]any, len(in))
+		for idx, arg := range in {
+			converted, err := i.toLox(arg)
+			if err != nil {
+				return fail(fmt.Errorf("argument %d %w", idx+1, err))
+			}
+			arguments[idx] = converted
+		}
+
+		result, err := callable.Call(i, arguments)
+		if err != nil {
+			return fail(err)
+		}
+
+		if t.NumOut() == 2 {
+			converted, err := i.fromLox(result, t.Out(0))
+			if err != nil {
+				return fail(fmt.Errorf("callback result %w", err))
+			}
+			out[0] = converted
+		}
+		return out
+	})
+	return fn, nil
+}
+
+// toGo converts Lox collections into plain Go ones for parameters typed as
+// interfaces, so host code does not need to know about List and Map.
+func toGo(value any) any {
+	switch v := value.(type) {
+	case *List:
+		elements := make([]any, len(v.Elements))
+		for idx, element := range v.Elements {
+			elements[idx] = toGo(element)
+		}
+		return elements
+	case *Map:
+		entries := make(map[string]any, len(v.Entries))
+		for key, entry := range v.Entries {
+			entries[key] = toGo(entry)
+		}
+		return entries
+	default:
+		return value
+	}
+}
@@ -0,0 +1,123 @@
+package lox
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAddress struct {
+	City string
+}
+
+type testDB struct {
+	Name    string
+	Count   int
+	Ratio   float32
+	Enabled bool
+	Tags    []string
+	Limits  map[string]int
+	Address testAddress
+	secret  string
+}
+
+func (db *testDB) Query(table string, limit int) ([]string, error) {
+	if table == "" {
+		return nil, errors.New("empty table name")
+	}
+	return []string{table + ":" + strings.Repeat("x", limit)}, nil
+}
+
+func (db *testDB) Each(items []string, fn func(string) error) error {
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *testDB) Describe(value any) string {
+	switch value.(type) {
+	case []any:
+		return "slice"
+	case map[string]any:
+		return "map"
+	default:
+		return "other"
+	}
+}
+
+func TestBind(t *testing.T) {
+	db := &testDB{
+		Name:   "main",
+		Count:  1,
+		Ratio:  0.5,
+		Tags:   []string{"a", "b"},
+		Limits: map[string]int{"rows": 10},
+	}
+
+	var output bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&output))
+	require.NoError(t, interpreter.Bind("db", db))
+
+	err := interpretSource(interpreter, `
+print db.Name + " " + str(db.Count) + " " + str(db.Ratio) + " " + str(db.Enabled);
+print db.Tags;
+print db.Limits.rows;
+db.Count += 41;
+db.Name = "renamed";
+db.Address.City = "Vancouver";
+db.Limits.rows = 20;
+print db.Query("users", 2);
+db.Each(db.Tags, (tag) => { print "tag " + tag; });
+print db.Describe(db.Tags);
+print db.Describe(db.Limits);
+print db;
+
+try { db.Query("", 1); } catch (e) { print e.message; }
+try { db.Query("t", 1.5); } catch (e) { print e.message; }
+try { db.Count = "many"; } catch (e) { print e.message; }
+try { db.secret; } catch (e) { print e.message; }
+`)
+	require.NoError(t, err)
+	assert.Equal(t, `main 1 0.5 false
+[a, b]
+10
+[users:xx]
+tag a
+tag b
+slice
+map
+<go *lox.testDB>
+empty table name
+Argument 2 to 'Query' must be an integer that fits in int.
+Cannot assign to field 'Count': must be an integer that fits in int.
+Undefined property 'secret'.
+`, output.String())
+
+	assert.Equal(t, 42, db.Count)
+	assert.Equal(t, "renamed", db.Name)
+	assert.Equal(t, "Vancouver", db.Address.City)
+	// Maps are copied into Lox, so assigning entries does not touch the Go map.
+	assert.Equal(t, 10, db.Limits["rows"])
+}
+
+func TestBindValueIsReadOnly(t *testing.T) {
+	var output bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&output))
+	require.NoError(t, interpreter.Bind("config", testAddress{City: "Paris"}))
+
+	err := interpretSource(interpreter, `
+print config.City;
+config.City = "Rome";
+`)
+	assert.Equal(t, "Paris\n", output.String())
+	assert.EqualError(t, err, "[line 3] Field 'City' is read-only. Bind a pointer to make fields assignable.")
+
+	assert.ErrorContains(t, interpreter.Bind("ch", make(chan int)), "unsupported type chan int")
+}
@@ -29,27 +29,52 @@ func (c Clock) String() string {
 }
 
 type Function struct {
-	declaration *FunctionDeclStmt
-	closure     *Environment
+	name       string // empty for anonymous functions
+	parameters []Token
+	body       []Stmt
+	closure    *Environment
+	owner      *Interpreter // the interpreter of the module that defined the function
 }
 
 var _ Callable = (*Function)(nil)
 
-func NewFunction(declaration *FunctionDeclStmt, closure *Environment) *Function {
-	return &Function{declaration: declaration, closure: closure}
+func NewFunction(declaration *FunctionDeclStmt, closure *Environment, owner *Interpreter) *Function {
+	return &Function{
+		name:       declaration.Name.Lexeme,
+		parameters: declaration.Parameters,
+		body:       declaration.Body,
+		closure:    closure,
+		owner:      owner,
+	}
+}
+
+func NewLambda(expr *FunctionExpr, closure *Environment, owner *Interpreter) *Function {
+	return &Function{parameters: expr.Parameters, body: expr.Body, closure: closure, owner: owner}
 }
 
 func (f *Function) Arity() int {
-	return len(f.declaration.Parameters)
+	return len(f.parameters)
 }
 
 func (f *Function) Call(interpreter *Interpreter, arguments []any) (any, error) {
 	environment := NewEnvironmentWithEnclosing(f.closure)
-	for i, param := range f.declaration.Parameters {
+	for i, param := range f.parameters {
 		environment.Define(param.Lexeme, arguments[i])
 	}
 
-	err := interpreter.executeBlock(f.declaration.Body, environment)
+	if err := f.owner.limits.enterCall(); err != nil {
+		return nil, err
+	}
+	defer f.owner.limits.exitCall()
+
+	if f.owner.debug != nil {
+		f.owner.debug.enter(f.String())
+		defer f.owner.debug.exit()
+	}
+
+	// The body was resolved against its own module, so it must run there even
+	// when called from another one.
+	err := f.owner.executeBlock(f.body, environment)
 	var returnErr *ReturnError
 	if errors.As(err, &returnErr) {
 		return returnErr.Value, nil
@@ -59,5 +84,9 @@ func (f *Function) Call(interpreter *Interpreter, arguments []any) (any, error)
 }
 
 func (f *Function) String() string {
-	return "<fn " + f.declaration.Name.Lexeme + ">"
+	if f.name == "" {
+		return "<fn anonymous>"
+	}
+
+	return "<fn " + f.name + ">"
 }
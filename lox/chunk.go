@@ -1,6 +1,9 @@
 package lox
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+)
 
 // opcodes
 const (
@@ -9,6 +12,8 @@ const (
 	OP_SUBTRACT
 	OP_MULTIPLY
 	OP_DIVIDE
+	OP_MODULO
+	OP_POWER
 	OP_NEGATE
 	OP_RETURN
 )
@@ -31,52 +36,56 @@ func (c *Chunk) addConstant(value Value) int {
 	return len(c.constants) - 1
 }
 
-func (c *Chunk) disassemble(name string) {
-	fmt.Printf("== %s ==\n", name)
+func (c *Chunk) disassemble(w io.Writer, name string) {
+	fmt.Fprintf(w, "== %s ==\n", name)
 
 	offset := 0
 	for offset < len(c.code) {
-		offset = c.disassembleInstruction(offset)
+		offset = c.disassembleInstruction(w, offset)
 	}
 }
 
-func (c *Chunk) disassembleInstruction(offset int) int {
-	fmt.Printf("%04d ", offset)
+func (c *Chunk) disassembleInstruction(w io.Writer, offset int) int {
+	fmt.Fprintf(w, "%04d ", offset)
 	if offset > 0 && c.lines[offset] == c.lines[offset-1] {
-		fmt.Printf("   | ")
+		fmt.Fprintf(w, "   | ")
 	} else {
-		fmt.Printf("%4d ", c.lines[offset])
+		fmt.Fprintf(w, "%4d ", c.lines[offset])
 	}
 
 	instruction := c.code[offset]
 	switch instruction {
 	case OP_CONSTANT:
-		return c.constantInstruction("OP_CONSTANT", offset)
+		return c.constantInstruction(w, "OP_CONSTANT", offset)
 	case OP_ADD:
-		return simpleInstruction("OP_ADD", offset)
+		return simpleInstruction(w, "OP_ADD", offset)
 	case OP_SUBTRACT:
-		return simpleInstruction("OP_SUBTRACT", offset)
+		return simpleInstruction(w, "OP_SUBTRACT", offset)
 	case OP_MULTIPLY:
-		return simpleInstruction("OP_MULTIPLY", offset)
+		return simpleInstruction(w, "OP_MULTIPLY", offset)
 	case OP_DIVIDE:
-		return simpleInstruction("OP_DIVIDE", offset)
+		return simpleInstruction(w, "OP_DIVIDE", offset)
+	case OP_MODULO:
+		return simpleInstruction(w, "OP_MODULO", offset)
+	case OP_POWER:
+		return simpleInstruction(w, "OP_POWER", offset)
 	case OP_NEGATE:
-		return simpleInstruction("OP_NEGATE", offset)
+		return simpleInstruction(w, "OP_NEGATE", offset)
 	case OP_RETURN:
-		return simpleInstruction("OP_RETURN", offset)
+		return simpleInstruction(w, "OP_RETURN", offset)
 	default:
-		fmt.Printf("Unknown opcode %d\n", instruction)
+		fmt.Fprintf(w, "Unknown opcode %d\n", instruction)
 		return offset + 1
 	}
 }
 
-func (c *Chunk) constantInstruction(name string, offset int) int {
+func (c *Chunk) constantInstruction(w io.Writer, name string, offset int) int {
 	constant := c.code[offset+1]
-	fmt.Printf("%-16s %4d '%v'\n", name, constant, c.constants[constant])
+	fmt.Fprintf(w, "%-16s %4d '%v'\n", name, constant, c.constants[constant])
 	return offset + 2
 }
 
-func simpleInstruction(name string, offset int) int {
-	fmt.Println(name)
+func simpleInstruction(w io.Writer, name string, offset int) int {
+	fmt.Fprintln(w, name)
 	return offset + 1
 }
@@ -2,11 +2,14 @@ package lox
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"os"
 )
 
 type compiler struct {
+	stdout         io.Writer // where DEBUG_PRINT_CODE listings go
+	stderr         io.Writer // where compile errors are reported
 	scanner        *Scanner
 	compilingChunk *Chunk
 	current        Token
@@ -15,8 +18,10 @@ type compiler struct {
 	rules          map[TokenType]parseRule
 }
 
-func compile(source string, chunk *Chunk) bool {
+func compile(source string, chunk *Chunk, opts options) bool {
 	c := &compiler{
+		stdout:         opts.stdout,
+		stderr:         opts.stderr,
 		scanner:        NewScanner(source),
 		compilingChunk: chunk,
 	}
@@ -26,6 +31,8 @@ func compile(source string, chunk *Chunk) bool {
 		RIGHT_PAREN:   {nil, nil, PREC_NONE},
 		LEFT_BRACE:    {nil, nil, PREC_NONE},
 		RIGHT_BRACE:   {nil, nil, PREC_NONE},
+		LEFT_BRACKET:  {nil, nil, PREC_NONE},
+		RIGHT_BRACKET: {nil, nil, PREC_NONE},
 		COMMA:         {nil, nil, PREC_NONE},
 		DOT:           {nil, nil, PREC_NONE},
 		MINUS:         {c.unary, c.binary, PREC_TERM},
@@ -33,31 +40,49 @@ func compile(source string, chunk *Chunk) bool {
 		SEMICOLON:     {nil, nil, PREC_NONE},
 		SLASH:         {nil, c.binary, PREC_FACTOR},
 		STAR:          {nil, c.binary, PREC_FACTOR},
+		PERCENT:       {nil, c.binary, PREC_FACTOR},
+		QUESTION:      {nil, nil, PREC_NONE},
+		COLON:         {nil, nil, PREC_NONE},
 		BANG:          {nil, nil, PREC_NONE},
 		BANG_EQUAL:    {nil, nil, PREC_NONE},
 		EQUAL:         {nil, nil, PREC_NONE},
 		EQUAL_EQUAL:   {nil, nil, PREC_NONE},
+		ARROW:         {nil, nil, PREC_NONE},
 		GREATER:       {nil, nil, PREC_NONE},
 		GREATER_EQUAL: {nil, nil, PREC_NONE},
 		LESS:          {nil, nil, PREC_NONE},
 		LESS_EQUAL:    {nil, nil, PREC_NONE},
+		PLUS_EQUAL:    {nil, nil, PREC_NONE},
+		MINUS_EQUAL:   {nil, nil, PREC_NONE},
+		STAR_EQUAL:    {nil, nil, PREC_NONE},
+		SLASH_EQUAL:   {nil, nil, PREC_NONE},
+		PERCENT_EQUAL: {nil, nil, PREC_NONE},
+		STAR_STAR:     {nil, c.binary, PREC_POWER},
 		IDENTIFIER:    {nil, nil, PREC_NONE},
 		STRING:        {nil, nil, PREC_NONE},
+		INTERPOLATION: {nil, nil, PREC_NONE},
 		NUMBER:        {c.number, nil, PREC_NONE},
 		AND:           {nil, nil, PREC_NONE},
+		CATCH:         {nil, nil, PREC_NONE},
 		CLASS:         {nil, nil, PREC_NONE},
 		ELSE:          {nil, nil, PREC_NONE},
+		EXPORT:        {nil, nil, PREC_NONE},
 		FALSE:         {nil, nil, PREC_NONE},
+		FINALLY:       {nil, nil, PREC_NONE},
 		FOR:           {nil, nil, PREC_NONE},
 		FUN:           {nil, nil, PREC_NONE},
 		IF:            {nil, nil, PREC_NONE},
+		IMPORT:        {nil, nil, PREC_NONE},
+		IN:            {nil, nil, PREC_NONE},
 		NIL:           {nil, nil, PREC_NONE},
 		OR:            {nil, nil, PREC_NONE},
 		PRINT:         {nil, nil, PREC_NONE},
 		RETURN:        {nil, nil, PREC_NONE},
 		SUPER:         {nil, nil, PREC_NONE},
 		THIS:          {nil, nil, PREC_NONE},
+		THROW:         {nil, nil, PREC_NONE},
 		TRUE:          {nil, nil, PREC_NONE},
+		TRY:           {nil, nil, PREC_NONE},
 		VAR:           {nil, nil, PREC_NONE},
 		WHILE:         {nil, nil, PREC_NONE},
 		EOF:           {nil, nil, PREC_NONE},
@@ -80,7 +105,7 @@ func (c *compiler) advance() {
 			break
 		}
 
-		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(c.stderr, err)
 	}
 }
 
@@ -98,8 +123,9 @@ const (
 	PREC_EQUALITY              // == !=
 	PREC_COMPARISON            // < > <= >=
 	PREC_TERM                  // + -
-	PREC_FACTOR                // * /
+	PREC_FACTOR                // * / %
 	PREC_UNARY                 // ! -
+	PREC_POWER                 // **
 	PREC_CALL                  // . ()
 	PREC_PRIMARY
 )
@@ -157,7 +183,12 @@ func (c *compiler) unary() {
 func (c *compiler) binary() {
 	operatorType := c.previous.Type
 	rule := c.getRule(operatorType)
-	c.parsePrecedence(rule.precedence + 1)
+	if operatorType == STAR_STAR {
+		// right-associative
+		c.parsePrecedence(rule.precedence)
+	} else {
+		c.parsePrecedence(rule.precedence + 1)
+	}
 
 	switch operatorType {
 	case PLUS:
@@ -168,6 +199,10 @@ func (c *compiler) binary() {
 		c.emitByte(OP_MULTIPLY)
 	case SLASH:
 		c.emitByte(OP_DIVIDE)
+	case PERCENT:
+		c.emitByte(OP_MODULO)
+	case STAR_STAR:
+		c.emitByte(OP_POWER)
 	}
 }
 
@@ -190,15 +225,15 @@ func (c *compiler) errorAtCurrent(message string) {
 }
 
 func (c *compiler) errorAt(token Token, message string) {
-	fmt.Fprintf(os.Stderr, "[line %d] Error", token.Line)
+	fmt.Fprintf(c.stderr, "[line %d] Error", token.Line)
 
 	if token.Type == EOF {
-		fmt.Fprint(os.Stderr, " at end")
+		fmt.Fprint(c.stderr, " at end")
 	} else {
-		fmt.Fprintf(os.Stderr, " at '%s'", token.Lexeme)
+		fmt.Fprintf(c.stderr, " at '%s'", token.Lexeme)
 	}
 
-	fmt.Fprintf(os.Stderr, ": %s\n", message)
+	fmt.Fprintf(c.stderr, ": %s\n", message)
 	c.hadError = true
 }
 
@@ -219,7 +254,7 @@ func (c *compiler) end() {
 	c.emitReturn()
 
 	if os.Getenv("DEBUG_PRINT_CODE") == "1" && !c.hadError {
-		c.currentChunk().disassemble("code")
+		c.currentChunk().disassemble(c.stdout, "code")
 	}
 }
 
@@ -0,0 +1,299 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Debugger is notified before each statement the interpreter executes once it
+// is attached with SetDebugger. With no debugger attached, the interpreter
+// pays only for a nil check per statement.
+type Debugger interface {
+	// Statement is called before a statement runs. The debugger pauses the
+	// script by not returning, and can inspect it through state meanwhile.
+	// Returning an error stops the script with that error.
+	Statement(state *DebugState) error
+}
+
+// DebugFrame is one entry of the call stack.
+type DebugFrame struct {
+	Function string // "<script>", "<module name>" or the function's name
+	Path     string // the file the code is in, or "" if it was not read from one
+	Line     int    // the line executing in this frame
+}
+
+// DebugVariable is a variable visible where the script is paused.
+type DebugVariable struct {
+	Name  string
+	Value any
+}
+
+// debugHook is the debugging state shared by an interpreter and the modules
+// it imports.
+type debugHook struct {
+	debugger Debugger
+	frames   []DebugFrame
+
+	// paused is set while the debugger has control, so that code it runs,
+	// such as a function called from a watch expression, does not call back
+	// into it.
+	paused bool
+}
+
+// SetDebugger attaches a debugger, or detaches it when debugger is nil.
+func (i *Interpreter) SetDebugger(debugger Debugger) {
+	if debugger == nil {
+		i.debug = nil
+		return
+	}
+
+	i.debug = &debugHook{
+		debugger: debugger,
+		frames:   []DebugFrame{{Function: "<script>"}},
+	}
+}
+
+func (h *debugHook) statement(interpreter *Interpreter, stmt Stmt) error {
+	// Blocks are not paused at; the statements inside them are.
+	if _, ok := stmt.(*BlockStmt); ok || h.paused {
+		return nil
+	}
+
+	line := stmtLine(stmt)
+	frame := &h.frames[len(h.frames)-1]
+	frame.Path = interpreter.path
+	frame.Line = line
+
+	h.paused = true
+	defer func() { h.paused = false }()
+
+	return h.debugger.Statement(&DebugState{
+		Line:        line,
+		Path:        interpreter.path,
+		interpreter: interpreter,
+		hook:        h,
+	})
+}
+
+func (h *debugHook) enter(function string) {
+	h.frames = append(h.frames, DebugFrame{Function: function})
+}
+
+func (h *debugHook) exit() {
+	h.frames = h.frames[:len(h.frames)-1]
+}
+
+// DebugState is the paused script as seen by a Debugger. It is only valid
+// during the call to Statement it was passed to.
+type DebugState struct {
+	Line int    // the line of the statement about to run
+	Path string // the file that statement is in
+
+	interpreter *Interpreter
+	hook        *debugHook
+}
+
+// Depth is the number of calls in progress, 1 at the top level.
+func (s *DebugState) Depth() int {
+	return len(s.hook.frames)
+}
+
+// Backtrace returns the call stack, innermost frame first.
+func (s *DebugState) Backtrace() []DebugFrame {
+	frames := make([]DebugFrame, len(s.hook.frames))
+	for idx, frame := range s.hook.frames {
+		frames[len(frames)-1-idx] = frame
+	}
+	return frames
+}
+
+// Locals returns the variables of the enclosing scopes up to but not
+// including the globals, innermost scope first and sorted by name within a
+// scope. A variable shadowed by an inner one is left out.
+func (s *DebugState) Locals() []DebugVariable {
+	var variables []DebugVariable
+	seen := make(map[string]bool)
+
+	for env := s.interpreter.environment; env != nil && env != s.interpreter.globals; env = env.enclosing {
+		variables = append(variables, scopeVariables(env, seen)...)
+	}
+	return variables
+}
+
+// Globals returns the global variables of the module being executed, sorted
+// by name.
+func (s *DebugState) Globals() []DebugVariable {
+	return scopeVariables(s.interpreter.globals, make(map[string]bool))
+}
+
+func scopeVariables(env *Environment, seen map[string]bool) []DebugVariable {
+	var variables []DebugVariable
+	for name, value := range env.values {
+		if !seen[name] {
+			seen[name] = true
+			variables = append(variables, DebugVariable{Name: name, Value: value})
+		}
+	}
+	sort.Slice(variables, func(a, b int) bool { return variables[a].Name < variables[b].Name })
+	return variables
+}
+
+// Evaluate evaluates an expression where the script is paused, so it can use
+// the local variables in scope there.
+func (s *DebugState) Evaluate(source string) (any, error) {
+	tokens, errs := NewScanner(source).ScanTokens()
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	parser := NewParser(tokens)
+	expr, err := parser.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !parser.isAtEnd() {
+		return nil, NewParseError(parser.peek(), "Expect end of expression.")
+	}
+
+	// Rebuild the resolver's scopes from the environment chain so that names
+	// resolve to the environments they live in at this point.
+	resolver := newResolver(s.interpreter.locals)
+	for env := s.interpreter.environment; env != nil && env != s.interpreter.globals; env = env.enclosing {
+		scope := make(map[string]bool)
+		for name := range env.values {
+			scope[name] = true
+		}
+		resolver.scopes = append([]map[string]bool{scope}, resolver.scopes...)
+	}
+	if err := resolver.resolveExpr(expr); err != nil {
+		return nil, err
+	}
+
+	value, err := s.interpreter.Evaluate(expr)
+	var nativeErr *nativeError
+	if errors.As(err, &nativeErr) {
+		return nil, errors.New(nativeErr.message)
+	}
+	return value, err
+}
+
+// DebugMembers returns the elements of a list, or the properties of a map or
+// other object, sorted by name. It returns nil for values without members.
+func DebugMembers(value any) []DebugVariable {
+	switch value := value.(type) {
+	case *List:
+		variables := make([]DebugVariable, len(value.Elements))
+		for idx, element := range value.Elements {
+			variables[idx] = DebugVariable{Name: fmt.Sprintf("[%d]", idx), Value: element}
+		}
+		return variables
+	case propertyLister:
+		object, ok := value.(Object)
+		if !ok {
+			return nil
+		}
+
+		var variables []DebugVariable
+		for _, name := range value.properties() {
+			member, err := object.Get(Token{Type: IDENTIFIER, Lexeme: name})
+			if err == nil {
+				variables = append(variables, DebugVariable{Name: name, Value: member})
+			}
+		}
+		return variables
+	}
+	return nil
+}
+
+// Stringify formats a value the way print does.
+func Stringify(value any) string {
+	return stringify(value)
+}
+
+// stmtLine returns the line a statement starts on, or 0 if it has no tokens
+// to tell.
+func stmtLine(stmt Stmt) int {
+	return stmtStart(stmt).Line
+}
+
+// stmtStart returns the first token of a statement that the AST keeps, or the
+// zero Token if it keeps none.
+func stmtStart(stmt Stmt) Token {
+	switch stmt := stmt.(type) {
+	case *ExprStmt:
+		return exprStart(stmt.Expression)
+	case *PrintStmt:
+		return stmt.Keyword
+	case *VarDeclStmt:
+		return stmt.Name
+	case *BlockStmt:
+		if len(stmt.Statements) > 0 {
+			return stmtStart(stmt.Statements[0])
+		}
+	case *IfStmt:
+		return stmt.Keyword
+	case *WhileStmt:
+		return stmt.Keyword
+	case *FunctionDeclStmt:
+		return stmt.Name
+	case *ReturnStmt:
+		return stmt.Keyword
+	case *ForInStmt:
+		return stmt.Name
+	case *ThrowStmt:
+		return stmt.Keyword
+	case *TryStmt:
+		return stmt.Keyword
+	case *ImportStmt:
+		return stmt.Keyword
+	case *ExportStmt:
+		return stmt.Keyword
+	}
+	return Token{}
+}
+
+// exprStart returns the first token of an expression that the AST keeps, or
+// the zero Token if it keeps none, as for the condition of for (;;).
+func exprStart(expr Expr) Token {
+	switch expr := expr.(type) {
+	case *BinaryExpr:
+		return firstToken(exprStart(expr.Left), expr.Operator)
+	case *GroupingExpr:
+		return exprStart(expr.Expression)
+	case *LiteralExpr:
+		return expr.Token
+	case *UnaryExpr:
+		return expr.Operator
+	case *VariableExpr:
+		return expr.Name
+	case *AssignExpr:
+		return expr.Name
+	case *LogicalExpr:
+		return firstToken(exprStart(expr.Left), expr.Operator)
+	case *CallExpr:
+		return firstToken(exprStart(expr.Callee), expr.Paren)
+	case *FunctionExpr:
+		return expr.Keyword
+	case *GetExpr:
+		return firstToken(exprStart(expr.Object), expr.Name)
+	case *ConditionalExpr:
+		return exprStart(expr.Condition)
+	case *StringifyExpr:
+		return exprStart(expr.Expression)
+	case *SetExpr:
+		return firstToken(exprStart(expr.Object), expr.Name)
+	case *IndexExpr:
+		return firstToken(exprStart(expr.Object), expr.Bracket)
+	case *IndexSetExpr:
+		return firstToken(exprStart(expr.Object), expr.Bracket)
+	}
+	return Token{}
+}
+
+func firstToken(token, fallback Token) Token {
+	if token.Line > 0 {
+		return token
+	}
+	return fallback
+}
@@ -0,0 +1,99 @@
+package lox
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// debuggerFunc adapts a function to the Debugger interface.
+type debuggerFunc func(state *DebugState) error
+
+func (f debuggerFunc) Statement(state *DebugState) error {
+	return f(state)
+}
+
+func TestDebuggerSeesEachStatement(t *testing.T) {
+	var lines []int
+	interpreter := NewInterpreter(WithStdout(&bytes.Buffer{}))
+	interpreter.SetDebugger(debuggerFunc(func(state *DebugState) error {
+		lines = append(lines, state.Line)
+		return nil
+	}))
+
+	require.NoError(t, interpretSource(interpreter, "var a = 1;\nif (a > 0) {\n  print a;\n}\nwhile (a < 3)\n  a = a + 1;\n"))
+	assert.Equal(t, []int{1, 2, 3, 5, 6, 6}, lines)
+}
+
+func TestDebugStateInspection(t *testing.T) {
+	source := `var g = 10;
+fun outer(x) {
+  var y = x * 2;
+  {
+    var x = "shadow";
+    inner(y);
+  }
+}
+fun inner(z) {
+  print z;
+}
+outer(1);
+`
+	var stdout bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&stdout))
+	stopped := false
+	interpreter.SetDebugger(debuggerFunc(func(state *DebugState) error {
+		if state.Line != 6 {
+			return nil
+		}
+		stopped = true
+
+		assert.Equal(t, 2, state.Depth())
+		assert.Equal(t, []DebugFrame{
+			{Function: "<fn outer>", Line: 6},
+			{Function: "<script>", Line: 12},
+		}, state.Backtrace())
+		assert.Equal(t, []DebugVariable{
+			{Name: "x", Value: "shadow"},
+			{Name: "y", Value: 2.0},
+		}, state.Locals())
+		assert.Contains(t, state.Globals(), DebugVariable{Name: "g", Value: 10.0})
+
+		value, err := state.Evaluate("y + g")
+		require.NoError(t, err)
+		assert.Equal(t, 12.0, value)
+
+		value, err = state.Evaluate("inner(x)")
+		require.NoError(t, err)
+		assert.Nil(t, value)
+
+		_, err = state.Evaluate("y +")
+		assert.Error(t, err)
+		_, err = state.Evaluate("y + x")
+		assert.EqualError(t, err, "[line 1] Operands must be two numbers or two strings.")
+		return nil
+	}))
+
+	require.NoError(t, interpretSource(interpreter, source))
+	assert.True(t, stopped)
+	assert.Equal(t, "shadow\n2\n", stdout.String())
+}
+
+func TestDebuggerErrorStopsScript(t *testing.T) {
+	stop := errors.New("stop")
+	var stdout bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&stdout))
+	interpreter.SetDebugger(debuggerFunc(func(state *DebugState) error {
+		if state.Line == 2 {
+			return stop
+		}
+		return nil
+	}))
+
+	err := interpretSource(interpreter, "print 1;\nprint 2;\n")
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, "1\n", stdout.String())
+}
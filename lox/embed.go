@@ -0,0 +1,84 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// DefineNative exposes a Go function to scripts as a global named name. The
+// function receives exactly arity arguments. A non-nil error, or a result that
+// cannot be converted to a Lox value, is reported as a runtime error at the
+// call site, where scripts can catch it.
+func (i *Interpreter) DefineNative(name string, arity int, fn func(args []any) (any, error)) {
+	i.globals.Define(name, &NativeFunction{
+		name:  name,
+		arity: arity,
+		fn: func(_ *Interpreter, arguments []any) (any, error) {
+			value, err := fn(arguments)
+			if err != nil {
+				return nil, hostError(err)
+			}
+			converted, err := i.toLox(reflect.ValueOf(value))
+			if err != nil {
+				return nil, nativeErrorf("%s() result %s.", name, err)
+			}
+			return converted, nil
+		},
+	})
+}
+
+// SetGlobal defines or replaces a global variable. The value is converted as
+// Bind converts it; an error means its type has no Lox equivalent.
+func (i *Interpreter) SetGlobal(name string, value any) error {
+	converted, err := i.toLox(reflect.ValueOf(value))
+	if err != nil {
+		return fmt.Errorf("set %s: value %w", name, err)
+	}
+
+	i.globals.Define(name, converted)
+	return nil
+}
+
+// GetGlobal returns the value of a global variable and whether it is defined.
+func (i *Interpreter) GetGlobal(name string) (any, bool) {
+	value, ok := i.globals.values[name]
+	return value, ok
+}
+
+// Call invokes a Lox function, such as a callback a script passed to a native,
+// with the given arguments.
+func (i *Interpreter) Call(fn any, args ...any) (any, error) {
+	callable, ok := fn.(Callable)
+	if !ok {
+		return nil, fmt.Errorf("Can only call functions and classes, got %s.", stringify(fn))
+	}
+
+	if len(args) != callable.Arity() {
+		return nil, fmt.Errorf("Expected %d arguments but got %d.", callable.Arity(), len(args))
+	}
+
+	arguments := make([]any, len(args))
+	for idx, arg := range args {
+		argument, err := i.toLox(reflect.ValueOf(arg))
+		if err != nil {
+			return nil, fmt.Errorf("argument %d %w", idx+1, err)
+		}
+		arguments[idx] = argument
+	}
+
+	return callable.Call(i, arguments)
+}
+
+// hostError turns an error returned by host code into one reported at the
+// call site. Errors that came from the interpreter itself, for example from a
+// callback run through Call, are passed along untouched.
+func hostError(err error) error {
+	var runtimeErr *RuntimeError
+	var throwErr *ThrowError
+	var nativeErr *nativeError
+	if errors.As(err, &runtimeErr) || errors.As(err, &throwErr) || errors.As(err, &nativeErr) || isFatal(err) {
+		return err
+	}
+	return &nativeError{message: err.Error()}
+}
@@ -0,0 +1,107 @@
+package lox
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedding(t *testing.T) {
+	var output bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&output))
+	require.NoError(t, interpreter.SetGlobal("limit", 3))
+
+	var callbacks []any
+	interpreter.DefineNative("onEvent", 1, func(args []any) (any, error) {
+		callbacks = append(callbacks, args[0])
+		return len(callbacks), nil
+	})
+	interpreter.DefineNative("fail", 0, func(args []any) (any, error) {
+		return nil, errors.New("host failure")
+	})
+
+	err := interpretSource(interpreter, `
+print onEvent((x) => x * limit) + 1;
+try { fail(); } catch (e) { print e.message; }
+var result = "set by script";
+`)
+	require.NoError(t, err)
+	assert.Equal(t, "2\nhost failure\n", output.String())
+
+	result, ok := interpreter.GetGlobal("result")
+	assert.True(t, ok)
+	assert.Equal(t, "set by script", result)
+
+	_, ok = interpreter.GetGlobal("missing")
+	assert.False(t, ok)
+
+	require.Len(t, callbacks, 1)
+	value, err := interpreter.Call(callbacks[0], 7)
+	require.NoError(t, err)
+	assert.Equal(t, 21.0, value)
+
+	_, err = interpreter.Call(callbacks[0])
+	assert.EqualError(t, err, "Expected 1 arguments but got 0.")
+
+	_, err = interpreter.Call("not a function")
+	assert.EqualError(t, err, "Can only call functions and classes, got not a function.")
+}
+
+func TestEmbeddingCollections(t *testing.T) {
+	var output bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&output))
+	require.NoError(t, interpreter.SetGlobal("xs", []int{1, 2, 3}))
+	require.NoError(t, interpreter.SetGlobal("m", map[string]int{"a": 1}))
+	interpreter.DefineNative("pair", 0, func(args []any) (any, error) {
+		return []string{"x", "y"}, nil
+	})
+	interpreter.DefineNative("counts", 0, func(args []any) (any, error) {
+		return map[string]float64{"n": 2}, nil
+	})
+
+	require.NoError(t, interpretSource(interpreter, `
+print xs == xs;
+print xs[0] + xs[2];
+print m.a + m["a"];
+var p = pair();
+print p == pair();
+print p[0] + p[1];
+print counts().n;
+fun describe(list, map) { return str(len(list)) + " " + str(map.k); }
+`))
+	assert.Equal(t, "true\n4\n2\nfalse\nxy\n2\n", output.String())
+
+	describe, ok := interpreter.GetGlobal("describe")
+	require.True(t, ok)
+	result, err := interpreter.Call(describe, []bool{true, false}, map[string]string{"k": "v"})
+	require.NoError(t, err)
+	assert.Equal(t, "2 v", result)
+}
+
+func TestEmbeddingUnsupportedValues(t *testing.T) {
+	var output bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&output))
+
+	err := interpreter.SetGlobal("ch", make(chan int))
+	assert.EqualError(t, err, "set ch: value has unsupported type chan int")
+	err = interpreter.SetGlobal("byID", map[int]string{})
+	assert.EqualError(t, err, "set byID: value has unsupported type map[int]string: map keys must be strings")
+
+	interpreter.DefineNative("channel", 0, func(args []any) (any, error) {
+		return make(chan int), nil
+	})
+	err = interpretSource(interpreter, `
+try { channel(); } catch (e) { print e.message; }
+fun id(x) { return x; }
+`)
+	require.NoError(t, err)
+	assert.Equal(t, "channel() result has unsupported type chan int.\n", output.String())
+
+	id, ok := interpreter.GetGlobal("id")
+	require.True(t, ok)
+	_, err = interpreter.Call(id, []chan int{nil})
+	assert.EqualError(t, err, "argument 1 has unsupported type chan int")
+}
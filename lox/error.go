@@ -1,6 +1,25 @@
 package lox
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ScanError is an error in the source text itself, such as an unexpected
+// character or an unterminated string.
+type ScanError struct {
+	Line    int
+	Column  int
+	message string
+}
+
+func NewScanError(line, column int, message string) *ScanError {
+	return &ScanError{Line: line, Column: column, message: message}
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("[line %d] Error: %s", e.Line, e.message)
+}
 
 type ParseError struct {
 	token   Token
@@ -19,6 +38,24 @@ func (e *ParseError) Error() string {
 	return fmt.Sprintf("[line %d] Error at '%s': %s", e.token.Line, e.token.Lexeme, e.message)
 }
 
+// TypeError is a type mismatch found by the TypeChecker. It spans the token
+// it is reported at.
+type TypeError struct {
+	Line    int
+	Column  int
+	Length  int
+	lexeme  string
+	message string
+}
+
+func NewTypeError(token Token, message string) *TypeError {
+	return &TypeError{Line: token.Line, Column: token.Column, Length: len(token.Lexeme), lexeme: token.Lexeme, message: message}
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("[line %d] Error at '%s': %s", e.Line, e.lexeme, e.message)
+}
+
 type RuntimeError struct {
 	token   Token
 	message string
@@ -32,6 +69,40 @@ func (e *RuntimeError) Error() string {
 	return fmt.Sprintf("[line %d] %s", e.token.Line, e.message)
 }
 
+// ThrowError unwinds the stack from a throw statement to the nearest enclosing
+// catch clause.
+type ThrowError struct {
+	token Token
+	Value any
+}
+
+func NewThrowError(token Token, value any) *ThrowError {
+	return &ThrowError{token: token, Value: value}
+}
+
+func (e *ThrowError) Error() string {
+	// Rethrowing a caught runtime error reports it where it first happened.
+	if val, ok := e.Value.(*ErrorValue); ok {
+		return fmt.Sprintf("[line %d] %s", val.Line, val.Message)
+	}
+
+	return fmt.Sprintf("[line %d] Uncaught exception: %s", e.token.Line, stringify(e.Value))
+}
+
+// ExitError stops the script when it calls exit(). It is not catchable by
+// try/catch.
+type ExitError struct {
+	Code int
+}
+
+func NewExitError(code int) *ExitError {
+	return &ExitError{Code: code}
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
 type ReturnError struct {
 	Value any
 }
@@ -43,3 +114,90 @@ func NewReturnError(value any) *ReturnError {
 func (e *ReturnError) Error() string {
 	return fmt.Sprintf("return %v", e.Value)
 }
+
+// InterruptedError stops a script when the context passed to InterpretContext
+// is canceled or its deadline passes. It unwraps to the context's error.
+type InterruptedError struct {
+	Err error
+}
+
+func NewInterruptedError(err error) *InterruptedError {
+	return &InterruptedError{Err: err}
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("Script interrupted: %s.", e.Err)
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// StepLimitError stops a script that executes more statements than allowed by
+// WithMaxSteps.
+type StepLimitError struct {
+	Limit int
+}
+
+func NewStepLimitError(limit int) *StepLimitError {
+	return &StepLimitError{Limit: limit}
+}
+
+func (e *StepLimitError) Error() string {
+	return fmt.Sprintf("Step limit of %d exceeded.", e.Limit)
+}
+
+// CallDepthError stops a script whose calls nest deeper than allowed by
+// WithMaxCallDepth.
+type CallDepthError struct {
+	Limit int
+}
+
+func NewCallDepthError(limit int) *CallDepthError {
+	return &CallDepthError{Limit: limit}
+}
+
+func (e *CallDepthError) Error() string {
+	return fmt.Sprintf("Call depth limit of %d exceeded.", e.Limit)
+}
+
+// AllocationLimitError stops a script that creates more objects than allowed
+// by WithMaxAllocations.
+type AllocationLimitError struct {
+	Limit int
+}
+
+func NewAllocationLimitError(limit int) *AllocationLimitError {
+	return &AllocationLimitError{Limit: limit}
+}
+
+func (e *AllocationLimitError) Error() string {
+	return fmt.Sprintf("Allocation limit of %d exceeded.", e.Limit)
+}
+
+// MemoryLimitError stops a script that allocates more bytes than allowed by
+// WithMaxMemory.
+type MemoryLimitError struct {
+	Limit int
+}
+
+func NewMemoryLimitError(limit int) *MemoryLimitError {
+	return &MemoryLimitError{Limit: limit}
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("Memory limit of %d bytes exceeded.", e.Limit)
+}
+
+// isFatal reports whether err must stop the whole script: it cannot be caught
+// by try/catch and is passed through module and host boundaries unchanged.
+func isFatal(err error) bool {
+	var exitErr *ExitError
+	var interruptedErr *InterruptedError
+	var stepErr *StepLimitError
+	var depthErr *CallDepthError
+	var allocationErr *AllocationLimitError
+	var memoryErr *MemoryLimitError
+	return errors.As(err, &exitErr) || errors.As(err, &interruptedErr) || errors.As(err, &stepErr) ||
+		errors.As(err, &depthErr) || errors.As(err, &allocationErr) || errors.As(err, &memoryErr)
+}
@@ -10,10 +10,12 @@ package lox
 // unary          → ( "-" | "!" ) expression ;
 // binary         → expression operator expression ;
 // operator       → "==" | "!=" | "<" | "<=" | ">" | ">="
-//                | "+"  | "-"  | "*" | "/" ;
+//                | "+"  | "-"  | "*" | "/" | "%" | "**" ;
 
+// Expr is an expression node. Accept calls the visitor's method for the
+// node's type and returns its result.
 type Expr interface {
-	accept(visitor exprVisitor) (any, error)
+	Accept(visitor ExprVisitor) (any, error)
 }
 
 type BinaryExpr struct {
@@ -26,8 +28,8 @@ func NewBinaryExpr(left Expr, operator Token, right Expr) *BinaryExpr {
 	return &BinaryExpr{Left: left, Operator: operator, Right: right}
 }
 
-func (expr *BinaryExpr) accept(visitor exprVisitor) (any, error) {
-	return visitor.visitBinaryExpr(expr)
+func (expr *BinaryExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitBinaryExpr(expr)
 }
 
 type GroupingExpr struct {
@@ -38,20 +40,21 @@ func NewGroupingExpr(expression Expr) *GroupingExpr {
 	return &GroupingExpr{Expression: expression}
 }
 
-func (expr *GroupingExpr) accept(visitor exprVisitor) (any, error) {
-	return visitor.visitGroupingExpr(expr)
+func (expr *GroupingExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitGroupingExpr(expr)
 }
 
 type LiteralExpr struct {
+	Token Token // zero for the condition the parser supplies to for (;;)
 	Value Literal
 }
 
-func NewLiteralExpr(value Literal) *LiteralExpr {
-	return &LiteralExpr{Value: value}
+func NewLiteralExpr(token Token, value Literal) *LiteralExpr {
+	return &LiteralExpr{Token: token, Value: value}
 }
 
-func (expr *LiteralExpr) accept(visitor exprVisitor) (any, error) {
-	return visitor.visitLiteralExpr(expr)
+func (expr *LiteralExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitLiteralExpr(expr)
 }
 
 type UnaryExpr struct {
@@ -63,8 +66,8 @@ func NewUnaryExpr(operator Token, right Expr) *UnaryExpr {
 	return &UnaryExpr{Operator: operator, Right: right}
 }
 
-func (expr *UnaryExpr) accept(visitor exprVisitor) (any, error) {
-	return visitor.visitUnaryExpr(expr)
+func (expr *UnaryExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitUnaryExpr(expr)
 }
 
 type VariableExpr struct {
@@ -75,21 +78,22 @@ func NewVariableExpr(name Token) *VariableExpr {
 	return &VariableExpr{Name: name}
 }
 
-func (expr *VariableExpr) accept(visitor exprVisitor) (any, error) {
-	return visitor.visitVariableExpr(expr)
+func (expr *VariableExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitVariableExpr(expr)
 }
 
 type AssignExpr struct {
-	Name  Token
-	Value Expr
+	Name     Token
+	Operator *Token // the binary operator of a compound assignment, nil for "="
+	Value    Expr
 }
 
-func NewAssignExpr(name Token, value Expr) *AssignExpr {
-	return &AssignExpr{Name: name, Value: value}
+func NewAssignExpr(name Token, operator *Token, value Expr) *AssignExpr {
+	return &AssignExpr{Name: name, Operator: operator, Value: value}
 }
 
-func (expr *AssignExpr) accept(visitor exprVisitor) (any, error) {
-	return visitor.visitAssignExpr(expr)
+func (expr *AssignExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitAssignExpr(expr)
 }
 
 type LogicalExpr struct {
@@ -102,8 +106,8 @@ func NewLogicalExpr(left Expr, operator Token, right Expr) *LogicalExpr {
 	return &LogicalExpr{Left: left, Operator: operator, Right: right}
 }
 
-func (expr *LogicalExpr) accept(visitor exprVisitor) (any, error) {
-	return visitor.visitLogicalExpr(expr)
+func (expr *LogicalExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitLogicalExpr(expr)
 }
 
 type CallExpr struct {
@@ -116,17 +120,128 @@ func NewCallExpr(callee Expr, paren Token, arguments []Expr) *CallExpr {
 	return &CallExpr{Callee: callee, Paren: paren, Arguments: arguments}
 }
 
-func (expr *CallExpr) accept(visitor exprVisitor) (any, error) {
-	return visitor.visitCallExpr(expr)
+func (expr *CallExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitCallExpr(expr)
 }
 
-type exprVisitor interface {
-	visitBinaryExpr(expr *BinaryExpr) (any, error)
-	visitGroupingExpr(expr *GroupingExpr) (any, error)
-	visitLiteralExpr(expr *LiteralExpr) (any, error)
-	visitUnaryExpr(expr *UnaryExpr) (any, error)
-	visitVariableExpr(expr *VariableExpr) (any, error)
-	visitAssignExpr(expr *AssignExpr) (any, error)
-	visitLogicalExpr(expr *LogicalExpr) (any, error)
-	visitCallExpr(expr *CallExpr) (any, error)
+type FunctionExpr struct {
+	Keyword    Token
+	Parameters []Token
+	Types      *FunctionTypes // nil when the function has no annotations
+	Body       []Stmt
+}
+
+func NewFunctionExpr(keyword Token, parameters []Token, body []Stmt) *FunctionExpr {
+	return &FunctionExpr{Keyword: keyword, Parameters: parameters, Body: body}
+}
+
+func (expr *FunctionExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitFunctionExpr(expr)
+}
+
+type GetExpr struct {
+	Object Expr
+	Name   Token
+}
+
+func NewGetExpr(object Expr, name Token) *GetExpr {
+	return &GetExpr{Object: object, Name: name}
+}
+
+func (expr *GetExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitGetExpr(expr)
+}
+
+type ConditionalExpr struct {
+	Condition  Expr
+	ThenBranch Expr
+	ElseBranch Expr
+}
+
+func NewConditionalExpr(condition Expr, thenBranch Expr, elseBranch Expr) *ConditionalExpr {
+	return &ConditionalExpr{Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}
+}
+
+func (expr *ConditionalExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitConditionalExpr(expr)
+}
+
+// StringifyExpr converts the value of Expression to its printed form. The
+// parser produces it when desugaring string interpolation.
+type StringifyExpr struct {
+	Expression Expr
+}
+
+func NewStringifyExpr(expression Expr) *StringifyExpr {
+	return &StringifyExpr{Expression: expression}
+}
+
+func (expr *StringifyExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitStringifyExpr(expr)
+}
+
+type SetExpr struct {
+	Object   Expr
+	Name     Token
+	Operator *Token // the binary operator of a compound assignment, nil for "="
+	Value    Expr
+}
+
+func NewSetExpr(object Expr, name Token, operator *Token, value Expr) *SetExpr {
+	return &SetExpr{Object: object, Name: name, Operator: operator, Value: value}
+}
+
+func (expr *SetExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitSetExpr(expr)
+}
+
+// IndexExpr reads an element of a list or an entry of a map, as in a[i].
+type IndexExpr struct {
+	Object  Expr
+	Bracket Token // the closing "]", where errors are reported
+	Index   Expr
+}
+
+func NewIndexExpr(object Expr, bracket Token, index Expr) *IndexExpr {
+	return &IndexExpr{Object: object, Bracket: bracket, Index: index}
+}
+
+func (expr *IndexExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitIndexExpr(expr)
+}
+
+type IndexSetExpr struct {
+	Object   Expr
+	Bracket  Token
+	Index    Expr
+	Operator *Token // the binary operator of a compound assignment, nil for "="
+	Value    Expr
+}
+
+func NewIndexSetExpr(object Expr, bracket Token, index Expr, operator *Token, value Expr) *IndexSetExpr {
+	return &IndexSetExpr{Object: object, Bracket: bracket, Index: index, Operator: operator, Value: value}
+}
+
+func (expr *IndexSetExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitIndexSetExpr(expr)
+}
+
+// ExprVisitor is implemented by passes over expressions, which must handle
+// every node type. The ast package walks and rewrites trees without one.
+type ExprVisitor interface {
+	VisitBinaryExpr(expr *BinaryExpr) (any, error)
+	VisitGroupingExpr(expr *GroupingExpr) (any, error)
+	VisitLiteralExpr(expr *LiteralExpr) (any, error)
+	VisitUnaryExpr(expr *UnaryExpr) (any, error)
+	VisitVariableExpr(expr *VariableExpr) (any, error)
+	VisitAssignExpr(expr *AssignExpr) (any, error)
+	VisitLogicalExpr(expr *LogicalExpr) (any, error)
+	VisitCallExpr(expr *CallExpr) (any, error)
+	VisitFunctionExpr(expr *FunctionExpr) (any, error)
+	VisitGetExpr(expr *GetExpr) (any, error)
+	VisitSetExpr(expr *SetExpr) (any, error)
+	VisitIndexExpr(expr *IndexExpr) (any, error)
+	VisitIndexSetExpr(expr *IndexSetExpr) (any, error)
+	VisitConditionalExpr(expr *ConditionalExpr) (any, error)
+	VisitStringifyExpr(expr *StringifyExpr) (any, error)
 }
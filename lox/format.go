@@ -0,0 +1,629 @@
+package lox
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Format lays out a script in the canonical style: two-space indentation,
+// one statement per line, block braces on the line that opens them and at
+// most one blank line between statements. Comments are kept, and so is
+// every blank line that separates statements in the source.
+//
+// Strings are written with their escapes in a canonical form, and numbers
+// with as few digits as it takes to read them back.
+func Format(source string) (string, error) {
+	scanner := NewScanner(source)
+	scanner.RecordComments()
+	tokens, errs := scanner.ScanTokens()
+	if len(errs) > 0 {
+		return "", errs[0]
+	}
+
+	statements, err := NewParser(tokens).Parse()
+	if err != nil {
+		return "", err
+	}
+
+	f := &formatter{
+		lines:    strings.Split(source, "\n"),
+		tokens:   tokens,
+		comments: scanner.Comments(),
+		fresh:    true,
+	}
+	f.statements(statements)
+	f.flushComments(len(f.lines) + 1)
+	return f.out.String(), nil
+}
+
+// formatter writes statements out one line at a time. Everything it writes
+// follows the source order, so comments can be placed by line: before each
+// statement and closing brace, the comments on earlier lines are written.
+type formatter struct {
+	out    bytes.Buffer
+	indent int
+
+	lines    []string // of the source
+	tokens   []Token
+	cursor   int // the first token not yet written
+	comments []Comment
+
+	// fresh is set at the start of the file and of each block, where no
+	// blank line is kept.
+	fresh bool
+}
+
+var (
+	_ ExprVisitor = (*formatter)(nil)
+	_ StmtVisitor = (*formatter)(nil)
+)
+
+func (f *formatter) statements(statements []Stmt) {
+	for _, stmt := range statements {
+		f.startLine(f.stmtLine(stmt))
+		f.stmt(stmt)
+		f.write("\n")
+	}
+}
+
+// startLine flushes the comments before the given source line and keeps a
+// blank line before it, then indents for the line about to be written. A line
+// of 0 means its position is unknown.
+func (f *formatter) startLine(line int) {
+	if line > 0 {
+		f.flushComments(line)
+		f.blankLine(line)
+	}
+	f.fresh = false
+	f.write(strings.Repeat("  ", f.indent))
+}
+
+func (f *formatter) blankLine(line int) {
+	if !f.fresh && line >= 2 && line-2 < len(f.lines) && strings.TrimSpace(f.lines[line-2]) == "" {
+		f.write("\n")
+	}
+}
+
+// flushComments writes the comments on lines before line. A trailing
+// comment goes at the end of the last line written.
+func (f *formatter) flushComments(line int) {
+	for len(f.comments) > 0 && f.comments[0].Line < line {
+		comment := f.comments[0]
+		f.comments = f.comments[1:]
+
+		if comment.Trailing && f.out.Len() > 0 {
+			f.out.Truncate(f.out.Len() - 1)
+			f.write(" " + comment.Text + "\n")
+			continue
+		}
+
+		f.blankLine(comment.Line)
+		f.fresh = false
+		f.write(strings.Repeat("  ", f.indent) + comment.Text + "\n")
+	}
+}
+
+func (f *formatter) write(s string) {
+	f.out.WriteString(s)
+}
+
+// next moves the cursor past the next token of the given type and returns
+// it.
+func (f *formatter) next(tokenType TokenType) Token {
+	for f.cursor < len(f.tokens)-1 && f.tokens[f.cursor].Type != tokenType {
+		f.cursor++
+	}
+	token := f.tokens[f.cursor]
+	f.cursor++
+	return token
+}
+
+// block writes a braced list of statements, finding its braces in the
+// tokens so the comments inside it stay inside.
+func (f *formatter) block(statements []Stmt) {
+	f.next(LEFT_BRACE)
+	closing := f.cursor
+	for depth := 0; closing < len(f.tokens)-1; closing++ {
+		if f.tokens[closing].Type == LEFT_BRACE {
+			depth++
+		} else if f.tokens[closing].Type == RIGHT_BRACE {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+	}
+	line := f.tokens[closing].Line
+
+	if len(statements) == 0 && (len(f.comments) == 0 || f.comments[0].Line >= line) {
+		f.write("{}")
+		f.cursor = closing + 1
+		return
+	}
+
+	f.write("{\n")
+	f.indent++
+	f.fresh = true
+	f.statements(statements)
+	f.flushComments(line)
+	f.indent--
+	f.write(strings.Repeat("  ", f.indent) + "}")
+	f.cursor = closing + 1
+}
+
+// body writes the body of a statement such as if or while: a block after a
+// space, or any other statement indented on the next line. It reports
+// whether the body was a block.
+func (f *formatter) body(stmt Stmt) bool {
+	if block, ok := stmt.(*BlockStmt); ok && !isForLoop(block) {
+		f.write(" ")
+		f.block(block.Statements)
+		return true
+	}
+
+	f.write("\n")
+	f.indent++
+	f.fresh = true
+	f.startLine(f.stmtLine(stmt))
+	f.stmt(stmt)
+	f.indent--
+	return false
+}
+
+func (f *formatter) stmt(stmt Stmt) {
+	_, _ = stmt.Accept(f)
+}
+
+func (f *formatter) expr(expr Expr) {
+	_, _ = expr.Accept(f)
+}
+
+func (f *formatter) VisitExprStmt(stmt *ExprStmt) (any, error) {
+	f.expr(stmt.Expression)
+	f.write(";")
+	return nil, nil
+}
+
+func (f *formatter) VisitPrintStmt(stmt *PrintStmt) (any, error) {
+	f.write("print ")
+	f.expr(stmt.Expression)
+	f.write(";")
+	return nil, nil
+}
+
+func (f *formatter) VisitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
+	f.write("var " + stmt.Name.Lexeme + annotation(stmt.Type))
+	if stmt.Initializer != nil {
+		f.write(" = ")
+		f.expr(stmt.Initializer)
+	}
+	f.write(";")
+	return nil, nil
+}
+
+func (f *formatter) VisitBlockStmt(stmt *BlockStmt) (any, error) {
+	if isForLoop(stmt) {
+		return stmt.Statements[1].Accept(f)
+	}
+
+	f.block(stmt.Statements)
+	return nil, nil
+}
+
+func (f *formatter) VisitIfStmt(stmt *IfStmt) (any, error) {
+	f.write("if (")
+	f.expr(stmt.Condition)
+	f.write(")")
+	block := f.body(stmt.ThenBranch)
+	if stmt.ElseBranch == nil {
+		return nil, nil
+	}
+
+	// Comments before the else, such as a trailing one after the then
+	// branch, stay before it, which puts the else on a line of its own.
+	line := f.next(ELSE).Line
+	if block && (len(f.comments) == 0 || f.comments[0].Line >= line) {
+		f.write(" else")
+	} else {
+		f.write("\n")
+		f.flushComments(line)
+		f.write(strings.Repeat("  ", f.indent) + "else")
+	}
+
+	if elseIf, ok := stmt.ElseBranch.(*IfStmt); ok {
+		f.write(" ")
+		return f.VisitIfStmt(elseIf)
+	}
+	f.body(stmt.ElseBranch)
+	return nil, nil
+}
+
+func (f *formatter) VisitWhileStmt(stmt *WhileStmt) (any, error) {
+	if stmt.For == nil {
+		f.write("while (")
+		f.expr(stmt.Condition)
+		f.write(")")
+		f.body(stmt.Body)
+		return nil, nil
+	}
+
+	f.write("for (")
+	if stmt.For.Initializer != nil {
+		f.stmt(stmt.For.Initializer)
+	} else {
+		f.write(";")
+	}
+	if stmt.For.Condition != nil {
+		f.write(" ")
+		f.expr(stmt.For.Condition)
+	}
+	f.write(";")
+	body := stmt.Body
+	if stmt.For.Increment != nil {
+		f.write(" ")
+		f.expr(stmt.For.Increment)
+		body = body.(*BlockStmt).Statements[0]
+	}
+	f.write(")")
+	f.body(body)
+	return nil, nil
+}
+
+func (f *formatter) VisitForInStmt(stmt *ForInStmt) (any, error) {
+	f.write("for (var " + stmt.Name.Lexeme + " in ")
+	f.expr(stmt.Iterable)
+	f.write(")")
+	f.body(stmt.Body)
+	return nil, nil
+}
+
+func (f *formatter) VisitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error) {
+	f.write("fun " + stmt.Name.Lexeme)
+	f.function(stmt.Parameters, stmt.Types, stmt.Body)
+	return nil, nil
+}
+
+func (f *formatter) VisitReturnStmt(stmt *ReturnStmt) (any, error) {
+	f.write("return")
+	if stmt.Value != nil {
+		f.write(" ")
+		f.expr(stmt.Value)
+	}
+	f.write(";")
+	return nil, nil
+}
+
+func (f *formatter) VisitThrowStmt(stmt *ThrowStmt) (any, error) {
+	f.write("throw ")
+	f.expr(stmt.Value)
+	f.write(";")
+	return nil, nil
+}
+
+func (f *formatter) VisitTryStmt(stmt *TryStmt) (any, error) {
+	f.write("try ")
+	f.block(stmt.Body)
+	if stmt.CatchName != nil {
+		f.write(" catch (" + stmt.CatchName.Lexeme + ") ")
+		f.block(stmt.CatchBody)
+	}
+	// An empty finally block parses the same as none, so look for it.
+	if f.cursor < len(f.tokens) && f.tokens[f.cursor].Type == FINALLY {
+		f.write(" finally ")
+		f.block(stmt.FinallyBody)
+	}
+	return nil, nil
+}
+
+func (f *formatter) VisitImportStmt(stmt *ImportStmt) (any, error) {
+	if stmt.Alias != nil {
+		f.write("import " + stmt.Path.Lexeme + " as " + stmt.Alias.Lexeme + ";")
+		return nil, nil
+	}
+
+	f.next(LEFT_BRACE)
+	f.next(RIGHT_BRACE)
+	names := make([]string, len(stmt.Names))
+	for i, name := range stmt.Names {
+		names[i] = name.Lexeme
+	}
+	f.write("import { " + strings.Join(names, ", ") + " } from " + stmt.Path.Lexeme + ";")
+	return nil, nil
+}
+
+func (f *formatter) VisitExportStmt(stmt *ExportStmt) (any, error) {
+	f.write("export ")
+	f.stmt(stmt.Declaration)
+	return nil, nil
+}
+
+// function writes a parameter list and a function body.
+func (f *formatter) function(parameters []Token, types *FunctionTypes, body []Stmt) {
+	f.write(signature(parameters, types) + " ")
+	f.block(body)
+}
+
+// signature returns a parameter list with its type annotations.
+func signature(parameters []Token, types *FunctionTypes) string {
+	if types == nil {
+		types = &FunctionTypes{}
+	}
+
+	names := make([]string, len(parameters))
+	for i, param := range parameters {
+		names[i] = param.Lexeme
+		if i < len(types.Parameters) {
+			names[i] += annotation(types.Parameters[i])
+		}
+	}
+	return "(" + strings.Join(names, ", ") + ")" + annotation(types.Return)
+}
+
+func annotation(annotation *TypeAnnotation) string {
+	if annotation == nil {
+		return ""
+	}
+	return ": " + annotation.Name.Lexeme
+}
+
+func (f *formatter) VisitBinaryExpr(expr *BinaryExpr) (any, error) {
+	if parts, ok := interpolationParts(expr); ok {
+		f.write(`"`)
+		for i, part := range parts {
+			if i%2 == 0 {
+				f.write(quote(part.(*LiteralExpr).Value.Value.(string)))
+			} else {
+				f.write("${")
+				f.expr(part.(*StringifyExpr).Expression)
+				f.write("}")
+			}
+		}
+		f.write(`"`)
+		return nil, nil
+	}
+
+	f.expr(expr.Left)
+	f.write(" " + expr.Operator.Lexeme + " ")
+	f.expr(expr.Right)
+	return nil, nil
+}
+
+func (f *formatter) VisitGroupingExpr(expr *GroupingExpr) (any, error) {
+	f.write("(")
+	f.expr(expr.Expression)
+	f.write(")")
+	return nil, nil
+}
+
+func (f *formatter) VisitLiteralExpr(expr *LiteralExpr) (any, error) {
+	switch value := expr.Value.Value.(type) {
+	case nil:
+		f.write("nil")
+	case float64:
+		f.write(strconv.FormatFloat(value, 'f', -1, 64))
+	case string:
+		f.write(`"` + quote(value) + `"`)
+	default:
+		f.write(fmt.Sprint(value))
+	}
+	return nil, nil
+}
+
+func (f *formatter) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
+	f.write(expr.Operator.Lexeme)
+	f.expr(expr.Right)
+	return nil, nil
+}
+
+func (f *formatter) VisitVariableExpr(expr *VariableExpr) (any, error) {
+	f.write(expr.Name.Lexeme)
+	return nil, nil
+}
+
+func (f *formatter) VisitAssignExpr(expr *AssignExpr) (any, error) {
+	f.write(expr.Name.Lexeme + " ")
+	if expr.Operator != nil {
+		f.write(expr.Operator.Lexeme)
+	}
+	f.write("= ")
+	f.expr(expr.Value)
+	return nil, nil
+}
+
+func (f *formatter) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
+	f.expr(expr.Left)
+	f.write(" " + expr.Operator.Lexeme + " ")
+	f.expr(expr.Right)
+	return nil, nil
+}
+
+func (f *formatter) VisitCallExpr(expr *CallExpr) (any, error) {
+	f.expr(expr.Callee)
+	f.write("(")
+	for i, argument := range expr.Arguments {
+		if i > 0 {
+			f.write(", ")
+		}
+		f.expr(argument)
+	}
+	f.write(")")
+	return nil, nil
+}
+
+func (f *formatter) VisitFunctionExpr(expr *FunctionExpr) (any, error) {
+	if expr.Keyword.Type != ARROW {
+		f.write("fun ")
+		f.function(expr.Parameters, expr.Types, expr.Body)
+		return nil, nil
+	}
+
+	f.write(signature(expr.Parameters, expr.Types) + " => ")
+
+	// An expression body was parsed into a return carrying the arrow.
+	if len(expr.Body) == 1 {
+		if ret, ok := expr.Body[0].(*ReturnStmt); ok && ret.Keyword.Type == ARROW {
+			f.expr(ret.Value)
+			return nil, nil
+		}
+	}
+	f.block(expr.Body)
+	return nil, nil
+}
+
+func (f *formatter) VisitGetExpr(expr *GetExpr) (any, error) {
+	f.expr(expr.Object)
+	f.write("." + expr.Name.Lexeme)
+	return nil, nil
+}
+
+func (f *formatter) VisitSetExpr(expr *SetExpr) (any, error) {
+	f.expr(expr.Object)
+	f.write("." + expr.Name.Lexeme + " ")
+	if expr.Operator != nil {
+		f.write(expr.Operator.Lexeme)
+	}
+	f.write("= ")
+	f.expr(expr.Value)
+	return nil, nil
+}
+
+func (f *formatter) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	f.expr(expr.Object)
+	f.write("[")
+	f.expr(expr.Index)
+	f.write("]")
+	return nil, nil
+}
+
+func (f *formatter) VisitIndexSetExpr(expr *IndexSetExpr) (any, error) {
+	f.expr(expr.Object)
+	f.write("[")
+	f.expr(expr.Index)
+	f.write("] ")
+	if expr.Operator != nil {
+		f.write(expr.Operator.Lexeme)
+	}
+	f.write("= ")
+	f.expr(expr.Value)
+	return nil, nil
+}
+
+func (f *formatter) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	f.expr(expr.Condition)
+	f.write(" ? ")
+	f.expr(expr.ThenBranch)
+	f.write(" : ")
+	f.expr(expr.ElseBranch)
+	return nil, nil
+}
+
+func (f *formatter) VisitStringifyExpr(expr *StringifyExpr) (any, error) {
+	// Only the parser makes these, inside interpolations.
+	f.write(`"${`)
+	f.expr(expr.Expression)
+	f.write(`}"`)
+	return nil, nil
+}
+
+// isForLoop reports whether a block is the one the parser wraps around a for
+// loop with an initializer.
+func isForLoop(block *BlockStmt) bool {
+	if len(block.Statements) != 2 {
+		return false
+	}
+	loop, ok := block.Statements[1].(*WhileStmt)
+	return ok && loop.For != nil && loop.For.Initializer == block.Statements[0]
+}
+
+// stmtLine returns the line a statement starts on. Unlike the package's
+// stmtLine, that is the line of the keyword of a for loop rather than its
+// initializer, and the line of the brace opening a block.
+func (f *formatter) stmtLine(stmt Stmt) int {
+	block, ok := stmt.(*BlockStmt)
+	if !ok {
+		return stmtLine(stmt)
+	}
+	if isForLoop(block) {
+		return block.Statements[1].(*WhileStmt).Keyword.Line
+	}
+
+	for i := f.cursor; i < len(f.tokens); i++ {
+		if f.tokens[i].Type == LEFT_BRACE {
+			return f.tokens[i].Line
+		}
+	}
+	return 0
+}
+
+// interpolationParts undoes the parser's desugaring of an interpolated
+// string, which turns "a${b}c" into "a" + str(b) + "c". It returns the
+// literal segments and StringifyExpr values in turn, starting and ending with
+// a segment.
+func interpolationParts(expr *BinaryExpr) ([]Expr, bool) {
+	var parts []Expr
+	var current Expr = expr
+	for wantSegment := true; ; wantSegment = !wantSegment {
+		binary, ok := current.(*BinaryExpr)
+		if !ok || binary.Operator.Type != PLUS {
+			break
+		}
+		if wantSegment && !isStringLiteral(binary.Right) {
+			break
+		}
+		if _, ok := binary.Right.(*StringifyExpr); !wantSegment && !ok {
+			break
+		}
+
+		parts = append(parts, binary.Right)
+		current = binary.Left
+
+		// The first segment ends the chain, after a value.
+		if !wantSegment && isStringLiteral(current) {
+			parts = append(parts, current)
+			for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+				parts[i], parts[j] = parts[j], parts[i]
+			}
+			return parts, true
+		}
+	}
+	return nil, false
+}
+
+func isStringLiteral(expr Expr) bool {
+	literal, ok := expr.(*LiteralExpr)
+	if !ok {
+		return false
+	}
+	_, ok = literal.Value.Value.(string)
+	return ok
+}
+
+// quote escapes the text of a string literal, without the quotes.
+func quote(s string) string {
+	var builder strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '"':
+			builder.WriteString(`\"`)
+		case r == '\\':
+			builder.WriteString(`\\`)
+		case r == '\n':
+			builder.WriteString(`\n`)
+		case r == '\t':
+			builder.WriteString(`\t`)
+		case r == '\r':
+			builder.WriteString(`\r`)
+		case r == 0:
+			builder.WriteString(`\0`)
+		case r == '$' && strings.HasPrefix(s[i+1:], "{"):
+			builder.WriteString(`\$`)
+		case r < ' ' || r == 0x7f:
+			fmt.Fprintf(&builder, `\u{%x}`, r)
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,94 @@
+package lox
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestFormatCorpus formats each script in testdata/format and compares the
+// result with its golden file. Formatting must be idempotent, keep every
+// comment and leave what the script prints unchanged.
+func TestFormatCorpus(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "format", "*.lox"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			formatted, err := Format(string(source))
+			require.NoError(t, err)
+
+			golden := strings.TrimSuffix(path, ".lox") + ".golden"
+			if *update {
+				require.NoError(t, os.WriteFile(golden, []byte(formatted), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), formatted)
+
+			again, err := Format(formatted)
+			require.NoError(t, err)
+			assert.Equal(t, formatted, again, "formatting is not idempotent")
+
+			assert.Equal(t, commentTexts(string(source)), commentTexts(formatted))
+			assert.Equal(t, runSource(t, string(source)), runSource(t, formatted))
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"import   \"util.lox\" as util;", "import \"util.lox\" as util;\n"},
+		{"import {a,b} from \"util.lox\";{print a;}", "import { a, b } from \"util.lox\";\n{\n  print a;\n}\n"},
+		{"export fun f(){}\nexport var x=f;", "export fun f() {}\nexport var x = f;\n"},
+		{"if (a) {} else {}", "if (a) {} else {}\n"},
+		{"for (;;) { // forever\n}", "for (;;) { // forever\n}\n"},
+		{"a.b+=1;a.c=2;", "a.b += 1;\na.c = 2;\n"},
+		{"a-=b*=2;", "a -= b *= 2;\n"},
+		{"a[ i+1 ]%=b [0];", "a[i + 1] %= b[0];\n"},
+		{"var x:number=1;fun f(a:string,b):bool{}var g=(n:number):nil=>nil;", "var x: number = 1;\nfun f(a: string, b): bool {}\nvar g = (n: number): nil => nil;\n"},
+		{"// only a comment", "// only a comment\n"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		formatted, err := Format(test.source)
+		if assert.NoError(t, err, test.source) {
+			assert.Equal(t, test.expected, formatted, test.source)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	_, err := Format("print 1")
+	assert.EqualError(t, err, "[line 1] Error at end: Expect ';' after value.")
+
+	_, err = Format(`print "open`)
+	assert.EqualError(t, err, "[line 1] Error: Unterminated string.")
+}
+
+func commentTexts(source string) []string {
+	scanner := NewScanner(source)
+	scanner.RecordComments()
+	scanner.ScanTokens()
+
+	var texts []string
+	for _, comment := range scanner.Comments() {
+		texts = append(texts, comment.Text)
+	}
+	return texts
+}
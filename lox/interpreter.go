@@ -1,31 +1,107 @@
 package lox
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"time"
 )
 
 type Interpreter struct {
 	globals     *Environment
 	environment *Environment
 	locals      map[Expr]int
+
+	path    string          // the script being run, for resolving imports
+	exports map[string]bool // names this script exports to importers
+	loader  *moduleLoader
+	random  *rand.Rand
+
+	capabilities Capabilities
+	args         []string
+
+	stdout io.Writer
+	stdin  *bufio.Reader
+	limits *limits
+	debug  *debugHook // nil unless a debugger is attached
 }
 
-func NewInterpreter() *Interpreter {
+func NewInterpreter(opts ...Option) *Interpreter {
+	o := newOptions(opts)
+
+	interpreter := newInterpreter(newModuleLoader())
+	interpreter.stdout = o.stdout
+	interpreter.stdin = bufio.NewReader(o.stdin)
+	interpreter.limits = newLimits(o)
+	return interpreter
+}
+
+func newInterpreter(loader *moduleLoader) *Interpreter {
 	globals := NewEnvironment()
-	globals.Define("clock", Clock{})
+	defineStdlib(globals)
 
 	return &Interpreter{
 		globals:     globals,
 		environment: globals,
 		locals:      make(map[Expr]int),
+		exports:     make(map[string]bool),
+		loader:      loader,
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
+// newModuleInterpreter creates the interpreter for a module imported by i. It
+// has its own globals but shares the state of the running program.
+func (i *Interpreter) newModuleInterpreter(path string) *Interpreter {
+	interpreter := newInterpreter(i.loader)
+	interpreter.path = path
+	interpreter.random = i.random
+	interpreter.capabilities = i.capabilities
+	interpreter.args = i.args
+	interpreter.stdout = i.stdout
+	interpreter.stdin = i.stdin
+	interpreter.limits = i.limits
+	interpreter.debug = i.debug
+	return interpreter
+}
+
+// SetScriptPath records the file the interpreter is running so that imports
+// can be resolved relative to it, and so that importing it back is reported
+// as a cycle.
+func (i *Interpreter) SetScriptPath(path string) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	i.path = path
+	i.loader.loading = []string{path}
+}
+
 func (i *Interpreter) Evaluate(expr Expr) (any, error) {
-	return expr.accept(i)
+	return expr.Accept(i)
 }
 
 func (i *Interpreter) Interpret(statements []Stmt) error {
+	return i.InterpretContext(context.Background(), statements)
+}
+
+// InterpretContext is like Interpret but stops with an InterruptedError once
+// ctx is done. The step and allocation counts start from zero on each call.
+func (i *Interpreter) InterpretContext(ctx context.Context, statements []Stmt) error {
+	defer i.limits.start(ctx)()
+	if err := i.limits.checkContext(); err != nil {
+		return err
+	}
+
+	return i.interpret(statements)
+}
+
+func (i *Interpreter) interpret(statements []Stmt) error {
 	for _, stmt := range statements {
 		if err := i.execute(stmt); err != nil {
 			return err
@@ -35,9 +111,9 @@ func (i *Interpreter) Interpret(statements []Stmt) error {
 	return nil
 }
 
-var _ exprVisitor = (*Interpreter)(nil)
+var _ ExprVisitor = (*Interpreter)(nil)
 
-func (i *Interpreter) visitBinaryExpr(expr *BinaryExpr) (any, error) {
+func (i *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (any, error) {
 	left, err := i.Evaluate(expr.Left)
 	if err != nil {
 		return nil, err
@@ -48,10 +124,21 @@ func (i *Interpreter) visitBinaryExpr(expr *BinaryExpr) (any, error) {
 		return nil, err
 	}
 
-	switch expr.Operator.Type {
+	return i.binary(expr.Operator, left, right)
+}
+
+// binary applies a binary operator to two evaluated operands. Compound
+// assignment to fields shares it with ordinary binary expressions.
+func (i *Interpreter) binary(operator Token, left, right any) (any, error) {
+	switch operator.Type {
 	case PLUS:
 		if left, ok := left.(string); ok {
 			if right, ok := right.(string); ok {
+				// Check before concatenating so a runaway script cannot build
+				// a huge string first.
+				if err := i.limits.allocateBytes(stringSize + len(left) + len(right)); err != nil {
+					return nil, err
+				}
 				return left + right, nil
 			}
 		}
@@ -62,39 +149,49 @@ func (i *Interpreter) visitBinaryExpr(expr *BinaryExpr) (any, error) {
 			}
 		}
 
-		return nil, NewRuntimeError(expr.Operator, "Operands must be two numbers or two strings.")
+		return nil, NewRuntimeError(operator, "Operands must be two numbers or two strings.")
 	case MINUS:
-		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := i.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 		return left.(float64) - right.(float64), nil
 	case STAR:
-		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := i.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 		return left.(float64) * right.(float64), nil
 	case SLASH:
-		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := i.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 		return left.(float64) / right.(float64), nil
+	case PERCENT:
+		if err := i.checkNumberOperands(operator, left, right); err != nil {
+			return nil, err
+		}
+		return math.Mod(left.(float64), right.(float64)), nil
+	case STAR_STAR:
+		if err := i.checkNumberOperands(operator, left, right); err != nil {
+			return nil, err
+		}
+		return math.Pow(left.(float64), right.(float64)), nil
 	case GREATER:
-		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := i.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 		return left.(float64) > right.(float64), nil
 	case GREATER_EQUAL:
-		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := i.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 		return left.(float64) >= right.(float64), nil
 	case LESS:
-		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := i.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 		return left.(float64) < right.(float64), nil
 	case LESS_EQUAL:
-		if err := i.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := i.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 		return left.(float64) <= right.(float64), nil
@@ -107,15 +204,15 @@ func (i *Interpreter) visitBinaryExpr(expr *BinaryExpr) (any, error) {
 	return nil, nil
 }
 
-func (i *Interpreter) visitGroupingExpr(expr *GroupingExpr) (any, error) {
+func (i *Interpreter) VisitGroupingExpr(expr *GroupingExpr) (any, error) {
 	return i.Evaluate(expr.Expression)
 }
 
-func (i *Interpreter) visitLiteralExpr(expr *LiteralExpr) (any, error) {
+func (i *Interpreter) VisitLiteralExpr(expr *LiteralExpr) (any, error) {
 	return expr.Value.Value, nil
 }
 
-func (i *Interpreter) visitUnaryExpr(expr *UnaryExpr) (any, error) {
+func (i *Interpreter) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
 	right, err := i.Evaluate(expr.Right)
 	if err != nil {
 		return nil, err
@@ -134,7 +231,7 @@ func (i *Interpreter) visitUnaryExpr(expr *UnaryExpr) (any, error) {
 	}
 }
 
-func (i *Interpreter) visitVariableExpr(expr *VariableExpr) (any, error) {
+func (i *Interpreter) VisitVariableExpr(expr *VariableExpr) (any, error) {
 	return i.lookUpVariable(expr.Name, expr)
 }
 
@@ -146,12 +243,30 @@ func (i *Interpreter) lookUpVariable(name Token, expr Expr) (any, error) {
 	return i.globals.Get(name)
 }
 
-func (i *Interpreter) visitAssignExpr(expr *AssignExpr) (any, error) {
+func (i *Interpreter) VisitAssignExpr(expr *AssignExpr) (any, error) {
+	// A compound assignment reads the variable before evaluating the value,
+	// as a = a + b would.
+	var current any
+	if expr.Operator != nil {
+		var err error
+		current, err = i.lookUpVariable(expr.Name, expr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	value, err := i.Evaluate(expr.Value)
 	if err != nil {
 		return nil, err
 	}
 
+	if expr.Operator != nil {
+		value, err = i.binary(*expr.Operator, current, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if distance, ok := i.locals[expr]; ok {
 		i.environment.AssignAt(distance, expr.Name, value)
 	} else {
@@ -163,7 +278,7 @@ func (i *Interpreter) visitAssignExpr(expr *AssignExpr) (any, error) {
 	return value, nil
 }
 
-func (i *Interpreter) visitLogicalExpr(expr *LogicalExpr) (any, error) {
+func (i *Interpreter) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
 	left, err := i.Evaluate(expr.Left)
 	if err != nil {
 		return nil, err
@@ -182,7 +297,7 @@ func (i *Interpreter) visitLogicalExpr(expr *LogicalExpr) (any, error) {
 	return i.Evaluate(expr.Right)
 }
 
-func (i *Interpreter) visitCallExpr(expr *CallExpr) (any, error) {
+func (i *Interpreter) VisitCallExpr(expr *CallExpr) (any, error) {
 	callee, err := i.Evaluate(expr.Callee)
 	if err != nil {
 		return nil, err
@@ -207,30 +322,241 @@ func (i *Interpreter) visitCallExpr(expr *CallExpr) (any, error) {
 		arguments[idx] = val
 	}
 
-	return callable.Call(i, arguments)
+	value, err := callable.Call(i, arguments)
+	var nativeErr *nativeError
+	if errors.As(err, &nativeErr) {
+		return nil, NewRuntimeError(expr.Paren, nativeErr.message)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Values returned by natives are new as far as the script is concerned.
+	// Natives whose results can dwarf their arguments have charged for them
+	// already.
+	native, isNative := callable.(*NativeFunction)
+	if _, ok := callable.(*Function); !ok && !(isNative && native.charged) {
+		if err := i.limits.allocate(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
 }
 
-var _ stmtVisitor = (*Interpreter)(nil)
+func (i *Interpreter) VisitFunctionExpr(expr *FunctionExpr) (any, error) {
+	function := NewLambda(expr, i.environment, i)
+	return function, i.limits.allocate(function)
+}
 
-func (i *Interpreter) visitExprStmt(stmt *ExprStmt) (any, error) {
-	return i.Evaluate(stmt.Expression)
+func (i *Interpreter) VisitGetExpr(expr *GetExpr) (any, error) {
+	object, err := i.Evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	if object, ok := object.(Object); ok {
+		return object.Get(expr.Name)
+	}
+
+	return nil, NewRuntimeError(expr.Name, "Only objects have properties.")
 }
 
-func (i *Interpreter) visitPrintStmt(stmt *PrintStmt) (any, error) {
-	val, err := i.Evaluate(stmt.Expression)
+func (i *Interpreter) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	object, err := i.Evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := i.Evaluate(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.index(expr.Bracket, object, index)
+}
+
+func (i *Interpreter) VisitIndexSetExpr(expr *IndexSetExpr) (any, error) {
+	object, err := i.Evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := i.Evaluate(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	// A compound assignment reads the element before evaluating the value,
+	// as a[i] = a[i] + b would.
+	var current any
+	if expr.Operator != nil {
+		current, err = i.index(expr.Bracket, object, index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	value, err := i.Evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if expr.Operator != nil {
+		value, err = i.binary(*expr.Operator, current, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch object := object.(type) {
+	case *List:
+		n, err := listIndex(expr.Bracket, object, index)
+		if err != nil {
+			return nil, err
+		}
+		object.Elements[n] = value
+	case *Map:
+		key, ok := index.(string)
+		if !ok {
+			return nil, NewRuntimeError(expr.Bracket, "Map keys must be strings.")
+		}
+		if _, exists := object.Entries[key]; !exists {
+			if err := i.limits.growBytes(mapEntrySize + len(key)); err != nil {
+				return nil, err
+			}
+		}
+		object.Entries[key] = value
+	default:
+		return nil, NewRuntimeError(expr.Bracket, "Only lists and maps can be indexed.")
+	}
+
+	return value, nil
+}
+
+// index returns the element of a list or the entry of a map at index.
+func (i *Interpreter) index(bracket Token, object, index any) (any, error) {
+	switch object := object.(type) {
+	case *List:
+		n, err := listIndex(bracket, object, index)
+		if err != nil {
+			return nil, err
+		}
+		return object.Elements[n], nil
+	case *Map:
+		key, ok := index.(string)
+		if !ok {
+			return nil, NewRuntimeError(bracket, "Map keys must be strings.")
+		}
+		value, ok := object.Entries[key]
+		if !ok {
+			return nil, NewRuntimeError(bracket, "Undefined key '"+key+"'.")
+		}
+		return value, nil
+	default:
+		return nil, NewRuntimeError(bracket, "Only lists and maps can be indexed.")
+	}
+}
+
+func listIndex(bracket Token, list *List, index any) (int, error) {
+	n, ok := index.(float64)
+	if !ok || n != math.Trunc(n) {
+		return 0, NewRuntimeError(bracket, "List index must be an integer.")
+	}
+	if n < 0 || n >= float64(len(list.Elements)) {
+		return 0, NewRuntimeError(bracket, "List index out of range.")
+	}
+	return int(n), nil
+}
+
+func (i *Interpreter) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	condition, err := i.Evaluate(expr.Condition)
 	if err != nil {
 		return nil, err
 	}
 
-	if val == nil {
-		val = "nil"
+	if i.isTruthy(condition) {
+		return i.Evaluate(expr.ThenBranch)
 	}
 
-	fmt.Println(val)
+	return i.Evaluate(expr.ElseBranch)
+}
+
+func (i *Interpreter) VisitStringifyExpr(expr *StringifyExpr) (any, error) {
+	value, err := i.Evaluate(expr.Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	result := stringify(value)
+	return result, i.limits.allocate(result)
+}
+
+func (i *Interpreter) VisitSetExpr(expr *SetExpr) (any, error) {
+	value, err := i.Evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	object, ok := value.(MutableObject)
+	if !ok {
+		return nil, NewRuntimeError(expr.Name, "Only objects have fields.")
+	}
+
+	// A compound assignment reads the field before evaluating the value, as
+	// o.f = o.f + b would.
+	var current any
+	if expr.Operator != nil {
+		current, err = object.Get(expr.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	value, err = i.Evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if expr.Operator != nil {
+		value, err = i.binary(*expr.Operator, current, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if m, ok := object.(*Map); ok {
+		if _, exists := m.Entries[expr.Name.Lexeme]; !exists {
+			if err := i.limits.growBytes(mapEntrySize + len(expr.Name.Lexeme)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := object.Set(expr.Name, value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+var _ StmtVisitor = (*Interpreter)(nil)
+
+func (i *Interpreter) VisitExprStmt(stmt *ExprStmt) (any, error) {
+	return i.Evaluate(stmt.Expression)
+}
+
+func (i *Interpreter) VisitPrintStmt(stmt *PrintStmt) (any, error) {
+	val, err := i.Evaluate(stmt.Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(i.stdout, stringify(val))
 	return nil, nil
 }
 
-func (i *Interpreter) visitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
+func (i *Interpreter) VisitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
 	var value any
 	if stmt.Initializer != nil {
 		var err error
@@ -244,11 +570,11 @@ func (i *Interpreter) visitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
 	return nil, nil
 }
 
-func (i *Interpreter) visitBlockStmt(stmt *BlockStmt) (any, error) {
+func (i *Interpreter) VisitBlockStmt(stmt *BlockStmt) (any, error) {
 	return nil, i.executeBlock(stmt.Statements, NewEnvironmentWithEnclosing(i.environment))
 }
 
-func (i *Interpreter) visitIfStmt(stmt *IfStmt) (any, error) {
+func (i *Interpreter) VisitIfStmt(stmt *IfStmt) (any, error) {
 	value, err := i.Evaluate(stmt.Condition)
 	if err != nil {
 		return nil, err
@@ -265,7 +591,7 @@ func (i *Interpreter) visitIfStmt(stmt *IfStmt) (any, error) {
 	return nil, nil
 }
 
-func (i *Interpreter) visitWhileStmt(stmt *WhileStmt) (any, error) {
+func (i *Interpreter) VisitWhileStmt(stmt *WhileStmt) (any, error) {
 	for {
 		condition, err := i.Evaluate(stmt.Condition)
 		if err != nil {
@@ -284,13 +610,46 @@ func (i *Interpreter) visitWhileStmt(stmt *WhileStmt) (any, error) {
 	return nil, nil
 }
 
-func (i *Interpreter) visitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error) {
-	function := NewFunction(stmt, i.environment)
+func (i *Interpreter) VisitForInStmt(stmt *ForInStmt) (any, error) {
+	value, err := i.Evaluate(stmt.Iterable)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := i.iterator(stmt.Keyword, value)
+	if err != nil {
+		return nil, err
+	}
+
+	for iterator.HasNext() {
+		element := iterator.Next()
+		if err := iteratorErr(iterator); err != nil {
+			return nil, err
+		}
+
+		// Each iteration gets a fresh scope so closures capture their own element.
+		environment := NewEnvironmentWithEnclosing(i.environment)
+		environment.Define(stmt.Name.Lexeme, element)
+
+		if err := i.executeBlock([]Stmt{stmt.Body}, environment); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, iteratorErr(iterator)
+}
+
+func (i *Interpreter) VisitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error) {
+	function := NewFunction(stmt, i.environment, i)
+	if err := i.limits.allocate(function); err != nil {
+		return nil, err
+	}
+
 	i.environment.Define(stmt.Name.Lexeme, function)
 	return nil, nil
 }
 
-func (i *Interpreter) visitReturnStmt(stmt *ReturnStmt) (any, error) {
+func (i *Interpreter) VisitReturnStmt(stmt *ReturnStmt) (any, error) {
 	var value any
 	if stmt.Value != nil {
 		var err error
@@ -303,13 +662,104 @@ func (i *Interpreter) visitReturnStmt(stmt *ReturnStmt) (any, error) {
 	return nil, NewReturnError(value)
 }
 
-func (i *Interpreter) execute(stmt Stmt) error {
-	_, err := stmt.accept(i)
-	return err
+func (i *Interpreter) VisitThrowStmt(stmt *ThrowStmt) (any, error) {
+	value, err := i.Evaluate(stmt.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, NewThrowError(stmt.Keyword, value)
 }
 
-func (i *Interpreter) resolve(expr Expr, depth int) {
-	i.locals[expr] = depth
+func (i *Interpreter) VisitTryStmt(stmt *TryStmt) (any, error) {
+	err := i.executeBlock(stmt.Body, NewEnvironmentWithEnclosing(i.environment))
+
+	if stmt.CatchName != nil {
+		if value, ok := i.caught(err); ok {
+			environment := NewEnvironmentWithEnclosing(i.environment)
+			environment.Define(stmt.CatchName.Lexeme, value)
+			err = i.executeBlock(stmt.CatchBody, environment)
+		}
+	}
+
+	if stmt.FinallyBody != nil {
+		// A finally block that returns or throws replaces whatever was
+		// unwinding through it; otherwise the pending error carries on. A
+		// fatal error, such as a timeout, cannot be replaced, or a script
+		// could outlive its limits.
+		finallyErr := i.executeBlock(stmt.FinallyBody, NewEnvironmentWithEnclosing(i.environment))
+		if finallyErr != nil && !isFatal(err) {
+			return nil, finallyErr
+		}
+	}
+
+	return nil, err
+}
+
+// caught reports whether err can be handled by a catch clause and, if so,
+// the value the clause should bind.
+func (i *Interpreter) caught(err error) (any, bool) {
+	switch err := err.(type) {
+	case *ThrowError:
+		return err.Value, true
+	case *RuntimeError:
+		return NewErrorValue(err), true
+	default:
+		return nil, false
+	}
+}
+
+func (i *Interpreter) VisitImportStmt(stmt *ImportStmt) (any, error) {
+	module, err := i.importModule(stmt.Keyword, stmt.Path.Literal.Value.(string))
+	if err != nil {
+		return nil, err
+	}
+
+	if stmt.Alias != nil {
+		i.environment.Define(stmt.Alias.Lexeme, module)
+		return nil, nil
+	}
+
+	for _, name := range stmt.Names {
+		value, err := module.Get(name)
+		if err != nil {
+			return nil, err
+		}
+
+		i.environment.Define(name.Lexeme, value)
+	}
+
+	return nil, nil
+}
+
+func (i *Interpreter) VisitExportStmt(stmt *ExportStmt) (any, error) {
+	if err := i.execute(stmt.Declaration); err != nil {
+		return nil, err
+	}
+
+	switch declaration := stmt.Declaration.(type) {
+	case *VarDeclStmt:
+		i.exports[declaration.Name.Lexeme] = true
+	case *FunctionDeclStmt:
+		i.exports[declaration.Name.Lexeme] = true
+	}
+
+	return nil, nil
+}
+
+func (i *Interpreter) execute(stmt Stmt) error {
+	if err := i.limits.step(); err != nil {
+		return err
+	}
+
+	if i.debug != nil {
+		if err := i.debug.statement(i, stmt); err != nil {
+			return err
+		}
+	}
+
+	_, err := stmt.Accept(i)
+	return err
 }
 
 func (i *Interpreter) executeBlock(statements []Stmt, environment *Environment) error {
@@ -328,6 +778,71 @@ func (i *Interpreter) executeBlock(statements []Stmt, environment *Environment)
 	return nil
 }
 
+// iterator returns an Iterator over value. An object with an iterator()
+// method is walked with the iterator protocol, even if it is also a
+// collection such as a map.
+func (i *Interpreter) iterator(keyword Token, value any) (Iterator, error) {
+	if i.hasMethod(value, "iterator") {
+		iterator, err := i.callMethod(keyword, value, "iterator")
+		if err != nil {
+			return nil, err
+		}
+		return &objectIterator{interpreter: i, keyword: keyword, object: iterator}, nil
+	}
+
+	switch val := value.(type) {
+	case string:
+		return newStringIterator(val), nil
+	case Iterable:
+		return val.Iterator(), nil
+	default:
+		return nil, NewRuntimeError(keyword, "Can only iterate over strings and collections.")
+	}
+}
+
+func (i *Interpreter) hasMethod(value any, name string) bool {
+	object, ok := value.(Object)
+	if !ok {
+		return false
+	}
+	method, err := object.Get(Token{Type: IDENTIFIER, Lexeme: name})
+	_, ok = method.(Callable)
+	return err == nil && ok
+}
+
+// callMethod calls the method name of value with no arguments, reporting
+// problems at token.
+func (i *Interpreter) callMethod(token Token, value any, name string) (any, error) {
+	object, ok := value.(Object)
+	if !ok {
+		return nil, NewRuntimeError(token, fmt.Sprintf("Expected an object with a %s() method.", name))
+	}
+
+	method, err := object.Get(Token{Type: IDENTIFIER, Lexeme: name, Line: token.Line, Column: token.Column})
+	if err != nil {
+		return nil, err
+	}
+	callable, ok := method.(Callable)
+	if !ok || callable.Arity() != 0 {
+		return nil, NewRuntimeError(token, fmt.Sprintf("Expected '%s' to be a method with no parameters.", name))
+	}
+
+	value, err = callable.Call(i, nil)
+	var nativeErr *nativeError
+	if errors.As(err, &nativeErr) {
+		return nil, NewRuntimeError(token, nativeErr.message)
+	}
+	return value, err
+}
+
+func stringify(value any) string {
+	if value == nil {
+		return "nil"
+	}
+
+	return fmt.Sprint(value)
+}
+
 func (i *Interpreter) isTruthy(value any) bool {
 	switch val := value.(type) {
 	case nil:
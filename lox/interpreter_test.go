@@ -0,0 +1,565 @@
+package lox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForIn(t *testing.T) {
+	output := runSource(t, `
+for (var c in "héy") {
+  print c;
+}
+
+for (var c in "ab") {
+  fun show() { print c; }
+  show();
+}
+`)
+	assert.Equal(t, "h\né\ny\na\nb\n", output)
+}
+
+func TestForInCollections(t *testing.T) {
+	var output bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&output))
+	require.NoError(t, interpreter.Bind("m", map[string]int{"b": 2, "a": 1}))
+	require.NoError(t, interpretSource(interpreter, `
+for (var x in split("x,y", ",")) print x;
+for (var key in m) print key;`))
+	assert.Equal(t, "x\ny\na\nb\n", output.String())
+}
+
+// TestForInIterator walks an object through its iterator(), hasNext() and
+// next() methods, here a map of functions.
+func TestForInIterator(t *testing.T) {
+	setup := `
+var n = 0;
+counter.iterator = () => { n = 0; return counter; };
+counter.hasNext = () => n < 3;
+counter.next = () => { n += 1; return n; };
+`
+	tests := []struct {
+		source   string
+		expected string
+		err      string
+	}{
+		{`for (var x in counter) print x; for (var x in counter) print -x;`, "1\n2\n3\n-1\n-2\n-3\n", ""},
+		{`counter.next = () => { throw "done"; }; for (var x in counter) print x;`, "", "[line 1] Uncaught exception: done"},
+		{`counter.hasNext = true; for (var x in counter) print x;`, "", "[line 1] Expected 'hasNext' to be a method with no parameters."},
+		{`counter.iterator = () => 1; for (var x in counter) print x;`, "", "[line 1] Expected an object with a hasNext() method."},
+	}
+
+	for _, test := range tests {
+		var output bytes.Buffer
+		interpreter := NewInterpreter(WithStdout(&output))
+		require.NoError(t, interpreter.Bind("counter", map[string]any{}))
+		require.NoError(t, interpretSource(interpreter, setup))
+
+		err := interpretSource(interpreter, test.source)
+		if test.err == "" {
+			assert.NoError(t, err, test.source)
+		} else {
+			assert.EqualError(t, err, test.err, test.source)
+		}
+		assert.Equal(t, test.expected, output.String(), test.source)
+	}
+}
+
+func TestForInNotIterable(t *testing.T) {
+	err := runSourceErr(t, `for (var x in 123) print x;`)
+	assert.EqualError(t, err, "[line 1] Can only iterate over strings and collections.")
+}
+
+func TestLambda(t *testing.T) {
+	output := runSource(t, `
+fun apply(f, x) { return f(x); }
+
+print apply(fun (a) { return a + 1; }, 1);
+print apply((a) => a * 2, 21);
+
+fun counter() {
+  var n = 0;
+  return () => { n = n + 1; return n; };
+}
+var next = counter();
+next();
+print next();
+
+print fun () {};
+fun (x) { print x; }("called");
+`)
+	assert.Equal(t, "2\n42\n2\n<fn anonymous>\ncalled\n", output)
+}
+
+func TestTryCatch(t *testing.T) {
+	output := runSource(t, `
+try {
+  throw "boom";
+} catch (e) {
+  print e;
+}
+
+try {
+  print 1 + nil;
+} catch (e) {
+  print e.message;
+  print e.line;
+}
+
+try {
+  try {
+    throw 42;
+  } finally {
+    print "inner finally";
+  }
+} catch (e) {
+  print e;
+}
+
+fun f() {
+  try {
+    return "from try";
+  } finally {
+    print "finally runs";
+  }
+}
+print f();
+
+fun g() {
+  try {
+    throw "ignored";
+  } finally {
+    return "finally wins";
+  }
+}
+print g();
+`)
+	assert.Equal(t, "boom\nOperands must be two numbers or two strings.\n9\ninner finally\n42\nfinally runs\nfrom try\nfinally wins\n", output)
+}
+
+func TestUncaughtThrow(t *testing.T) {
+	err := runSourceErr(t, `throw "oops";`)
+	assert.EqualError(t, err, "[line 1] Uncaught exception: oops")
+
+	err = runSourceErr(t, "try {\n  -\"a\";\n} catch (e) {\n  throw e;\n}")
+	assert.EqualError(t, err, "[line 2] Operand must be a number.")
+}
+
+func TestConditionalAndCompoundAssignment(t *testing.T) {
+	output := runSource(t, `
+var a = 10;
+a += 5;
+a -= 3;
+a *= 2;
+a /= 4;
+print a;
+a %= 4;
+print a;
+
+var s = "a";
+s += "b";
+print s;
+
+print 7 % 3;
+print 2 ** 3 ** 2;
+print -2 ** 2;
+print 2 ** -1;
+
+print true ? "yes" : "no";
+print nil ? 1 : false ? 2 : 3;
+var b = a > 1 ? "big" : "small";
+print b;
+`)
+	assert.Equal(t, "6\n2\nab\n1\n512\n-4\n0.5\nyes\n3\nbig\n", output)
+
+	// The variable is read before the value is evaluated.
+	output = runSource(t, `
+var n = 1;
+fun bump() { n = 10; return 1; }
+n += bump();
+print n;`)
+	assert.Equal(t, "2\n", output)
+
+	// So is a field.
+	var fieldOutput bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&fieldOutput))
+	require.NoError(t, interpreter.Bind("o", map[string]any{"f": 1}))
+	require.NoError(t, interpretSource(interpreter, `
+fun bump() { o.f = 10; return 1; }
+o.f += bump();
+print o.f;`))
+	assert.Equal(t, "2\n", fieldOutput.String())
+
+	err := runSourceErr(t, "var a = 1;\na += \"x\";")
+	assert.EqualError(t, err, "[line 2] Operands must be two numbers or two strings.")
+
+	err = runSourceErr(t, "undefined -= 1;")
+	assert.EqualError(t, err, "[line 1] Undefined variable 'undefined'.")
+}
+
+func TestIndex(t *testing.T) {
+	var output bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&output))
+	require.NoError(t, interpreter.Bind("m", map[string]any{"a": 1}))
+	require.NoError(t, interpretSource(interpreter, `
+var xs = split("a,b,c", ",");
+print xs[0] + xs[2];
+xs[1] = "B";
+xs[1] += "!";
+print join(xs, "");
+var i = 0;
+xs[i + 2] = m["a"];
+print xs[2];
+
+m["a"] += 2;
+m["b"] = "new";
+print m.a;
+print m["b"];
+
+// The element is read before the value is evaluated.
+fun bump() { m["a"] = 10; return 1; }
+m["a"] += bump();
+print m["a"];`))
+	assert.Equal(t, "ac\naB!c\n1\n3\nnew\n4\n", output.String())
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{`print "abc"[0];`, "[line 1] Only lists and maps can be indexed."},
+		{`split("a", ",")[1] = 1;`, "[line 1] List index out of range."},
+		{`print split("a", ",")[-1];`, "[line 1] List index out of range."},
+		{`print split("a", ",")[0.5];`, "[line 1] List index must be an integer."},
+		{`split("a", ",")[0] += 1;`, "[line 1] Operands must be two numbers or two strings."},
+		{`nil[0] = 1;`, "[line 1] Only lists and maps can be indexed."},
+	}
+	for _, test := range tests {
+		assert.EqualError(t, runSourceErr(t, test.source), test.err, test.source)
+	}
+}
+
+func TestStringEscapesAndInterpolation(t *testing.T) {
+	output := runSource(t, `
+var name = "Lox";
+print "Hello ${name}!";
+print "tab\there \"quoted\" back\\slash \u{1F600} \${literal}";
+print "${1 + 2} and ${nil} and ${"nested ${name}"}";
+var f = () => { return 1; };
+print "call: ${f()}, block: ${ (fun () { return "{}"; })() }";
+print "line one
+line two";
+`)
+	assert.Equal(t, "Hello Lox!\ntab\there \"quoted\" back\\slash \U0001F600 ${literal}\n3 and nil and nested Lox\ncall: 1, block: {}\nline one\nline two\n", output)
+}
+
+func TestStdlib(t *testing.T) {
+	output := runSource(t, `
+print sqrt(16);
+print floor(1.7) + ceil(1.2) + abs(-3);
+print min(3, 4) + max(3, 4);
+seed(42);
+var a = random();
+seed(42);
+print a == random();
+print a >= 0 and a < 1;
+
+print str(1.5) + str(nil) + str(true);
+print num("  2.5 ") + 1;
+print num("abc");
+
+print len("héllo");
+print substr("héllo", 1, 3);
+print indexOf("héllo", "llo");
+print indexOf("hello", "z");
+
+var parts = split("a,b,c", ",");
+print parts;
+print len(parts);
+for (var p in parts) print upper(p);
+print join(parts, "-");
+print lower("ABC") + trim("  x  ");
+
+var start = now();
+sleep(1);
+print now() - start >= 1;
+`)
+	assert.Equal(t, "4\n6\n7\ntrue\ntrue\n1.5niltrue\n3.5\nnil\n5\nél\n2\n-1\n[a, b, c]\n3\nA\nB\nC\na-b-c\nabcx\ntrue\n", output)
+}
+
+func TestStdlibArgumentErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{`sqrt("4");`, "[line 1] Argument 1 to 'sqrt' must be a number."},
+		{`max(1, nil);`, "[line 1] Argument 2 to 'max' must be a number."},
+		{`len(1);`, "[line 1] Argument 1 to 'len' must be a string, list or map."},
+		{`substr("abc", 1.5, 2);`, "[line 1] Argument 2 to 'substr' must be a non-negative integer."},
+		{`substr("abc", 2, 5);`, "[line 1] Range [2, 5) is out of bounds for 'substr' on a string of length 3."},
+		{`substr("abc", 100000000000000000000, 3);`, "[line 1] Argument 2 to 'substr' is too large."},
+		{`substr("abc", 0, 1 / 0);`, "[line 1] Argument 3 to 'substr' is too large."},
+		{`exit(2 ** 64);`, "[line 1] Argument 1 to 'exit' is too large."},
+		{`join("abc", ",");`, "[line 1] Argument 1 to 'join' must be a list."},
+		{`upper(1);`, "[line 1] Argument 1 to 'upper' must be a string."},
+	}
+
+	for _, test := range tests {
+		err := runSourceErr(t, test.source)
+		assert.EqualError(t, err, test.err, test.source)
+	}
+}
+
+func TestSandboxedIO(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"data/in.txt": "hello",
+		"secret.txt":  "secret",
+	})
+	require.NoError(t, os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(dir, "data", "link.txt")))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "target.txt"), filepath.Join(dir, "data", "dangling.txt")))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "newdir"), filepath.Join(dir, "data", "danglingdir")))
+
+	var output bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&output))
+	interpreter.SetArgs([]string{"one", "two"})
+	interpreter.SetCapabilities(Capabilities{ReadRoot: filepath.Join(dir, "data"), WriteRoot: filepath.Join(dir, "data")})
+
+	err := interpretSource(interpreter, `
+print args();
+print readFile("in.txt");
+writeFile("out.txt", "written");
+print readFile("out.txt");
+print listDir(".");
+try { readFile("../secret.txt"); } catch (e) { print e; }
+try { readFile("link.txt"); } catch (e) { print e; }
+try { writeFile("dangling.txt", "x"); } catch (e) { print e; }
+try { writeFile("danglingdir/file.txt", "x"); } catch (e) { print e; }
+try { env("HOME"); } catch (e) { print e; }
+try { readLine(); } catch (e) { print e; }
+exit(3);
+print "unreachable";
+`)
+
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 3, exitErr.Code)
+	assert.Equal(t, "[one, two]\nhello\nwritten\n[dangling.txt, danglingdir, in.txt, link.txt, out.txt]\n"+
+		"Permission denied: '"+filepath.Join(dir, "secret.txt")+"' is outside the allowed directory.\n"+
+		"Permission denied: '"+filepath.Join(dir, "data", "link.txt")+"' is outside the allowed directory.\n"+
+		"Permission denied: '"+filepath.Join(dir, "data", "dangling.txt")+"' is a broken symlink.\n"+
+		"Permission denied: '"+filepath.Join(dir, "data", "danglingdir")+"' is a broken symlink.\n"+
+		"Permission denied: reading environment variables is not allowed.\n"+
+		"Permission denied: reading standard input is not allowed.\n", output.String())
+
+	assert.NoFileExists(t, filepath.Join(dir, "target.txt"))
+	assert.NoDirExists(t, filepath.Join(dir, "newdir"))
+
+	err = runSourceErr(t, `readFile("x");`)
+	assert.EqualError(t, err, "[line 1] Permission denied: read access to files is not allowed.")
+}
+
+func TestRedirectedStreams(t *testing.T) {
+	var stdout bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&stdout), WithStdin(strings.NewReader("first\nsecond")))
+	interpreter.SetCapabilities(Capabilities{Stdin: true})
+
+	err := interpretSource(interpreter, `
+print readLine();
+print readLine();
+print readLine();
+`)
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\nnil\n", stdout.String())
+}
+
+func TestConcurrentInterpretersDoNotInterleave(t *testing.T) {
+	outputs := make([]bytes.Buffer, 4)
+
+	var wg sync.WaitGroup
+	for n := range outputs {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			interpreter := NewInterpreter(WithStdout(&outputs[n]))
+			assert.NoError(t, interpreter.SetGlobal("n", n))
+			assert.NoError(t, interpretSource(interpreter, `
+for (var i = 0; i < 100; i += 1) print n;
+`))
+		}(n)
+	}
+	wg.Wait()
+
+	for n := range outputs {
+		assert.Equal(t, strings.Repeat(fmt.Sprintf("%d\n", n), 100), outputs[n].String())
+	}
+}
+
+func TestInterpretContext(t *testing.T) {
+	for _, source := range []string{
+		`while (true) {}`,
+		`try { while (true) {} } catch (e) { print "caught"; }`,
+		`fun f() { try { while (true) {} } finally { return nil; } } while (true) f();`,
+		`sleep(60000);`,
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		err := interpretSourceContext(ctx, NewInterpreter(WithStdout(io.Discard)), source)
+		cancel()
+
+		var interruptedErr *InterruptedError
+		assert.ErrorAs(t, err, &interruptedErr, source)
+		assert.ErrorIs(t, err, context.DeadlineExceeded, source)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		option Option
+		source string
+		err    any
+	}{
+		{WithMaxSteps(100), `for (var i = 0; i < 1000; i += 1) {}`, new(*StepLimitError)},
+		{WithMaxSteps(100), `for (var i = 0; i < 10; i += 1) {}`, nil},
+		{WithMaxCallDepth(50), `fun f(n) { return f(n + 1); } try { f(0); } catch (e) {}`, new(*CallDepthError)},
+		{WithMaxCallDepth(50), `fun f(n) { if (n > 0) f(n - 1); } f(49);`, nil},
+		{WithMaxCallDepth(50), `fun f() { try { f(); } finally { return nil; } } f();`, new(*CallDepthError)},
+		{WithMaxSteps(100), `fun f() { try { while (true) {} } finally { throw "again"; } } while (true) f();`, new(*StepLimitError)},
+		{WithMaxAllocations(10), `var s = ""; while (true) s = s + "x";`, new(*AllocationLimitError)},
+		{WithMaxAllocations(10), `while (true) split("a,b", ",");`, new(*AllocationLimitError)},
+		{WithMaxAllocations(10), `while (true) (fun () {});`, new(*AllocationLimitError)},
+		{WithMaxAllocations(10), `var n = 0; while (n < 100) n = n + 1;`, nil},
+	}
+
+	for _, tt := range tests {
+		err := interpretSource(NewInterpreter(tt.option, WithStdout(io.Discard)), tt.source)
+		if tt.err == nil {
+			assert.NoError(t, err, tt.source)
+		} else {
+			assert.ErrorAs(t, err, tt.err, tt.source)
+		}
+	}
+
+	// Recursion is bounded by default instead of overflowing the Go stack.
+	err := interpretSource(NewInterpreter(WithStdout(io.Discard)), `fun f(n) { return f(n + 1); } f(0);`)
+	var depthErr *CallDepthError
+	if assert.ErrorAs(t, err, &depthErr) {
+		assert.EqualError(t, err, fmt.Sprintf("Call depth limit of %d exceeded.", DefaultMaxCallDepth))
+	}
+	assert.NoError(t, interpretSource(NewInterpreter(WithStdout(io.Discard)), `fun f(n) { if (n > 0) f(n - 1); } f(5000);`))
+
+	// Each run starts with a fresh budget.
+	interpreter := NewInterpreter(WithMaxSteps(100), WithStdout(io.Discard))
+	for n := 0; n < 3; n++ {
+		assert.NoError(t, interpretSource(interpreter, `for (var i = 0; i < 20; i += 1) {}`))
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	for _, source := range []string{
+		`var s = "x"; while (true) s = s + s;`,
+		`while (true) join(split("a,b,c", ","), "-");`,
+		`try { while (true) str(clock()); } catch (e) { print "caught"; }`,
+	} {
+		err := interpretSource(NewInterpreter(WithMaxMemory(1<<20), WithStdout(io.Discard)), source)
+		var memoryErr *MemoryLimitError
+		assert.ErrorAs(t, err, &memoryErr, source)
+	}
+
+	// Allocation is cumulative: 100 concatenations build strings of 1 to 100
+	// bytes, each with a 16 byte header.
+	source := `var s = ""; for (var n = 0; n < 100; n += 1) s = s + "x";`
+	assert.NoError(t, interpretSource(NewInterpreter(WithMaxMemory(6650)), source))
+	assert.Error(t, interpretSource(NewInterpreter(WithMaxMemory(6649)), source))
+
+	// Adding keys grows a map; replacing them does not.
+	interpreter := NewInterpreter(WithMaxMemory(100))
+	require.NoError(t, interpreter.Bind("m", map[string]int{}))
+	assert.NoError(t, interpretSource(interpreter, `m.a = 1; m.b = 2; m.c = 3; m.a = 4; m.b = 5;`))
+	var memoryErr *MemoryLimitError
+	assert.ErrorAs(t, interpretSource(interpreter, `m.d = 4; m.e = 5; m.f = 6; m.g = 7;`), &memoryErr)
+}
+
+// TestMemoryLimitBeforeAllocating checks that natives whose results dwarf
+// their arguments fail before building them: each of these would make a
+// string of hundreds of megabytes from a 16 KB one, the last two from a list
+// that holds it 2^14 times over.
+func TestMemoryLimitBeforeAllocating(t *testing.T) {
+	for _, call := range []string{
+		`join(split(s, ""), s)`,
+		`str(l)`,
+		`join(pair(l, "!"), "")`,
+	} {
+		source := `
+var s = "x";
+for (var i = 0; i < 14; i += 1) s = s + s;
+var l = s;
+for (var i = 0; i < 14; i += 1) l = pair(l, l);
+` + call + `;`
+
+		interpreter := NewInterpreter(WithMaxMemory(1 << 20))
+		interpreter.DefineNative("pair", 2, func(args []any) (any, error) {
+			return NewList(args), nil
+		})
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		err := interpretSource(interpreter, source)
+		runtime.ReadMemStats(&after)
+
+		var memoryErr *MemoryLimitError
+		assert.ErrorAs(t, err, &memoryErr, call)
+		assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(64<<20), call)
+	}
+}
+
+// runSource scans, parses, resolves and interprets source and returns
+// everything it printed.
+func runSource(t *testing.T, source string) string {
+	t.Helper()
+
+	var output bytes.Buffer
+	err := interpretSource(NewInterpreter(WithStdout(&output)), source)
+	require.NoError(t, err)
+	return output.String()
+}
+
+// runSourceErr is like runSource but expects the program to fail.
+func runSourceErr(t *testing.T, source string) error {
+	t.Helper()
+
+	err := interpretSource(NewInterpreter(WithStdout(io.Discard)), source)
+	require.Error(t, err)
+	return err
+}
+
+func interpretSource(interpreter *Interpreter, source string) error {
+	return interpretSourceContext(context.Background(), interpreter, source)
+}
+
+func interpretSourceContext(ctx context.Context, interpreter *Interpreter, source string) error {
+	tokens, errs := NewScanner(source).ScanTokens()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	stmts, err := NewParser(tokens).Parse()
+	if err != nil {
+		return err
+	}
+
+	if err := NewResolver(interpreter).Resolve(stmts); err != nil {
+		return err
+	}
+
+	return interpreter.InterpretContext(ctx, stmts)
+}
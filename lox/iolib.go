@@ -0,0 +1,229 @@
+package lox
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Capabilities controls which host resources the I/O natives may use. The
+// zero value denies everything, which is what untrusted scripts should get.
+type Capabilities struct {
+	// ReadRoot is the directory readFile and listDir are confined to. Reads
+	// are denied when it is empty. Once it is set, imports are confined to
+	// it too.
+	ReadRoot string
+	// WriteRoot is the directory writeFile is confined to. Writes are denied
+	// when it is empty.
+	WriteRoot string
+	// Env allows env() to read environment variables.
+	Env bool
+	// Stdin allows readLine() to read from standard input.
+	Stdin bool
+}
+
+// SetCapabilities replaces the set of host resources scripts may access.
+func (i *Interpreter) SetCapabilities(capabilities Capabilities) {
+	i.capabilities = capabilities
+}
+
+// SetArgs sets the command-line arguments returned by args().
+func (i *Interpreter) SetArgs(args []string) {
+	i.args = args
+}
+
+var iolib = []*NativeFunction{
+	{name: "readFile", arity: 1, fn: readFile},
+	{name: "writeFile", arity: 2, fn: writeFile},
+	{name: "listDir", arity: 1, fn: listDir},
+	{name: "readLine", arity: 0, fn: readLine},
+	{name: "env", arity: 1, fn: env},
+	{name: "args", arity: 0, fn: args},
+	{name: "exit", arity: 1, fn: exit},
+}
+
+func readFile(interpreter *Interpreter, arguments []any) (any, error) {
+	path, err := stringArgument("readFile", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err = sandboxPath(interpreter.capabilities.ReadRoot, path, "read")
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nativeErrorf("Could not read file: %s", pathError(err))
+	}
+	return string(content), nil
+}
+
+func writeFile(interpreter *Interpreter, arguments []any) (any, error) {
+	path, err := stringArgument("writeFile", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+	content, err := stringArgument("writeFile", arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err = sandboxPath(interpreter.capabilities.WriteRoot, path, "write")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return nil, nativeErrorf("Could not write file: %s", pathError(err))
+	}
+	return nil, nil
+}
+
+func listDir(interpreter *Interpreter, arguments []any) (any, error) {
+	path, err := stringArgument("listDir", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err = sandboxPath(interpreter.capabilities.ReadRoot, path, "read")
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, nativeErrorf("Could not list directory: %s", pathError(err))
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	sort.Strings(names)
+
+	elements := make([]any, len(names))
+	for i, name := range names {
+		elements[i] = name
+	}
+	return NewList(elements), nil
+}
+
+// readLine returns the next line of standard input without its line ending,
+// or nil at the end of input.
+func readLine(interpreter *Interpreter, _ []any) (any, error) {
+	if !interpreter.capabilities.Stdin {
+		return nil, nativeErrorf("Permission denied: reading standard input is not allowed.")
+	}
+
+	line, err := interpreter.stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, nativeErrorf("Could not read standard input: %s", err)
+	}
+	if line == "" && errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// env returns the value of an environment variable, or nil if it is unset.
+func env(interpreter *Interpreter, arguments []any) (any, error) {
+	name, err := stringArgument("env", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if !interpreter.capabilities.Env {
+		return nil, nativeErrorf("Permission denied: reading environment variables is not allowed.")
+	}
+
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, nil
+	}
+	return value, nil
+}
+
+func args(interpreter *Interpreter, _ []any) (any, error) {
+	elements := make([]any, len(interpreter.args))
+	for i, arg := range interpreter.args {
+		elements[i] = arg
+	}
+	return NewList(elements), nil
+}
+
+// exit stops the script. The host decides what to do with the status code;
+// the interpreter never terminates the process itself.
+func exit(_ *Interpreter, arguments []any) (any, error) {
+	code, err := indexArgument("exit", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+	return nil, NewExitError(code)
+}
+
+// sandboxPath resolves path against root and makes sure the result, after
+// following symlinks, does not escape root. Relative paths are relative to
+// root.
+func sandboxPath(root string, path string, access string) (string, error) {
+	if root == "" {
+		return "", nativeErrorf("Permission denied: %s access to files is not allowed.", access)
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", nativeErrorf("Invalid sandbox directory: %s", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+
+	// The file itself may not exist yet when writing, so follow symlinks on
+	// the longest existing prefix. A component that exists but cannot be
+	// followed is a dangling symlink, which writing would create the target
+	// of wherever it points.
+	resolved := path
+	var missing []string
+	for {
+		if r, err := filepath.EvalSymlinks(resolved); err == nil {
+			resolved = filepath.Join(append([]string{r}, missing...)...)
+			break
+		}
+		if info, err := os.Lstat(resolved); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", nativeErrorf("Permission denied: '%s' is a broken symlink.", resolved)
+		}
+
+		parent := filepath.Dir(resolved)
+		if parent == resolved {
+			break
+		}
+		missing = append([]string{filepath.Base(resolved)}, missing...)
+		resolved = parent
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", nativeErrorf("Permission denied: '%s' is outside the allowed directory.", path)
+	}
+
+	return resolved, nil
+}
+
+// pathError drops the operation and path from an *os.PathError so messages
+// don't repeat what the script already knows.
+func pathError(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package lox
+
+import "unicode/utf8"
+
+// Iterable is implemented by runtime values that can be looped over with a
+// for-in statement.
+type Iterable interface {
+	Iterator() Iterator
+}
+
+// Iterator walks the elements of an Iterable one at a time.
+type Iterator interface {
+	HasNext() bool
+	Next() any
+}
+
+// stringIterator yields each character of a string as a one-character string.
+type stringIterator struct {
+	source  string
+	current int
+}
+
+var _ Iterator = (*stringIterator)(nil)
+
+func newStringIterator(source string) *stringIterator {
+	return &stringIterator{source: source}
+}
+
+func (it *stringIterator) HasNext() bool {
+	return it.current < len(it.source)
+}
+
+func (it *stringIterator) Next() any {
+	_, width := utf8.DecodeRuneInString(it.source[it.current:])
+	r := it.source[it.current : it.current+width]
+	it.current += width
+	return r
+}
+
+// objectIterator runs the iterator protocol for a script object, such as a
+// module or map of functions: the object's iterator() method returns an
+// object whose hasNext() and next() methods walk the elements. A failing
+// call ends the loop and is kept in err.
+type objectIterator struct {
+	interpreter *Interpreter
+	keyword     Token // "in", where errors are reported
+	object      any
+	err         error
+}
+
+var _ Iterator = (*objectIterator)(nil)
+
+func (it *objectIterator) HasNext() bool {
+	if it.err != nil {
+		return false
+	}
+	value, err := it.interpreter.callMethod(it.keyword, it.object, "hasNext")
+	if err != nil {
+		it.err = err
+		return false
+	}
+	return it.interpreter.isTruthy(value)
+}
+
+func (it *objectIterator) Next() any {
+	value, err := it.interpreter.callMethod(it.keyword, it.object, "next")
+	if err != nil {
+		it.err = err
+		return nil
+	}
+	return value
+}
+
+func (it *objectIterator) Err() error {
+	return it.err
+}
+
+// iteratorErr returns the error that ended an iterator, if it can fail.
+func iteratorErr(iterator Iterator) error {
+	if failing, ok := iterator.(interface{ Err() error }); ok {
+		return failing.Err()
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package lox
+
+import (
+	"context"
+	"math"
+)
+
+// contextCheckInterval is how many steps run between checks of the context,
+// so that polling it stays cheap relative to the work being done.
+const contextCheckInterval = 1024
+
+// limits tracks how much of its budget a script has used. An interpreter
+// shares it with the modules it imports so that they draw on the same budget.
+// A zero maximum means no limit; only the call depth is limited by default.
+type limits struct {
+	ctx context.Context
+
+	maxSteps       int
+	maxCallDepth   int
+	maxAllocations int
+	maxMemory      int
+
+	steps       int
+	callDepth   int
+	allocations int
+	memory      int
+}
+
+func newLimits(o options) *limits {
+	return &limits{
+		ctx:            context.Background(),
+		maxSteps:       o.maxSteps,
+		maxCallDepth:   o.maxCallDepth,
+		maxAllocations: o.maxAllocations,
+		maxMemory:      o.maxMemory,
+	}
+}
+
+// start resets the counters for a new run under ctx. The returned function
+// restores the previous context when the run finishes.
+func (l *limits) start(ctx context.Context) func() {
+	previous := l.ctx
+	l.ctx = ctx
+	l.steps = 0
+	l.allocations = 0
+	l.memory = 0
+	return func() { l.ctx = previous }
+}
+
+// step counts one unit of execution and periodically checks the context.
+func (l *limits) step() error {
+	l.steps++
+	if l.maxSteps > 0 && l.steps > l.maxSteps {
+		return NewStepLimitError(l.maxSteps)
+	}
+
+	if l.steps%contextCheckInterval == 0 {
+		return l.checkContext()
+	}
+	return nil
+}
+
+func (l *limits) checkContext() error {
+	if err := l.ctx.Err(); err != nil {
+		return NewInterruptedError(err)
+	}
+	return nil
+}
+
+func (l *limits) enterCall() error {
+	l.callDepth++
+	if l.maxCallDepth > 0 && l.callDepth > l.maxCallDepth {
+		l.callDepth--
+		return NewCallDepthError(l.maxCallDepth)
+	}
+	return nil
+}
+
+func (l *limits) exitCall() {
+	l.callDepth--
+}
+
+// allocate counts value against the allocation and memory limits if it is a
+// new heap object: a string, collection, function or error value.
+func (l *limits) allocate(value any) error {
+	size, ok := sizeOf(value)
+	if !ok {
+		return nil
+	}
+	return l.allocateBytes(size)
+}
+
+// allocateBytes counts one new object of size bytes. Memory is not given back
+// when a script drops a value, so the memory limit bounds everything a run
+// allocates rather than what it holds at any one time.
+func (l *limits) allocateBytes(size int) error {
+	l.allocations++
+	if l.maxAllocations > 0 && l.allocations > l.maxAllocations {
+		return NewAllocationLimitError(l.maxAllocations)
+	}
+	return l.growBytes(size)
+}
+
+// available returns how many more bytes the memory limit allows, or
+// math.MaxInt if there is no limit.
+func (l *limits) available() int {
+	if l.maxMemory == 0 {
+		return math.MaxInt
+	}
+	return max(l.maxMemory-l.memory, 0)
+}
+
+// growBytes counts size more bytes for an object that already exists, such
+// as a map gaining an entry.
+func (l *limits) growBytes(size int) error {
+	l.memory += size
+	if l.maxMemory > 0 && l.memory > l.maxMemory {
+		return NewMemoryLimitError(l.maxMemory)
+	}
+	return nil
+}
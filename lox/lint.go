@@ -0,0 +1,628 @@
+package lox
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Lint rules, by the names used to enable and disable them and in reports.
+const (
+	LintUnusedVariable        = "unused-variable"
+	LintUnusedParameter       = "unused-parameter"
+	LintUnusedFunction        = "unused-function"
+	LintUnreachableCode       = "unreachable-code"
+	LintShadowedVariable      = "shadowed-variable"
+	LintAssignmentInCondition = "assignment-in-condition"
+	LintWrongArity            = "wrong-arity"
+)
+
+// LintRules lists every lint rule.
+var LintRules = []string{
+	LintUnusedVariable,
+	LintUnusedParameter,
+	LintUnusedFunction,
+	LintUnreachableCode,
+	LintShadowedVariable,
+	LintAssignmentInCondition,
+	LintWrongArity,
+}
+
+// LintIssue is a problem the linter found in a script.
+type LintIssue struct {
+	Rule    string `json:"rule"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", i.Line, i.Column, i.Message, i.Rule)
+}
+
+// Linter checks scripts for code that is legal but likely a mistake.
+//
+// Names starting with an underscore are never reported as unused. A comment
+// "// lox-ignore" silences every issue on its own line, or on the next line
+// if it is the only thing on its line; "// lox-ignore rule, rule" silences
+// just the rules named.
+type Linter struct {
+	disabled map[string]bool
+}
+
+// NewLinter returns a linter with every rule enabled.
+func NewLinter() *Linter {
+	return &Linter{disabled: make(map[string]bool)}
+}
+
+// Enable turns a rule on.
+func (l *Linter) Enable(rule string) error {
+	if !isLintRule(rule) {
+		return fmt.Errorf("Unknown lint rule '%s'.", rule)
+	}
+	delete(l.disabled, rule)
+	return nil
+}
+
+// Disable turns a rule off.
+func (l *Linter) Disable(rule string) error {
+	if !isLintRule(rule) {
+		return fmt.Errorf("Unknown lint rule '%s'.", rule)
+	}
+	l.disabled[rule] = true
+	return nil
+}
+
+func isLintRule(rule string) bool {
+	for _, known := range LintRules {
+		if rule == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint checks a script and returns its issues in source order. A script that
+// does not scan, parse or resolve is an error rather than an issue.
+func (l *Linter) Lint(source string) ([]LintIssue, error) {
+	scanner := NewScanner(source)
+	scanner.RecordComments()
+	tokens, errs := scanner.ScanTokens()
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	statements, err := NewParser(tokens).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := newResolver(make(map[Expr]int)).Resolve(statements); err != nil {
+		return nil, err
+	}
+
+	pass := &lintPass{
+		globals:         make(map[string][]*lintName),
+		globalUses:      make(map[string]bool),
+		assignedGlobals: make(map[string]bool),
+	}
+	pass.statements(statements)
+	pass.finish()
+
+	ignores := lintIgnores(scanner.Comments())
+	var issues []LintIssue
+	for _, issue := range pass.issues {
+		if l.disabled[issue.Rule] {
+			continue
+		}
+		if rules, ok := ignores[issue.Line]; ok && (rules == nil || rules[issue.Rule]) {
+			continue
+		}
+		issues = append(issues, issue)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues, nil
+}
+
+// lintIgnores maps each line silenced by a lox-ignore comment to the rules
+// silenced on it, or to nil for all of them.
+func lintIgnores(comments []Comment) map[int]map[string]bool {
+	ignores := make(map[int]map[string]bool)
+	for _, comment := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		rest, ok := strings.CutPrefix(text, "lox-ignore")
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != ':') {
+			continue
+		}
+
+		line := comment.Line
+		if !comment.Trailing {
+			line++
+		}
+
+		rules := strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == ':' || r == ' ' })
+		if len(rules) == 0 {
+			ignores[line] = nil
+			continue
+		}
+
+		if _, all := ignores[line]; all && ignores[line] == nil {
+			continue
+		}
+		if ignores[line] == nil {
+			ignores[line] = make(map[string]bool)
+		}
+		for _, rule := range rules {
+			ignores[line][rule] = true
+		}
+	}
+	return ignores
+}
+
+// lintName is a declared name, tracked to find the unused ones and the arity
+// of the functions it is bound to.
+type lintName struct {
+	token    Token
+	kind     SymbolKind
+	used     bool // read, or exempt from the unused rules
+	assigned bool
+	arity    int // the number of parameters of the function bound to it, or -1
+}
+
+// lintCall is a call to a named function, checked once every assignment to
+// the name has been seen.
+type lintCall struct {
+	callee    Token
+	arguments int
+	target    *lintName // nil for a global
+}
+
+// lintPass walks a resolved script the way the Resolver does, keeping its own
+// scopes. Globals are matched by name at the end, because a function can use
+// a global declared after it.
+type lintPass struct {
+	issues []LintIssue
+
+	scopes          []map[string]*lintName
+	globals         map[string][]*lintName
+	globalOrder     []*lintName
+	globalUses      map[string]bool
+	assignedGlobals map[string]bool
+	calls           []lintCall
+}
+
+var (
+	_ ExprVisitor = (*lintPass)(nil)
+	_ StmtVisitor = (*lintPass)(nil)
+)
+
+func (p *lintPass) report(rule string, token Token, format string, args ...any) {
+	p.issues = append(p.issues, LintIssue{
+		Rule:    rule,
+		Line:    token.Line,
+		Column:  token.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// statements checks a list of statements, reporting the first one that
+// follows a return or throw.
+func (p *lintPass) statements(statements []Stmt) {
+	for idx, stmt := range statements {
+		p.stmt(stmt)
+
+		var keyword Token
+		switch stmt := stmt.(type) {
+		case *ReturnStmt:
+			keyword = stmt.Keyword
+		case *ThrowStmt:
+			keyword = stmt.Keyword
+		default:
+			continue
+		}
+
+		if idx+1 < len(statements) {
+			at := firstToken(stmtStart(statements[idx+1]), keyword)
+			p.report(LintUnreachableCode, at, "Unreachable code after '%s'.", keyword.Lexeme)
+			for _, rest := range statements[idx+1:] {
+				p.stmt(rest)
+			}
+		}
+		return
+	}
+}
+
+func (p *lintPass) stmt(stmt Stmt) {
+	_, _ = stmt.Accept(p)
+}
+
+func (p *lintPass) expr(expr Expr) {
+	_, _ = expr.Accept(p)
+}
+
+func (p *lintPass) beginScope() {
+	p.scopes = append(p.scopes, make(map[string]*lintName))
+}
+
+// endScope reports the names in the innermost scope that were never used.
+func (p *lintPass) endScope() {
+	scope := p.scopes[len(p.scopes)-1]
+	p.scopes = p.scopes[:len(p.scopes)-1]
+
+	for _, name := range scope {
+		if !name.used {
+			p.reportUnused(name)
+		}
+	}
+}
+
+func (p *lintPass) reportUnused(name *lintName) {
+	if strings.HasPrefix(name.token.Lexeme, "_") {
+		return
+	}
+
+	switch name.kind {
+	case SymbolVariable:
+		p.report(LintUnusedVariable, name.token, "Variable '%s' is never used.", name.token.Lexeme)
+	case SymbolParameter:
+		p.report(LintUnusedParameter, name.token, "Parameter '%s' is never used.", name.token.Lexeme)
+	case SymbolFunction:
+		p.report(LintUnusedFunction, name.token, "Function '%s' is never used.", name.token.Lexeme)
+	}
+}
+
+// declare adds a name to the innermost scope, or as a global, reporting it if
+// it shadows a name in an enclosing scope.
+func (p *lintPass) declare(token Token, kind SymbolKind, arity int) *lintName {
+	name := &lintName{token: token, kind: kind, arity: arity}
+	if len(p.scopes) == 0 {
+		p.globals[token.Lexeme] = append(p.globals[token.Lexeme], name)
+		p.globalOrder = append(p.globalOrder, name)
+		return name
+	}
+
+	if shadowed := p.lookUp(token.Lexeme, len(p.scopes)-2); shadowed != nil {
+		p.report(LintShadowedVariable, token, "'%s' shadows the declaration on line %d.", token.Lexeme, shadowed.token.Line)
+	}
+	p.scopes[len(p.scopes)-1][token.Lexeme] = name
+	return name
+}
+
+// lookUp finds a name in the scopes up to and including the one at index
+// scope, innermost first, then among the globals declared so far.
+func (p *lintPass) lookUp(name string, scope int) *lintName {
+	for i := scope; i >= 0; i-- {
+		if found, ok := p.scopes[i][name]; ok {
+			return found
+		}
+	}
+	if globals := p.globals[name]; len(globals) > 0 {
+		return globals[len(globals)-1]
+	}
+	return nil
+}
+
+// local finds a name in the local scopes.
+func (p *lintPass) local(name string) *lintName {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if found, ok := p.scopes[i][name]; ok {
+			return found
+		}
+	}
+	return nil
+}
+
+// finish reports the unused globals and the calls with the wrong number of
+// arguments.
+func (p *lintPass) finish() {
+	for _, name := range p.globalOrder {
+		if !name.used && !p.globalUses[name.token.Lexeme] {
+			p.reportUnused(name)
+		}
+	}
+
+	for _, call := range p.calls {
+		arity := -1
+		if call.target != nil {
+			if !call.target.assigned {
+				arity = call.target.arity
+			}
+		} else if name := call.callee.Lexeme; !p.assignedGlobals[name] {
+			if declared := p.globals[name]; len(declared) == 1 {
+				arity = declared[0].arity
+			} else if len(declared) == 0 {
+				arity = nativeArity(name)
+			}
+		}
+
+		if arity >= 0 && arity != call.arguments {
+			p.report(LintWrongArity, call.callee, "Expected %d arguments but got %d.", arity, call.arguments)
+		}
+	}
+}
+
+// nativeArity returns the arity of a native global, or -1 if there is none
+// by that name.
+func nativeArity(name string) int {
+	if name == "clock" {
+		return Clock{}.Arity()
+	}
+	for _, natives := range [][]*NativeFunction{stdlib, iolib} {
+		for _, native := range natives {
+			if native.name == name {
+				return native.arity
+			}
+		}
+	}
+	return -1
+}
+
+// condition checks an expression used as a condition. An assignment there is
+// reported unless it is wrapped in parentheses to show it is meant.
+func (p *lintPass) condition(expr Expr) {
+	switch expr := expr.(type) {
+	case *AssignExpr:
+		p.report(LintAssignmentInCondition, expr.Name, "Assignment used as a condition; use '==' to compare.")
+	case *SetExpr:
+		p.report(LintAssignmentInCondition, expr.Name, "Assignment used as a condition; use '==' to compare.")
+	case *IndexSetExpr:
+		p.report(LintAssignmentInCondition, expr.Bracket, "Assignment used as a condition; use '==' to compare.")
+	}
+	p.expr(expr)
+}
+
+func (p *lintPass) function(parameters []Token, body []Stmt) {
+	p.beginScope()
+	for _, param := range parameters {
+		p.declare(param, SymbolParameter, -1)
+	}
+	p.statements(body)
+	p.endScope()
+}
+
+func (p *lintPass) VisitExprStmt(stmt *ExprStmt) (any, error) {
+	p.expr(stmt.Expression)
+	return nil, nil
+}
+
+func (p *lintPass) VisitPrintStmt(stmt *PrintStmt) (any, error) {
+	p.expr(stmt.Expression)
+	return nil, nil
+}
+
+func (p *lintPass) VisitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
+	// Declared first, like the resolver does, so a function expression can
+	// call itself.
+	name := p.declare(stmt.Name, SymbolVariable, -1)
+	if stmt.Initializer != nil {
+		p.expr(stmt.Initializer)
+		if function, ok := stmt.Initializer.(*FunctionExpr); ok {
+			name.arity = len(function.Parameters)
+		}
+	}
+	return nil, nil
+}
+
+func (p *lintPass) VisitBlockStmt(stmt *BlockStmt) (any, error) {
+	p.beginScope()
+	p.statements(stmt.Statements)
+	p.endScope()
+	return nil, nil
+}
+
+func (p *lintPass) VisitIfStmt(stmt *IfStmt) (any, error) {
+	p.condition(stmt.Condition)
+	p.stmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		p.stmt(stmt.ElseBranch)
+	}
+	return nil, nil
+}
+
+func (p *lintPass) VisitWhileStmt(stmt *WhileStmt) (any, error) {
+	p.condition(stmt.Condition)
+	if stmt.For == nil || stmt.For.Increment == nil {
+		p.stmt(stmt.Body)
+		return nil, nil
+	}
+
+	// The increment the parser appended to the body is not unreachable when
+	// the body returns.
+	block := stmt.Body.(*BlockStmt)
+	p.beginScope()
+	p.stmt(block.Statements[0])
+	p.expr(stmt.For.Increment)
+	p.endScope()
+	return nil, nil
+}
+
+func (p *lintPass) VisitForInStmt(stmt *ForInStmt) (any, error) {
+	p.expr(stmt.Iterable)
+	p.beginScope()
+	p.declare(stmt.Name, SymbolVariable, -1)
+	p.stmt(stmt.Body)
+	p.endScope()
+	return nil, nil
+}
+
+func (p *lintPass) VisitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error) {
+	p.declare(stmt.Name, SymbolFunction, len(stmt.Parameters))
+	p.function(stmt.Parameters, stmt.Body)
+	return nil, nil
+}
+
+func (p *lintPass) VisitReturnStmt(stmt *ReturnStmt) (any, error) {
+	if stmt.Value != nil {
+		p.expr(stmt.Value)
+	}
+	return nil, nil
+}
+
+func (p *lintPass) VisitThrowStmt(stmt *ThrowStmt) (any, error) {
+	p.expr(stmt.Value)
+	return nil, nil
+}
+
+func (p *lintPass) VisitTryStmt(stmt *TryStmt) (any, error) {
+	p.beginScope()
+	p.statements(stmt.Body)
+	p.endScope()
+
+	if stmt.CatchName != nil {
+		p.beginScope()
+		// The caught value often goes unused on purpose.
+		p.declare(*stmt.CatchName, SymbolVariable, -1).used = true
+		p.statements(stmt.CatchBody)
+		p.endScope()
+	}
+
+	p.beginScope()
+	p.statements(stmt.FinallyBody)
+	p.endScope()
+	return nil, nil
+}
+
+func (p *lintPass) VisitImportStmt(stmt *ImportStmt) (any, error) {
+	names := stmt.Names
+	if stmt.Alias != nil {
+		names = []Token{*stmt.Alias}
+	}
+	for _, name := range names {
+		p.declare(name, SymbolImport, -1)
+	}
+	return nil, nil
+}
+
+func (p *lintPass) VisitExportStmt(stmt *ExportStmt) (any, error) {
+	p.stmt(stmt.Declaration)
+
+	// Other modules may use what is exported.
+	var name Token
+	switch declaration := stmt.Declaration.(type) {
+	case *FunctionDeclStmt:
+		name = declaration.Name
+	case *VarDeclStmt:
+		name = declaration.Name
+	}
+	if exported := p.lookUp(name.Lexeme, len(p.scopes)-1); exported != nil {
+		exported.used = true
+	}
+	return nil, nil
+}
+
+func (p *lintPass) VisitBinaryExpr(expr *BinaryExpr) (any, error) {
+	p.expr(expr.Left)
+	p.expr(expr.Right)
+	return nil, nil
+}
+
+func (p *lintPass) VisitGroupingExpr(expr *GroupingExpr) (any, error) {
+	p.expr(expr.Expression)
+	return nil, nil
+}
+
+func (p *lintPass) VisitLiteralExpr(expr *LiteralExpr) (any, error) {
+	return nil, nil
+}
+
+func (p *lintPass) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
+	p.expr(expr.Right)
+	return nil, nil
+}
+
+func (p *lintPass) VisitVariableExpr(expr *VariableExpr) (any, error) {
+	p.use(expr.Name.Lexeme)
+	return nil, nil
+}
+
+func (p *lintPass) use(lexeme string) {
+	if name := p.local(lexeme); name != nil {
+		name.used = true
+	} else {
+		p.globalUses[lexeme] = true
+	}
+}
+
+func (p *lintPass) VisitAssignExpr(expr *AssignExpr) (any, error) {
+	// A compound assignment reads the variable too.
+	if expr.Operator != nil {
+		p.use(expr.Name.Lexeme)
+	}
+
+	p.expr(expr.Value)
+	if name := p.local(expr.Name.Lexeme); name != nil {
+		name.assigned = true
+	} else {
+		p.assignedGlobals[expr.Name.Lexeme] = true
+	}
+	return nil, nil
+}
+
+func (p *lintPass) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
+	p.expr(expr.Left)
+	p.expr(expr.Right)
+	return nil, nil
+}
+
+func (p *lintPass) VisitCallExpr(expr *CallExpr) (any, error) {
+	p.expr(expr.Callee)
+	for _, argument := range expr.Arguments {
+		p.expr(argument)
+	}
+
+	if callee, ok := expr.Callee.(*VariableExpr); ok {
+		p.calls = append(p.calls, lintCall{
+			callee:    callee.Name,
+			arguments: len(expr.Arguments),
+			target:    p.local(callee.Name.Lexeme),
+		})
+	}
+	return nil, nil
+}
+
+func (p *lintPass) VisitFunctionExpr(expr *FunctionExpr) (any, error) {
+	p.function(expr.Parameters, expr.Body)
+	return nil, nil
+}
+
+func (p *lintPass) VisitGetExpr(expr *GetExpr) (any, error) {
+	p.expr(expr.Object)
+	return nil, nil
+}
+
+func (p *lintPass) VisitSetExpr(expr *SetExpr) (any, error) {
+	p.expr(expr.Object)
+	p.expr(expr.Value)
+	return nil, nil
+}
+
+func (p *lintPass) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	p.expr(expr.Object)
+	p.expr(expr.Index)
+	return nil, nil
+}
+
+func (p *lintPass) VisitIndexSetExpr(expr *IndexSetExpr) (any, error) {
+	p.expr(expr.Object)
+	p.expr(expr.Index)
+	p.expr(expr.Value)
+	return nil, nil
+}
+
+func (p *lintPass) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	p.condition(expr.Condition)
+	p.expr(expr.ThenBranch)
+	p.expr(expr.ElseBranch)
+	return nil, nil
+}
+
+func (p *lintPass) VisitStringifyExpr(expr *StringifyExpr) (any, error) {
+	p.expr(expr.Expression)
+	return nil, nil
+}
@@ -0,0 +1,156 @@
+package lox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lint(t *testing.T, linter *Linter, source string) []string {
+	t.Helper()
+
+	issues, err := linter.Lint(source)
+	require.NoError(t, err)
+
+	var reports []string
+	for _, issue := range issues {
+		reports = append(reports, issue.String())
+	}
+	return reports
+}
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		issues []string
+	}{
+		{
+			name: "unused",
+			source: `
+fun used(a, _b, c) { var local = c; var kept = 1; return kept; }
+fun unused() {}
+var top = used(1, 2, 3);
+var _ignored = 1;
+var printed = 2;
+print printed;`,
+			issues: []string{
+				"2:10: Parameter 'a' is never used. (unused-parameter)",
+				"2:26: Variable 'local' is never used. (unused-variable)",
+				"3:5: Function 'unused' is never used. (unused-function)",
+				"4:5: Variable 'top' is never used. (unused-variable)",
+			},
+		},
+		{
+			name: "used later",
+			source: `
+fun first() { return second(); }
+fun second() { return 1; }
+export fun api() {}
+print first();`,
+		},
+		{
+			name: "unreachable",
+			source: `
+fun f(x) {
+  if (x) {
+    return 1;
+    print "never";
+    print "again";
+  }
+  for (var i = 0; i < x; i = i + 1) return i;
+  throw "error";
+  x;
+}
+print f(1);`,
+			issues: []string{
+				"5:5: Unreachable code after 'return'. (unreachable-code)",
+				"10:3: Unreachable code after 'throw'. (unreachable-code)",
+			},
+		},
+		{
+			name: "shadowing",
+			source: `
+var a = 1;
+fun f(a) { { var a = 2; print a; } }
+for (var x in "xy") { var x = 1; print x; }
+f(a);`,
+			issues: []string{
+				"3:7: 'a' shadows the declaration on line 2. (shadowed-variable)",
+				"3:7: Parameter 'a' is never used. (unused-parameter)",
+				"3:18: 'a' shadows the declaration on line 3. (shadowed-variable)",
+				"4:10: Variable 'x' is never used. (unused-variable)",
+				"4:27: 'x' shadows the declaration on line 4. (shadowed-variable)",
+			},
+		},
+		{
+			name: "assignment in condition",
+			source: `
+var a = 1;
+var b = 2;
+if (a = b) print a;
+while ((a = b)) print a;
+print a = b ? 1 : 2;`,
+			issues: []string{
+				"4:5: Assignment used as a condition; use '==' to compare. (assignment-in-condition)",
+			},
+		},
+		{
+			name: "arity",
+			source: `
+fun add(a, b) { return a + b; }
+var double = (n) => n * 2;
+var swapped = (n) => n;
+swapped = add;
+print add(1) + double(1, 2) + swapped(1, 2) + sqrt(1, 2) + len("a");
+fun sqrt2() { return sqrt(); }
+print sqrt2;`,
+			issues: []string{
+				"6:7: Expected 2 arguments but got 1. (wrong-arity)",
+				"6:16: Expected 1 arguments but got 2. (wrong-arity)",
+				"6:47: Expected 1 arguments but got 2. (wrong-arity)",
+				"7:22: Expected 1 arguments but got 0. (wrong-arity)",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.issues, lint(t, NewLinter(), test.source))
+		})
+	}
+}
+
+func TestLintConfiguration(t *testing.T) {
+	source := `
+fun f(a) {
+  var b = 1; // lox-ignore
+  // lox-ignore unused-variable, shadowed-variable
+  var c = 2;
+  var d = 3; // lox-ignore wrong-arity
+}
+f(1);`
+
+	assert.Equal(t, []string{
+		"2:7: Parameter 'a' is never used. (unused-parameter)",
+		"6:7: Variable 'd' is never used. (unused-variable)",
+	}, lint(t, NewLinter(), source))
+
+	linter := NewLinter()
+	require.NoError(t, linter.Disable(LintUnusedParameter))
+	assert.Equal(t, []string{"6:7: Variable 'd' is never used. (unused-variable)"}, lint(t, linter, source))
+
+	require.NoError(t, linter.Enable(LintUnusedParameter))
+	assert.Len(t, lint(t, linter, source), 2)
+
+	assert.EqualError(t, linter.Disable("nope"), "Unknown lint rule 'nope'.")
+}
+
+func TestLintErrors(t *testing.T) {
+	_, err := NewLinter().Lint("print 1")
+	assert.EqualError(t, err, "[line 1] Error at end: Expect ';' after value.")
+
+	_, err = NewLinter().Lint("return 1;")
+	assert.Error(t, err)
+}
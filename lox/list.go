@@ -0,0 +1,42 @@
+package lox
+
+import "strings"
+
+// List is an ordered collection of values. There is no literal syntax for
+// lists yet; they are produced by natives such as split.
+type List struct {
+	Elements []any
+}
+
+var _ Iterable = (*List)(nil)
+
+func NewList(elements []any) *List {
+	return &List{Elements: elements}
+}
+
+func (l *List) Iterator() Iterator {
+	return &listIterator{list: l}
+}
+
+func (l *List) String() string {
+	parts := make([]string, len(l.Elements))
+	for i, element := range l.Elements {
+		parts[i] = stringify(element)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+type listIterator struct {
+	list    *List
+	current int
+}
+
+func (it *listIterator) HasNext() bool {
+	return it.current < len(it.list.Elements)
+}
+
+func (it *listIterator) Next() any {
+	element := it.list.Elements[it.current]
+	it.current++
+	return element
+}
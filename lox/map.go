@@ -0,0 +1,64 @@
+package lox
+
+import (
+	"sort"
+	"strings"
+)
+
+// Map is a collection of values keyed by string. There is no literal syntax
+// for maps yet; they come from host code, for example Go maps passed through
+// Bind. Entries are read and written as properties or by index, m["key"], and
+// a for-in loop visits the keys in sorted order.
+type Map struct {
+	Entries map[string]any
+}
+
+var (
+	_ MutableObject = (*Map)(nil)
+	_ Iterable      = (*Map)(nil)
+)
+
+func NewMap(entries map[string]any) *Map {
+	return &Map{Entries: entries}
+}
+
+func (m *Map) Get(name Token) (any, error) {
+	if value, ok := m.Entries[name.Lexeme]; ok {
+		return value, nil
+	}
+	return nil, NewRuntimeError(name, "Undefined key '"+name.Lexeme+"'.")
+}
+
+func (m *Map) Set(name Token, value any) error {
+	m.Entries[name.Lexeme] = value
+	return nil
+}
+
+func (m *Map) properties() []string {
+	return m.keys()
+}
+
+func (m *Map) Iterator() Iterator {
+	elements := make([]any, 0, len(m.Entries))
+	for _, key := range m.keys() {
+		elements = append(elements, key)
+	}
+	return NewList(elements).Iterator()
+}
+
+func (m *Map) String() string {
+	parts := make([]string, 0, len(m.Entries))
+	for _, key := range m.keys() {
+		parts = append(parts, key+": "+stringify(m.Entries[key]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (m *Map) keys() []string {
+	keys := make([]string, 0, len(m.Entries))
+	for key := range m.Entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,74 @@
+package lox
+
+// Approximate sizes in bytes of the values a script can create, used to
+// enforce WithMaxMemory. They are estimates of the Go representation rather
+// than exact figures, but they grow with the data the way real usage does.
+const (
+	stringSize     = 16 // string header
+	listSize       = 24 // slice header
+	mapSize        = 48 // map header
+	mapEntrySize   = 32 // key header, value interface and bucket overhead
+	interfaceSize  = 16 // one element of a list
+	functionSize   = 64
+	errorValueSize = 32
+)
+
+// stringLength returns len(stringify(value)) without building the string for
+// lists and maps, so that natives can charge for a string before making it.
+// It stops counting once the length passes limit.
+func stringLength(value any, limit int) int {
+	switch value := value.(type) {
+	case *List:
+		length := len("[]")
+		for i, element := range value.Elements {
+			if length > limit {
+				break
+			}
+			if i > 0 {
+				length += len(", ")
+			}
+			length += stringLength(element, limit-length)
+		}
+		return length
+	case *Map:
+		length := len("{}")
+		i := 0
+		for key, entry := range value.Entries {
+			if length > limit {
+				break
+			}
+			if i > 0 {
+				length += len(", ")
+			}
+			length += len(key) + len(": ") + stringLength(entry, limit-length-len(key))
+			i++
+		}
+		return length
+	default:
+		return len(stringify(value))
+	}
+}
+
+// sizeOf estimates the bytes used by value itself, not counting values it
+// refers to, which are accounted for when they are created. ok is false for
+// values that are not heap objects, such as numbers and booleans.
+func sizeOf(value any) (size int, ok bool) {
+	switch value := value.(type) {
+	case string:
+		return stringSize + len(value), true
+	case *List:
+		return listSize + interfaceSize*len(value.Elements), true
+	case *Map:
+		size := mapSize
+		for key := range value.Entries {
+			size += mapEntrySize + len(key)
+		}
+		return size, true
+	case *Function:
+		return functionSize, true
+	case *ErrorValue:
+		return errorValueSize + len(value.Message), true
+	default:
+		return 0, false
+	}
+}
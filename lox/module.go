@@ -0,0 +1,140 @@
+package lox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Module is the value an import binds: the exported globals of another
+// script, read through "." access.
+type Module struct {
+	path    string
+	globals *Environment
+	exports map[string]bool
+}
+
+var _ Object = (*Module)(nil)
+
+func (m *Module) Get(name Token) (any, error) {
+	if !m.exports[name.Lexeme] {
+		return nil, NewRuntimeError(name, fmt.Sprintf("Module '%s' does not export '%s'.", m.name(), name.Lexeme))
+	}
+
+	return m.globals.values[name.Lexeme], nil
+}
+
+func (m *Module) properties() []string {
+	names := make([]string, 0, len(m.exports))
+	for name := range m.exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *Module) String() string {
+	return "<module " + m.name() + ">"
+}
+
+func (m *Module) name() string {
+	return strings.TrimSuffix(filepath.Base(m.path), filepath.Ext(m.path))
+}
+
+// moduleLoader is shared by a script and everything it imports, so each file
+// runs at most once no matter how many times it is imported.
+type moduleLoader struct {
+	modules map[string]*Module
+	loading []string // the chain of imports currently being executed
+}
+
+func newModuleLoader() *moduleLoader {
+	return &moduleLoader{modules: make(map[string]*Module)}
+}
+
+// importModule loads the module at path, which is relative to the directory of
+// the importing script.
+func (i *Interpreter) importModule(keyword Token, path string) (*Module, error) {
+	if !filepath.IsAbs(path) && i.path != "" {
+		path = filepath.Join(filepath.Dir(i.path), path)
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, NewRuntimeError(keyword, err.Error())
+	}
+	if i.capabilities.ReadRoot != "" {
+		if path, err = sandboxPath(i.capabilities.ReadRoot, path, "read"); err != nil {
+			return nil, NewRuntimeError(keyword, err.Error())
+		}
+	}
+
+	loader := i.loader
+	if module, ok := loader.modules[path]; ok {
+		return module, nil
+	}
+
+	for idx, loading := range loader.loading {
+		if loading == path {
+			var cycle []string
+			for _, p := range append(loader.loading[idx:len(loader.loading):len(loader.loading)], path) {
+				cycle = append(cycle, filepath.Base(p))
+			}
+			return nil, NewRuntimeError(keyword, "Import cycle detected: "+strings.Join(cycle, " -> ")+".")
+		}
+	}
+
+	loader.loading = append(loader.loading, path)
+	defer func() { loader.loading = loader.loading[:len(loader.loading)-1] }()
+
+	module, err := i.runModule(path)
+	if isFatal(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, NewRuntimeError(keyword, fmt.Sprintf("Error in module '%s': %s", filepath.Base(path), err))
+	}
+
+	loader.modules[path] = module
+	return module, nil
+}
+
+// runModule executes the script at path with its own global environment.
+func (i *Interpreter) runModule(path string) (*Module, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, errs := NewScanner(string(source)).ScanTokens()
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	statements, err := NewParser(tokens).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	interpreter := i.newModuleInterpreter(path)
+	if interpreter.debug != nil {
+		interpreter.debug.enter((&Module{path: path}).String())
+		defer interpreter.debug.exit()
+	}
+
+	if err := NewResolver(interpreter).Resolve(statements); err != nil {
+		return nil, err
+	}
+	if interpreter.debug == nil {
+		// Keep every statement a breakpoint could be set on.
+		statements = NewOptimizer().Optimize(statements)
+	}
+
+	if err := interpreter.interpret(statements); err != nil {
+		return nil, err
+	}
+
+	return &Module{path: path, globals: interpreter.globals, exports: interpreter.exports}, nil
+}
@@ -0,0 +1,136 @@
+package lox
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImport(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.lox": `
+import "lib/util.lox" as util;
+import { double, greeting } from "lib/util.lox";
+
+print util.double(21);
+print double(2);
+print greeting;
+print util;
+var hidden = "main";
+print hidden;
+`,
+		"lib/util.lox": `
+print "loading util";
+import { suffix } from "suffix.lox";
+
+var hidden = "util";
+export var greeting = "hi" + suffix;
+export fun double(x) { return x * 2; }
+`,
+		"lib/suffix.lox": `export var suffix = "!";`,
+	})
+
+	output, err := runFile(filepath.Join(dir, "main.lox"))
+	require.NoError(t, err)
+	assert.Equal(t, "loading util\n42\n4\nhi!\n<module util>\nmain\n", output)
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"cycle_a.lox":       `import "cycle_b.lox" as b;`,
+		"cycle_b.lox":       `import "cycle_a.lox" as a;`,
+		"private.lox":       `import { hidden } from "lib.lox";`,
+		"lib.lox":           `var hidden = 1;`,
+		"missing.lox":       `import "nope.lox" as nope;`,
+		"nested_export.lox": `{ export var x = 1; }`,
+	})
+
+	_, err := runFile(filepath.Join(dir, "cycle_a.lox"))
+	assert.EqualError(t, err, "[line 1] Error in module 'cycle_b.lox': [line 1] Import cycle detected: cycle_a.lox -> cycle_b.lox -> cycle_a.lox.")
+
+	_, err = runFile(filepath.Join(dir, "private.lox"))
+	assert.EqualError(t, err, "[line 1] Module 'lib' does not export 'hidden'.")
+
+	_, err = runFile(filepath.Join(dir, "missing.lox"))
+	assert.ErrorContains(t, err, "Error in module 'nope.lox'")
+
+	_, err = runFile(filepath.Join(dir, "nested_export.lox"))
+	assert.EqualError(t, err, "[line 1] Can only export from top-level code.")
+}
+
+func TestImportSandbox(t *testing.T) {
+	outside := writeFiles(t, map[string]string{"secret.lox": `export var secret = "s3cret";`})
+	dir := writeFiles(t, map[string]string{
+		"lib.lox":      `export var value = "inside";`,
+		"main.lox":     `import { value } from "lib.lox"; print value;`,
+		"escape.lox":   `import { secret } from "` + filepath.Join(outside, "secret.lox") + `"; print secret;`,
+		"linked.lox":   `import { secret } from "link.lox"; print secret;`,
+		"relative.lox": `import { secret } from "../` + filepath.Base(outside) + `/secret.lox"; print secret;`,
+	})
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.lox"), filepath.Join(dir, "link.lox")))
+
+	run := func(name string) (string, error) {
+		var output bytes.Buffer
+		interpreter := NewInterpreter(WithStdout(&output))
+		interpreter.SetCapabilities(Capabilities{ReadRoot: dir})
+		err := interpretFile(interpreter, filepath.Join(dir, name))
+		return output.String(), err
+	}
+
+	output, err := run("main.lox")
+	require.NoError(t, err)
+	assert.Equal(t, "inside\n", output)
+
+	for _, name := range []string{"escape.lox", "linked.lox", "relative.lox"} {
+		output, err := run(name)
+		assert.ErrorContains(t, err, "is outside the allowed directory.", name)
+		assert.NotContains(t, output, "s3cret", name)
+	}
+
+	// Without a read root, imports are not confined.
+	output, err = runFile(filepath.Join(dir, "escape.lox"))
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret\n", output)
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func runFile(path string) (string, error) {
+	var output bytes.Buffer
+	err := interpretFile(NewInterpreter(WithStdout(&output)), path)
+	return output.String(), err
+}
+
+func interpretFile(interpreter *Interpreter, path string) error {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	tokens, _ := NewScanner(string(source)).ScanTokens()
+	stmts, err := NewParser(tokens).Parse()
+	if err != nil {
+		return err
+	}
+
+	interpreter.SetScriptPath(path)
+	if err := NewResolver(interpreter).Resolve(stmts); err != nil {
+		return err
+	}
+
+	return interpreter.Interpret(stmts)
+}
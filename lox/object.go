@@ -0,0 +1,51 @@
+package lox
+
+// Object is implemented by runtime values that expose properties through
+// "." access.
+type Object interface {
+	Get(name Token) (any, error)
+}
+
+// MutableObject is implemented by objects whose properties can be assigned.
+type MutableObject interface {
+	Object
+	Set(name Token, value any) error
+}
+
+// propertyLister is implemented by objects that can list their property
+// names, so the REPL can complete them.
+type propertyLister interface {
+	properties() []string
+}
+
+// ErrorValue is the value bound by a catch clause when a runtime error is
+// caught.
+type ErrorValue struct {
+	Message string
+	Line    int
+}
+
+var _ Object = (*ErrorValue)(nil)
+
+func NewErrorValue(err *RuntimeError) *ErrorValue {
+	return &ErrorValue{Message: err.message, Line: err.token.Line}
+}
+
+func (e *ErrorValue) Get(name Token) (any, error) {
+	switch name.Lexeme {
+	case "message":
+		return e.Message, nil
+	case "line":
+		return float64(e.Line), nil
+	default:
+		return nil, NewRuntimeError(name, "Undefined property '"+name.Lexeme+"'.")
+	}
+}
+
+func (e *ErrorValue) properties() []string {
+	return []string{"line", "message"}
+}
+
+func (e *ErrorValue) String() string {
+	return e.Message
+}
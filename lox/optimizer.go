@@ -0,0 +1,304 @@
+package lox
+
+// Optimizer simplifies a resolved program before the Interpreter runs it. It
+// folds operators whose operands are literals, drops if and while statements
+// whose literal conditions mean they never run, and short-circuits logical
+// and conditional operators with a literal condition. An operation that
+// would fail at runtime, such as 1 + "a", is left for the Interpreter to
+// report where it happens.
+//
+// Nodes the optimizer keeps are changed in place rather than copied, so the
+// scope information the Resolver recorded for variables still applies. The
+// optimized tree is only meant to be run: the clauses of for loops are left
+// as they were written.
+type Optimizer struct {
+	// evaluator folds constants with the same rules the program runs by.
+	evaluator *Interpreter
+}
+
+func NewOptimizer() *Optimizer {
+	return &Optimizer{evaluator: NewInterpreter()}
+}
+
+// Optimize returns the optimized statements.
+func (o *Optimizer) Optimize(statements []Stmt) []Stmt {
+	return o.stmts(statements)
+}
+
+var (
+	_ ExprVisitor = (*Optimizer)(nil)
+	_ StmtVisitor = (*Optimizer)(nil)
+)
+
+func (o *Optimizer) expr(expr Expr) Expr {
+	if expr == nil {
+		return nil
+	}
+	optimized, _ := expr.Accept(o)
+	return optimized.(Expr)
+}
+
+// stmt returns the optimized statement, or nil if it can be dropped.
+func (o *Optimizer) stmt(stmt Stmt) Stmt {
+	if stmt == nil {
+		return nil
+	}
+	optimized, _ := stmt.Accept(o)
+	if optimized == nil {
+		return nil
+	}
+	return optimized.(Stmt)
+}
+
+// body is like stmt for the body of a statement, which cannot be dropped.
+func (o *Optimizer) body(stmt Stmt) Stmt {
+	if optimized := o.stmt(stmt); optimized != nil {
+		return optimized
+	}
+	return NewBlockStmt(nil)
+}
+
+func (o *Optimizer) stmts(stmts []Stmt) []Stmt {
+	if stmts == nil {
+		return nil
+	}
+
+	optimized := make([]Stmt, 0, len(stmts))
+	for _, stmt := range stmts {
+		if stmt = o.stmt(stmt); stmt != nil {
+			optimized = append(optimized, stmt)
+		}
+	}
+	return optimized
+}
+
+// fold evaluates an expression whose operands are literals, returning a
+// literal of its value, or the expression itself if evaluating it fails.
+func (o *Optimizer) fold(expr Expr) Expr {
+	value, err := o.evaluator.Evaluate(expr)
+	if err != nil {
+		return expr
+	}
+
+	switch value.(type) {
+	case nil, bool, float64, string:
+		return NewLiteralExpr(exprStart(expr), NewLiteral(value))
+	}
+	return expr
+}
+
+func isLiteral(exprs ...Expr) bool {
+	for _, expr := range exprs {
+		if _, ok := expr.(*LiteralExpr); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// truthy reports whether expr is a literal and, if so, whether it is truthy.
+func (o *Optimizer) truthy(expr Expr) (truthy, ok bool) {
+	literal, ok := expr.(*LiteralExpr)
+	if !ok {
+		return false, false
+	}
+	return o.evaluator.isTruthy(literal.Value.Value), true
+}
+
+func (o *Optimizer) VisitBinaryExpr(expr *BinaryExpr) (any, error) {
+	expr.Left = o.expr(expr.Left)
+	expr.Right = o.expr(expr.Right)
+	if isLiteral(expr.Left, expr.Right) {
+		return o.fold(expr), nil
+	}
+	return expr, nil
+}
+
+func (o *Optimizer) VisitGroupingExpr(expr *GroupingExpr) (any, error) {
+	expr.Expression = o.expr(expr.Expression)
+	if isLiteral(expr.Expression) {
+		return expr.Expression, nil
+	}
+	return expr, nil
+}
+
+func (o *Optimizer) VisitLiteralExpr(expr *LiteralExpr) (any, error) {
+	return expr, nil
+}
+
+func (o *Optimizer) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
+	expr.Right = o.expr(expr.Right)
+	if isLiteral(expr.Right) {
+		return o.fold(expr), nil
+	}
+	return expr, nil
+}
+
+func (o *Optimizer) VisitVariableExpr(expr *VariableExpr) (any, error) {
+	return expr, nil
+}
+
+func (o *Optimizer) VisitAssignExpr(expr *AssignExpr) (any, error) {
+	expr.Value = o.expr(expr.Value)
+	return expr, nil
+}
+
+func (o *Optimizer) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
+	expr.Left = o.expr(expr.Left)
+	expr.Right = o.expr(expr.Right)
+
+	truthy, ok := o.truthy(expr.Left)
+	if !ok {
+		return expr, nil
+	}
+	// The operator's value is its left operand when that decides it.
+	if truthy == (expr.Operator.Type == OR) {
+		return expr.Left, nil
+	}
+	return expr.Right, nil
+}
+
+func (o *Optimizer) VisitCallExpr(expr *CallExpr) (any, error) {
+	expr.Callee = o.expr(expr.Callee)
+	for i, argument := range expr.Arguments {
+		expr.Arguments[i] = o.expr(argument)
+	}
+	return expr, nil
+}
+
+func (o *Optimizer) VisitFunctionExpr(expr *FunctionExpr) (any, error) {
+	expr.Body = o.stmts(expr.Body)
+	return expr, nil
+}
+
+func (o *Optimizer) VisitGetExpr(expr *GetExpr) (any, error) {
+	expr.Object = o.expr(expr.Object)
+	return expr, nil
+}
+
+func (o *Optimizer) VisitSetExpr(expr *SetExpr) (any, error) {
+	expr.Object = o.expr(expr.Object)
+	expr.Value = o.expr(expr.Value)
+	return expr, nil
+}
+
+func (o *Optimizer) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	expr.Object = o.expr(expr.Object)
+	expr.Index = o.expr(expr.Index)
+	return expr, nil
+}
+
+func (o *Optimizer) VisitIndexSetExpr(expr *IndexSetExpr) (any, error) {
+	expr.Object = o.expr(expr.Object)
+	expr.Index = o.expr(expr.Index)
+	expr.Value = o.expr(expr.Value)
+	return expr, nil
+}
+
+func (o *Optimizer) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	expr.Condition = o.expr(expr.Condition)
+	expr.ThenBranch = o.expr(expr.ThenBranch)
+	expr.ElseBranch = o.expr(expr.ElseBranch)
+
+	truthy, ok := o.truthy(expr.Condition)
+	if !ok {
+		return expr, nil
+	}
+	if truthy {
+		return expr.ThenBranch, nil
+	}
+	return expr.ElseBranch, nil
+}
+
+func (o *Optimizer) VisitStringifyExpr(expr *StringifyExpr) (any, error) {
+	expr.Expression = o.expr(expr.Expression)
+	if isLiteral(expr.Expression) {
+		return o.fold(expr), nil
+	}
+	return expr, nil
+}
+
+func (o *Optimizer) VisitExprStmt(stmt *ExprStmt) (any, error) {
+	stmt.Expression = o.expr(stmt.Expression)
+	return stmt, nil
+}
+
+func (o *Optimizer) VisitPrintStmt(stmt *PrintStmt) (any, error) {
+	stmt.Expression = o.expr(stmt.Expression)
+	return stmt, nil
+}
+
+func (o *Optimizer) VisitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
+	stmt.Initializer = o.expr(stmt.Initializer)
+	return stmt, nil
+}
+
+func (o *Optimizer) VisitBlockStmt(stmt *BlockStmt) (any, error) {
+	stmt.Statements = o.stmts(stmt.Statements)
+	return stmt, nil
+}
+
+func (o *Optimizer) VisitIfStmt(stmt *IfStmt) (any, error) {
+	stmt.Condition = o.expr(stmt.Condition)
+	stmt.ThenBranch = o.body(stmt.ThenBranch)
+	stmt.ElseBranch = o.stmt(stmt.ElseBranch)
+
+	truthy, ok := o.truthy(stmt.Condition)
+	if !ok {
+		return stmt, nil
+	}
+	if truthy {
+		return stmt.ThenBranch, nil
+	}
+	if stmt.ElseBranch == nil {
+		return nil, nil
+	}
+	return stmt.ElseBranch, nil
+}
+
+func (o *Optimizer) VisitWhileStmt(stmt *WhileStmt) (any, error) {
+	stmt.Condition = o.expr(stmt.Condition)
+	stmt.Body = o.body(stmt.Body)
+
+	if truthy, ok := o.truthy(stmt.Condition); ok && !truthy {
+		return nil, nil
+	}
+	return stmt, nil
+}
+
+func (o *Optimizer) VisitForInStmt(stmt *ForInStmt) (any, error) {
+	stmt.Iterable = o.expr(stmt.Iterable)
+	stmt.Body = o.body(stmt.Body)
+	return stmt, nil
+}
+
+func (o *Optimizer) VisitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error) {
+	stmt.Body = o.stmts(stmt.Body)
+	return stmt, nil
+}
+
+func (o *Optimizer) VisitReturnStmt(stmt *ReturnStmt) (any, error) {
+	stmt.Value = o.expr(stmt.Value)
+	return stmt, nil
+}
+
+func (o *Optimizer) VisitThrowStmt(stmt *ThrowStmt) (any, error) {
+	stmt.Value = o.expr(stmt.Value)
+	return stmt, nil
+}
+
+func (o *Optimizer) VisitTryStmt(stmt *TryStmt) (any, error) {
+	stmt.Body = o.stmts(stmt.Body)
+	stmt.CatchBody = o.stmts(stmt.CatchBody)
+	stmt.FinallyBody = o.stmts(stmt.FinallyBody)
+	return stmt, nil
+}
+
+func (o *Optimizer) VisitImportStmt(stmt *ImportStmt) (any, error) {
+	return stmt, nil
+}
+
+func (o *Optimizer) VisitExportStmt(stmt *ExportStmt) (any, error) {
+	stmt.Declaration = o.body(stmt.Declaration)
+	return stmt, nil
+}
@@ -0,0 +1,118 @@
+package lox
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runOptimized runs source like runSource, optimizing it after resolving.
+func runOptimized(t *testing.T, source string) (string, error) {
+	t.Helper()
+
+	statements := parseSource(t, source)
+	var output bytes.Buffer
+	interpreter := NewInterpreter(WithStdout(&output))
+	require.NoError(t, NewResolver(interpreter).Resolve(statements))
+	err := interpreter.InterpretContext(context.Background(), NewOptimizer().Optimize(statements))
+	return output.String(), err
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`print 1 + 2 * 3 - -(4);`, `(print 11.0)`},
+		{`print 2 ** 3 % 5 / 2;`, `(print 1.5)`},
+		{`print "a" + "b" + "${1 + 1}!";`, `(print ab2!)`},
+		{`print 1 < 2 == !nil;`, `(print true)`},
+		{`print "a" != "a" or 3 >= 4;`, `(print false)`},
+		{`print 1 + "a";`, `(print (+ 1.0 a))`},
+		{`print -"a" + 1;`, `(print (+ (- a) 1.0))`},
+		{`print x + (1 + 2);`, `(print (+ x 3.0))`},
+		{`print true and x;`, `(print x)`},
+		{`print nil or x;`, `(print x)`},
+		{`print false and x;`, `(print false)`},
+		{`print 1 or x;`, `(print 1.0)`},
+		{`print x and true;`, `(print (and x true))`},
+		{`print 1 > 2 ? x : -2;`, `(print -2.0)`},
+		{`if (1 == 1) print x; else print y;`, `(print x)`},
+		{`if (false) print x; else { print y; }`, `(block (print y))`},
+		{`if (nil) print x;`, ``},
+		{`if (x) if (false) print x;`, `(if x (block))`},
+		{`while (1 > 2) print x;`, ``},
+		{`while (x) { if ("" == "") print x; }`, `(while x (block (print x)))`},
+		{`for (var i = 0; false; i = i + 1) print i;`, `(block (var i 0.0))`},
+		{`fun f() { if (false) return 1; return 2 ** 3; }`, `(fun f () (return 8.0))`},
+	}
+
+	var printer AstPrinter
+	for _, test := range tests {
+		statements := parseSource(t, test.source)
+		require.NoError(t, newResolver(make(map[Expr]int)).Resolve(statements), test.source)
+
+		var printed []string
+		for _, stmt := range NewOptimizer().Optimize(statements) {
+			printed = append(printed, printer.stmt(stmt))
+		}
+		assert.Equal(t, test.expected, strings.Join(printed, "\n"), test.source)
+	}
+}
+
+func TestOptimizeKeepsRuntimeErrors(t *testing.T) {
+	_, err := runOptimized(t, "print 1;\nprint 1 + \"a\";")
+	assert.EqualError(t, err, "[line 2] Operands must be two numbers or two strings.")
+
+	_, err = runOptimized(t, `if (true) print -"a";`)
+	assert.EqualError(t, err, "[line 1] Operand must be a number.")
+}
+
+func TestOptimizeKeepsScopes(t *testing.T) {
+	source := `
+var a = "global";
+{
+  var a = "local";
+  fun show() { print a; }
+  if (true and 1 < 2) show(); else print "never";
+  while (false) a = "changed";
+  for (var i = 0; i < 1 + 1; i = i + 1) {
+    var a = "loop ${i}";
+    if (!false) show();
+    print (nil or a) + "!";
+  }
+  a = "assigned" + (1 > 0 ? "" : "?");
+  show();
+}
+print a;`
+
+	output, err := runOptimized(t, source)
+	require.NoError(t, err)
+	assert.Equal(t, runSource(t, source), output)
+	assert.Equal(t, "local\nlocal\nloop 0!\nlocal\nloop 1!\nassigned\nglobal\n", output)
+}
+
+// TestOptimizeCorpus checks that the scripts in testdata/format print the
+// same once optimized.
+func TestOptimizeCorpus(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "format", "*.lox"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			output, err := runOptimized(t, string(source))
+			require.NoError(t, err)
+			assert.Equal(t, runSource(t, string(source)), output)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package lox
+
+import (
+	"io"
+	"os"
+)
+
+// Option configures an Interpreter or a VM.
+type Option func(*options)
+
+type options struct {
+	stdout io.Writer
+	stderr io.Writer
+	stdin  io.Reader
+
+	maxSteps       int
+	maxCallDepth   int
+	maxAllocations int
+	maxMemory      int
+}
+
+// DefaultMaxCallDepth is how deeply function calls may nest unless
+// WithMaxCallDepth says otherwise. It stops runaway recursion with a
+// CallDepthError well before the Go stack overflows.
+const DefaultMaxCallDepth = 10000
+
+func newOptions(opts []Option) options {
+	o := options{
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		stdin:  os.Stdin,
+
+		maxCallDepth: DefaultMaxCallDepth,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithStdout sets where print statements and results are written.
+func WithStdout(w io.Writer) Option {
+	return func(o *options) { o.stdout = w }
+}
+
+// WithStderr sets where the bytecode compiler reports compile errors.
+func WithStderr(w io.Writer) Option {
+	return func(o *options) { o.stderr = w }
+}
+
+// WithStdin sets where readLine reads from.
+func WithStdin(r io.Reader) Option {
+	return func(o *options) { o.stdin = r }
+}
+
+// WithMaxSteps limits how many statements a single run may execute, or for
+// the VM how many instructions. Exceeding it fails with a StepLimitError.
+func WithMaxSteps(n int) Option {
+	return func(o *options) { o.maxSteps = n }
+}
+
+// WithMaxCallDepth limits how deeply function calls may nest, in place of
+// DefaultMaxCallDepth. Exceeding it fails with a CallDepthError. Zero removes
+// the limit, which lets deep recursion overflow the Go stack.
+func WithMaxCallDepth(n int) Option {
+	return func(o *options) { o.maxCallDepth = n }
+}
+
+// WithMaxAllocations limits how many strings, collections, functions and
+// error values a single run may create. Exceeding it fails with an
+// AllocationLimitError.
+func WithMaxAllocations(n int) Option {
+	return func(o *options) { o.maxAllocations = n }
+}
+
+// WithMaxMemory limits how many bytes of strings, collections, functions and
+// error values a single run may allocate. Exceeding it fails with a
+// MemoryLimitError.
+func WithMaxMemory(bytes int) Option {
+	return func(o *options) { o.maxMemory = bytes }
+}
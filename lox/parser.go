@@ -1,9 +1,15 @@
 package lox
 
 // program        → declaration* EOF ;
-// declaration    → funDecl
+// declaration    → importDecl
+//                | exportDecl
+//                | funDecl
 //                | varDecl
 //                | statement ;
+// importDecl     → "import" STRING "as" IDENTIFIER ";"
+//                | "import" "{" IDENTIFIER ( "," IDENTIFIER )* "}"
+//                  "from" STRING ";" ;
+// exportDecl     → "export" ( funDecl | varDecl ) ;
 // funDecl        → "fun" function ;
 // function       → IDENTIFIER "(" parameters? ")" block ;
 // parameters     → IDENTIFIER ( "," IDENTIFIER )* ;
@@ -13,38 +19,58 @@ package lox
 //                | ifStmt
 //                | printStmt
 //                | returnStmt
+//                | throwStmt
+//                | tryStmt
 //                | whileStmt
 //                | block ;
 // exprStmt       → expression ";" ;
 // forStmt        → "for" "(" ( varDecl | exprStmt | ";" )
 //                  expression? ";"
-//                  expression? ")" statement ;
+//                  expression? ")" statement
+//                | "for" "(" "var" IDENTIFIER "in" expression ")" statement ;
 // ifStmt         → "if" "(" expression ")" statement
 //                ( "else" statement )? ;
 // printStmt      → "print" expression ";" ;
 // returnStmt     → "return" expression? ";" ;
+// throwStmt      → "throw" expression ";" ;
+// tryStmt        → "try" block
+//                  ( "catch" "(" IDENTIFIER ")" block )?
+//                  ( "finally" block )? ;
 // whileStmt      → "while" "(" expression ")" statement ;
 // block          → "{" declaration* "}" ;
 // expression     → assignment ;
-// assignment     → IDENTIFIER "=" assignment
-//                | logic_or ;
+// assignment     → ( call "." )? IDENTIFIER
+//                  ( "=" | "+=" | "-=" | "*=" | "/=" | "%=" ) assignment
+//                | conditional ;
+// conditional    → logic_or ( "?" expression ":" conditional )? ;
 // logic_or       → logic_and ( "or" logic_and )* ;
 // logic_and      → equality ( "and" equality )* ;
 // equality       → comparison ( ( "!=" | "==" ) comparison )* ;
 // comparison     → term ( ( ">" | ">=" | "<" | "<=" ) term )* ;
 // term           → factor ( ( "-" | "+" ) factor )* ;
-// factor         → unary ( ( "/" | "*" ) unary )* ;
-// unary          → ( "!" | "-" ) unary | call ;
-// call           → primary ( "(" arguments? ")" )* ;
+// factor         → unary ( ( "/" | "*" | "%" ) unary )* ;
+// unary          → ( "!" | "-" ) unary | power ;
+// power          → call ( "**" unary )? ;
+// call           → primary ( "(" arguments? ")" | "." IDENTIFIER )* ;
 // arguments      → expression ( "," expression )* ;
 // primary        → "true" | "false" | "nil"
-//                | NUMBER | STRING
+//                | NUMBER | STRING | interpolation
 //                | "(" expression ")"
-//                | IDENTIFIER ;
+//                | IDENTIFIER
+//                | lambda ;
+// interpolation  → ( INTERPOLATION expression )+ STRING ;
+// lambda         → "fun" "(" parameters? ")" block
+//                | "(" parameters? ")" "=>" ( block | expression ) ;
 
 type Parser struct {
 	tokens  []Token
 	current int
+
+	// In tolerant mode a parse error inside a block is recorded in errs and
+	// parsing resumes at the next statement, so that tools get as much of
+	// the program as possible.
+	tolerant bool
+	errs     []error
 }
 
 func NewParser(tokens []Token) *Parser {
@@ -69,12 +95,55 @@ func (p *Parser) Parse() ([]Stmt, error) {
 	return statements, nil
 }
 
+// ParseTolerant parses as much of the program as it can, skipping statements
+// that have errors, and returns every error it found. Blocks left open at the
+// end of the source are closed.
+func (p *Parser) ParseTolerant() ([]Stmt, []error) {
+	p.tolerant = true
+	defer func() { p.tolerant = false }()
+
+	var statements []Stmt
+	for !p.isAtEnd() {
+		start := p.current
+		stmt, err := p.declaration()
+		if err != nil {
+			p.errs = append(p.errs, err)
+			p.skipStatement(start)
+			if p.current == start {
+				// A stray '}' at the top level.
+				p.advance()
+			}
+			continue
+		}
+
+		statements = append(statements, stmt)
+	}
+
+	errs := p.errs
+	p.errs = nil
+	return statements, errs
+}
+
 func (p *Parser) ParseExpr() (Expr, error) {
 	return p.expression()
 }
 
+// AtEnd reports whether the parser has consumed every token.
+func (p *Parser) AtEnd() bool {
+	return p.isAtEnd()
+}
+
 func (p *Parser) declaration() (Stmt, error) {
-	if p.match(FUN) {
+	if p.match(IMPORT) {
+		return p.importDeclaration()
+	}
+
+	if p.match(EXPORT) {
+		return p.exportDeclaration()
+	}
+
+	if p.check(FUN) && p.checkNext(IDENTIFIER) {
+		p.advance()
 		return p.function("function")
 	}
 
@@ -85,6 +154,90 @@ func (p *Parser) declaration() (Stmt, error) {
 	return p.statement()
 }
 
+func (p *Parser) importDeclaration() (Stmt, error) {
+	keyword := p.previous()
+
+	if p.match(LEFT_BRACE) {
+		var names []Token
+		for {
+			name, err := p.consume(IDENTIFIER, "Expect imported name.")
+			if err != nil {
+				return nil, err
+			}
+
+			names = append(names, name)
+
+			if !p.match(COMMA) {
+				break
+			}
+		}
+
+		if _, err := p.consume(RIGHT_BRACE, "Expect '}' after imported names."); err != nil {
+			return nil, err
+		}
+
+		if _, err := p.consumeContextual("from", "Expect 'from' after imported names."); err != nil {
+			return nil, err
+		}
+
+		path, err := p.consume(STRING, "Expect module path.")
+		if err != nil {
+			return nil, err
+		}
+
+		semicolon, err := p.consume(SEMICOLON, "Expect ';' after import.")
+		if err != nil {
+			return nil, err
+		}
+
+		stmt := NewImportStmt(keyword, path, nil, names)
+		stmt.Semicolon = semicolon
+		return stmt, nil
+	}
+
+	path, err := p.consume(STRING, "Expect module path after 'import'.")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consumeContextual("as", "Expect 'as' after module path."); err != nil {
+		return nil, err
+	}
+
+	alias, err := p.consume(IDENTIFIER, "Expect module name after 'as'.")
+	if err != nil {
+		return nil, err
+	}
+
+	semicolon, err := p.consume(SEMICOLON, "Expect ';' after import.")
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := NewImportStmt(keyword, path, &alias, nil)
+	stmt.Semicolon = semicolon
+	return stmt, nil
+}
+
+func (p *Parser) exportDeclaration() (Stmt, error) {
+	keyword := p.previous()
+
+	var declaration Stmt
+	var err error
+	if p.match(FUN) {
+		declaration, err = p.function("function")
+	} else if p.match(VAR) {
+		declaration, err = p.varDeclaration()
+	} else {
+		return nil, NewParseError(p.peek(), "Expect function or variable declaration after 'export'.")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewExportStmt(keyword, declaration), nil
+}
+
 func (p *Parser) function(kind string) (Stmt, error) {
 	name, err := p.consume(IDENTIFIER, "Expect "+kind+" name.")
 	if err != nil {
@@ -95,20 +248,52 @@ func (p *Parser) function(kind string) (Stmt, error) {
 		return nil, err
 	}
 
+	parameters, types, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(LEFT_BRACE, "Expect '{' before "+kind+" body."); err != nil {
+		return nil, err
+	}
+
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+
+	function := NewFunctionDeclStmt(name, parameters, body)
+	function.Types = types
+	return function, nil
+}
+
+// parameters parses a parameter list up to and including the closing ')',
+// and the return type annotation after it. The opening '(' must already have
+// been consumed. The types are nil if there are no annotations.
+func (p *Parser) parameters() ([]Token, *FunctionTypes, error) {
 	var parameters []Token
+	types := &FunctionTypes{}
+	annotated := false
 	if !p.check(RIGHT_PAREN) {
 		for {
 			if len(parameters) >= 255 {
-				return nil, NewParseError(p.peek(), "Can't have more than 255 parameters.")
+				return nil, nil, NewParseError(p.peek(), "Can't have more than 255 parameters.")
 			}
 
 			param, err := p.consume(IDENTIFIER, "Expect parameter name.")
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 
 			parameters = append(parameters, param)
 
+			annotation, err := p.typeAnnotation()
+			if err != nil {
+				return nil, nil, err
+			}
+			types.Parameters = append(types.Parameters, annotation)
+			annotated = annotated || annotation != nil
+
 			if !p.match(COMMA) {
 				break
 			}
@@ -116,27 +301,46 @@ func (p *Parser) function(kind string) (Stmt, error) {
 	}
 
 	if _, err := p.consume(RIGHT_PAREN, "Expect ')' after parameters."); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if _, err := p.consume(LEFT_BRACE, "Expect '{' before function body."); err != nil {
-		return nil, err
+	annotation, err := p.typeAnnotation()
+	if err != nil {
+		return nil, nil, err
 	}
+	types.Return = annotation
 
-	body, err := p.block()
-	if err != nil {
-		return nil, err
+	if !annotated && annotation == nil {
+		return parameters, nil, nil
+	}
+	return parameters, types, nil
+}
+
+// typeAnnotation parses an optional ": type", returning nil if there is none.
+func (p *Parser) typeAnnotation() (*TypeAnnotation, error) {
+	if !p.match(COLON) {
+		return nil, nil
 	}
 
-	return NewFunctionDeclStmt(name, parameters, body), nil
+	if !p.match(IDENTIFIER, NIL, FUN) {
+		return nil, NewParseError(p.peek(), "Expect type after ':'.")
+	}
+
+	return &TypeAnnotation{Name: p.previous()}, nil
 }
 
 func (p *Parser) varDeclaration() (Stmt, error) {
+	keyword := p.previous()
 	name, err := p.consume(IDENTIFIER, "Expect variable name.")
 	if err != nil {
 		return nil, err
 	}
 
+	annotation, err := p.typeAnnotation()
+	if err != nil {
+		return nil, err
+	}
+
 	var initializer Expr
 	if p.match(EQUAL) {
 		initializer, err = p.expression()
@@ -145,11 +349,15 @@ func (p *Parser) varDeclaration() (Stmt, error) {
 		}
 	}
 
-	if _, err := p.consume(SEMICOLON, "Expect ';' after variable declaration."); err != nil {
+	semicolon, err := p.consume(SEMICOLON, "Expect ';' after variable declaration.")
+	if err != nil {
 		return nil, err
 	}
 
-	return NewVarDeclStmt(name, initializer), nil
+	decl := NewVarDeclStmt(keyword, name, initializer)
+	decl.Type = annotation
+	decl.Semicolon = semicolon
+	return decl, nil
 }
 
 func (p *Parser) statement() (Stmt, error) {
@@ -169,6 +377,14 @@ func (p *Parser) statement() (Stmt, error) {
 		return p.returnStatement()
 	}
 
+	if p.match(THROW) {
+		return p.throwStatement()
+	}
+
+	if p.match(TRY) {
+		return p.tryStatement()
+	}
+
 	if p.match(WHILE) {
 		return p.whileStatement()
 	}
@@ -186,6 +402,7 @@ func (p *Parser) statement() (Stmt, error) {
 }
 
 func (p *Parser) forStatement() (Stmt, error) {
+	keyword := p.previous()
 	if _, err := p.consume(LEFT_PAREN, "Expect '(' after 'for'."); err != nil {
 		return nil, err
 	}
@@ -195,6 +412,10 @@ func (p *Parser) forStatement() (Stmt, error) {
 	if p.match(SEMICOLON) {
 		initializer = nil
 	} else if p.match(VAR) {
+		if p.checkNext(IN) {
+			return p.forInStatement()
+		}
+
 		initializer, err = p.varDeclaration()
 		if err != nil {
 			return nil, err
@@ -233,14 +454,18 @@ func (p *Parser) forStatement() (Stmt, error) {
 		return nil, err
 	}
 
+	clauses := &ForClauses{Initializer: initializer, Condition: condition, Increment: increment}
+
 	if increment != nil {
 		body = NewBlockStmt([]Stmt{body, NewExprStmt(increment)})
 	}
 
 	if condition == nil {
-		condition = NewLiteralExpr(NewLiteral(true))
+		condition = NewLiteralExpr(Token{}, NewLiteral(true))
 	}
-	body = NewWhileStmt(condition, body)
+	loop := NewWhileStmt(keyword, condition, body)
+	loop.For = clauses
+	body = loop
 
 	if initializer != nil {
 		body = NewBlockStmt([]Stmt{initializer, body})
@@ -249,7 +474,36 @@ func (p *Parser) forStatement() (Stmt, error) {
 	return body, nil
 }
 
+func (p *Parser) forInStatement() (Stmt, error) {
+	name, err := p.consume(IDENTIFIER, "Expect variable name.")
+	if err != nil {
+		return nil, err
+	}
+
+	keyword, err := p.consume(IN, "Expect 'in' after loop variable.")
+	if err != nil {
+		return nil, err
+	}
+
+	iterable, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(RIGHT_PAREN, "Expect ')' after for-in clause."); err != nil {
+		return nil, err
+	}
+
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewForInStmt(name, keyword, iterable, body), nil
+}
+
 func (p *Parser) ifStatement() (Stmt, error) {
+	keyword := p.previous()
 	if _, err := p.consume(LEFT_PAREN, "Expect '(' after 'if'."); err != nil {
 		return nil, err
 	}
@@ -276,20 +530,24 @@ func (p *Parser) ifStatement() (Stmt, error) {
 		}
 	}
 
-	return NewIfStmt(condition, thenBranch, elseBranch), nil
+	return NewIfStmt(keyword, condition, thenBranch, elseBranch), nil
 }
 
 func (p *Parser) printStatement() (Stmt, error) {
+	keyword := p.previous()
 	value, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
 
-	if _, err := p.consume(SEMICOLON, "Expect ';' after value."); err != nil {
+	semicolon, err := p.consume(SEMICOLON, "Expect ';' after value.")
+	if err != nil {
 		return nil, err
 	}
 
-	return NewPrintStmt(value), nil
+	stmt := NewPrintStmt(keyword, value)
+	stmt.Semicolon = semicolon
+	return stmt, nil
 }
 
 func (p *Parser) returnStatement() (Stmt, error) {
@@ -303,14 +561,93 @@ func (p *Parser) returnStatement() (Stmt, error) {
 		}
 	}
 
-	if _, err := p.consume(SEMICOLON, "Expect ';' after return value."); err != nil {
+	semicolon, err := p.consume(SEMICOLON, "Expect ';' after return value.")
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := NewReturnStmt(keyword, value)
+	stmt.Semicolon = semicolon
+	return stmt, nil
+}
+
+func (p *Parser) throwStatement() (Stmt, error) {
+	keyword := p.previous()
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	semicolon, err := p.consume(SEMICOLON, "Expect ';' after thrown value.")
+	if err != nil {
 		return nil, err
 	}
 
-	return NewReturnStmt(keyword, value), nil
+	stmt := NewThrowStmt(keyword, value)
+	stmt.Semicolon = semicolon
+	return stmt, nil
+}
+
+func (p *Parser) tryStatement() (Stmt, error) {
+	keyword := p.previous()
+	if _, err := p.consume(LEFT_BRACE, "Expect '{' after 'try'."); err != nil {
+		return nil, err
+	}
+
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+
+	var catchName *Token
+	var catchBody []Stmt
+	if p.match(CATCH) {
+		if _, err := p.consume(LEFT_PAREN, "Expect '(' after 'catch'."); err != nil {
+			return nil, err
+		}
+
+		name, err := p.consume(IDENTIFIER, "Expect exception variable name.")
+		if err != nil {
+			return nil, err
+		}
+		catchName = &name
+
+		if _, err := p.consume(RIGHT_PAREN, "Expect ')' after exception variable."); err != nil {
+			return nil, err
+		}
+
+		if _, err := p.consume(LEFT_BRACE, "Expect '{' after catch clause."); err != nil {
+			return nil, err
+		}
+
+		catchBody, err = p.block()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var finallyBody []Stmt
+	hasFinally := p.match(FINALLY)
+	if hasFinally {
+		if _, err := p.consume(LEFT_BRACE, "Expect '{' after 'finally'."); err != nil {
+			return nil, err
+		}
+
+		finallyBody, err = p.block()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if catchName == nil && !hasFinally {
+		return nil, NewParseError(keyword, "Expect 'catch' or 'finally' after try block.")
+	}
+
+	return NewTryStmt(keyword, body, catchName, catchBody, finallyBody), nil
 }
 
 func (p *Parser) whileStatement() (Stmt, error) {
+	keyword := p.previous()
 	if _, err := p.consume(LEFT_PAREN, "Expect '(' after 'while'."); err != nil {
 		return nil, err
 	}
@@ -329,23 +666,33 @@ func (p *Parser) whileStatement() (Stmt, error) {
 		return nil, err
 	}
 
-	return NewWhileStmt(condition, body), nil
+	return NewWhileStmt(keyword, condition, body), nil
 }
 
 func (p *Parser) block() ([]Stmt, error) {
 	var statements []Stmt
 
 	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+		start := p.current
 		stmt, err := p.declaration()
 		if err != nil {
-			return nil, err
+			if !p.tolerant {
+				return nil, err
+			}
+
+			p.errs = append(p.errs, err)
+			p.skipStatement(start)
+			continue
 		}
 
 		statements = append(statements, stmt)
 	}
 
 	if _, err := p.consume(RIGHT_BRACE, "Expect '}' after block."); err != nil {
-		return nil, err
+		if !p.tolerant {
+			return nil, err
+		}
+		p.errs = append(p.errs, err)
 	}
 
 	return statements, nil
@@ -357,33 +704,55 @@ func (p *Parser) expressionStatement() (Stmt, error) {
 		return nil, err
 	}
 
-	if _, err := p.consume(SEMICOLON, "Expect ';' after value."); err != nil {
+	semicolon, err := p.consume(SEMICOLON, "Expect ';' after value.")
+	if err != nil {
 		return nil, err
 	}
 
-	return NewExprStmt(expr), nil
+	stmt := NewExprStmt(expr)
+	stmt.Semicolon = semicolon
+	return stmt, nil
 }
 
 func (p *Parser) expression() (Expr, error) {
 	return p.assignment()
 }
 
+// compoundOperators maps each compound assignment token to the binary
+// operator it applies.
+var compoundOperators = map[TokenType]TokenType{
+	PLUS_EQUAL:    PLUS,
+	MINUS_EQUAL:   MINUS,
+	STAR_EQUAL:    STAR,
+	SLASH_EQUAL:   SLASH,
+	PERCENT_EQUAL: PERCENT,
+}
+
 func (p *Parser) assignment() (Expr, error) {
-	expr, err := p.or()
+	expr, err := p.conditional()
 	if err != nil {
 		return nil, err
 	}
 
-	if p.match(EQUAL) {
+	if p.match(EQUAL, PLUS_EQUAL, MINUS_EQUAL, STAR_EQUAL, SLASH_EQUAL, PERCENT_EQUAL) {
 		equals := p.previous()
 		value, err := p.assignment()
 		if err != nil {
 			return nil, err
 		}
 
-		if v, ok := expr.(*VariableExpr); ok {
-			name := v.Name
-			return NewAssignExpr(name, value), nil
+		var operator *Token
+		if operatorType, ok := compoundOperators[equals.Type]; ok {
+			operator = &Token{Type: operatorType, Lexeme: equals.Lexeme[:len(equals.Lexeme)-1], Line: equals.Line, Column: equals.Column}
+		}
+
+		switch target := expr.(type) {
+		case *VariableExpr:
+			return NewAssignExpr(target.Name, operator, value), nil
+		case *GetExpr:
+			return NewSetExpr(target.Object, target.Name, operator, value), nil
+		case *IndexExpr:
+			return NewIndexSetExpr(target.Object, target.Bracket, target.Index, operator, value), nil
 		}
 
 		return nil, NewParseError(equals, "Invalid assignment target.")
@@ -392,6 +761,33 @@ func (p *Parser) assignment() (Expr, error) {
 	return expr, nil
 }
 
+func (p *Parser) conditional() (Expr, error) {
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(QUESTION) {
+		thenBranch, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.consume(COLON, "Expect ':' after then branch of conditional expression."); err != nil {
+			return nil, err
+		}
+
+		elseBranch, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+
+		expr = NewConditionalExpr(expr, thenBranch, elseBranch)
+	}
+
+	return expr, nil
+}
+
 func (p *Parser) or() (Expr, error) {
 	expr, err := p.and()
 	if err != nil {
@@ -493,7 +889,7 @@ func (p *Parser) factor() (Expr, error) {
 		return nil, err
 	}
 
-	for p.match(SLASH, STAR) {
+	for p.match(SLASH, STAR, PERCENT) {
 		operator := p.previous()
 		right, err := p.unary()
 		if err != nil {
@@ -517,7 +913,28 @@ func (p *Parser) unary() (Expr, error) {
 		return NewUnaryExpr(operator, right), nil
 	}
 
-	return p.call()
+	return p.power()
+}
+
+func (p *Parser) power() (Expr, error) {
+	expr, err := p.call()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(STAR_STAR) {
+		operator := p.previous()
+		// Recursing through unary makes "**" right-associative and lets the
+		// exponent be negated, as in 2 ** -1.
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		expr = NewBinaryExpr(expr, operator, right)
+	}
+
+	return expr, nil
 }
 
 func (p *Parser) call() (Expr, error) {
@@ -532,6 +949,25 @@ func (p *Parser) call() (Expr, error) {
 			if err != nil {
 				return nil, err
 			}
+		} else if p.match(DOT) {
+			name, err := p.consume(IDENTIFIER, "Expect property name after '.'.")
+			if err != nil {
+				return nil, err
+			}
+
+			expr = NewGetExpr(expr, name)
+		} else if p.match(LEFT_BRACKET) {
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+
+			bracket, err := p.consume(RIGHT_BRACKET, "Expect ']' after index.")
+			if err != nil {
+				return nil, err
+			}
+
+			expr = NewIndexExpr(expr, bracket, index)
 		} else {
 			break
 		}
@@ -571,25 +1007,37 @@ func (p *Parser) finishCall(callee Expr) (Expr, error) {
 
 func (p *Parser) primary() (Expr, error) {
 	if p.match(NUMBER, STRING) {
-		return NewLiteralExpr(p.previous().Literal), nil
+		return NewLiteralExpr(p.previous(), p.previous().Literal), nil
+	}
+
+	if p.match(INTERPOLATION) {
+		return p.interpolation()
 	}
 
 	if p.match(FALSE) {
-		return NewLiteralExpr(NewLiteral(false)), nil
+		return NewLiteralExpr(p.previous(), NewLiteral(false)), nil
 	}
 
 	if p.match(TRUE) {
-		return NewLiteralExpr(NewLiteral(true)), nil
+		return NewLiteralExpr(p.previous(), NewLiteral(true)), nil
 	}
 
 	if p.match(NIL) {
-		return NewLiteralExpr(NewLiteral(nil)), nil
+		return NewLiteralExpr(p.previous(), NewLiteral(nil)), nil
 	}
 
 	if p.match(IDENTIFIER) {
 		return NewVariableExpr(p.previous()), nil
 	}
 
+	if p.match(FUN) {
+		return p.lambda()
+	}
+
+	if p.isArrowFunction() {
+		return p.arrowFunction()
+	}
+
 	if p.match(LEFT_PAREN) {
 		expr, err := p.expression()
 		if err != nil {
@@ -606,6 +1054,138 @@ func (p *Parser) primary() (Expr, error) {
 	return nil, NewParseError(p.peek(), "Expect expression.")
 }
 
+// interpolation desugars "a${b}c" into "a" + str(b) + "c".
+func (p *Parser) interpolation() (Expr, error) {
+	segment := p.previous()
+	var expr Expr = NewLiteralExpr(segment, segment.Literal)
+
+	for {
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+
+		plus := Token{Type: PLUS, Lexeme: "+", Line: segment.Line, Column: segment.Column}
+		expr = NewBinaryExpr(expr, plus, NewStringifyExpr(value))
+
+		if p.match(INTERPOLATION) {
+			segment = p.previous()
+			expr = NewBinaryExpr(expr, plus, NewLiteralExpr(segment, segment.Literal))
+			continue
+		}
+
+		segment, err = p.consume(STRING, "Expect '}' after interpolated expression.")
+		if err != nil {
+			return nil, err
+		}
+
+		return NewBinaryExpr(expr, plus, NewLiteralExpr(segment, segment.Literal)), nil
+	}
+}
+
+func (p *Parser) lambda() (Expr, error) {
+	keyword := p.previous()
+
+	if _, err := p.consume(LEFT_PAREN, "Expect '(' after 'fun'."); err != nil {
+		return nil, err
+	}
+
+	parameters, types, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(LEFT_BRACE, "Expect '{' before function body."); err != nil {
+		return nil, err
+	}
+
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+
+	function := NewFunctionExpr(keyword, parameters, body)
+	function.Types = types
+	return function, nil
+}
+
+func (p *Parser) arrowFunction() (Expr, error) {
+	if _, err := p.consume(LEFT_PAREN, "Expect '(' before parameters."); err != nil {
+		return nil, err
+	}
+
+	parameters, types, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+
+	arrow, err := p.consume(ARROW, "Expect '=>' after parameters.")
+	if err != nil {
+		return nil, err
+	}
+
+	var body []Stmt
+	if p.match(LEFT_BRACE) {
+		body, err = p.block()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// An expression body is shorthand for a block that returns it.
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		body = []Stmt{NewReturnStmt(arrow, value)}
+	}
+
+	function := NewFunctionExpr(arrow, parameters, body)
+	function.Types = types
+	return function, nil
+}
+
+// isArrowFunction looks ahead for "(" parameters? ")" "=>" without consuming
+// anything, so a parenthesized expression can still be parsed as a grouping.
+// Parameters and the return value may carry type annotations.
+func (p *Parser) isArrowFunction() bool {
+	if !p.check(LEFT_PAREN) {
+		return false
+	}
+
+	i := p.current + 1
+	skipAnnotation := func() {
+		if p.tokens[i].Type == COLON {
+			switch p.tokens[i+1].Type {
+			case IDENTIFIER, NIL, FUN:
+				i += 2
+			}
+		}
+	}
+
+	if p.tokens[i].Type != RIGHT_PAREN {
+		for {
+			if p.tokens[i].Type != IDENTIFIER {
+				return false
+			}
+			i++
+			skipAnnotation()
+
+			if p.tokens[i].Type != COMMA {
+				break
+			}
+			i++
+		}
+	}
+
+	if p.tokens[i].Type != RIGHT_PAREN {
+		return false
+	}
+	i++
+	skipAnnotation()
+
+	return p.tokens[i].Type == ARROW
+}
+
 func (p *Parser) match(tokens ...TokenType) bool {
 	for _, token := range tokens {
 		if p.check(token) {
@@ -623,6 +1203,15 @@ func (p *Parser) consume(tokenType TokenType, message string) (Token, error) {
 	return Token{}, NewParseError(p.peek(), message)
 }
 
+// consumeContextual consumes an identifier that acts as a keyword only in
+// certain positions, such as "as" and "from" in imports.
+func (p *Parser) consumeContextual(lexeme string, message string) (Token, error) {
+	if p.check(IDENTIFIER) && p.peek().Lexeme == lexeme {
+		return p.advance(), nil
+	}
+	return Token{}, NewParseError(p.peek(), message)
+}
+
 func (p *Parser) check(tokenType TokenType) bool {
 	if p.isAtEnd() {
 		return false
@@ -630,6 +1219,13 @@ func (p *Parser) check(tokenType TokenType) bool {
 	return p.peek().Type == tokenType
 }
 
+func (p *Parser) checkNext(tokenType TokenType) bool {
+	if p.isAtEnd() || p.current+1 >= len(p.tokens) {
+		return false
+	}
+	return p.tokens[p.current+1].Type == tokenType
+}
+
 func (p *Parser) advance() Token {
 	if !p.isAtEnd() {
 		p.current++
@@ -649,6 +1245,37 @@ func (p *Parser) previous() Token {
 	return p.tokens[p.current-1]
 }
 
+// skipStatement discards the rest of a statement that failed to parse,
+// starting from the token at start. Unlike synchronize it never leaves the
+// enclosing block, and it skips nested blocks whole.
+func (p *Parser) skipStatement(start int) {
+	depth := 0
+	for !p.isAtEnd() {
+		if p.current > start && depth == 0 {
+			if p.previous().Type == SEMICOLON {
+				return
+			}
+
+			switch p.peek().Type {
+			case CLASS, FUN, VAR, FOR, IF, WHILE, PRINT, RETURN, THROW, TRY, IMPORT, EXPORT:
+				return
+			}
+		}
+
+		switch p.peek().Type {
+		case LEFT_BRACE:
+			depth++
+		case RIGHT_BRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+
+		p.advance()
+	}
+}
+
 func (p *Parser) synchronize() {
 	p.advance()
 
@@ -658,7 +1285,7 @@ func (p *Parser) synchronize() {
 		}
 
 		switch p.peek().Type {
-		case CLASS, FUN, VAR, FOR, IF, WHILE, PRINT, RETURN:
+		case CLASS, FUN, VAR, FOR, IF, WHILE, PRINT, RETURN, THROW, TRY, IMPORT, EXPORT:
 			return
 		}
 
@@ -0,0 +1,73 @@
+package lox
+
+import (
+	"context"
+	"errors"
+)
+
+// Program is a script prepared once and run any number of times, either as a
+// resolved syntax tree for the Interpreter or as a compiled chunk for the VM.
+// A Program is never modified after it is created, so many goroutines may run
+// it at once as long as each uses its own Interpreter or VM.
+type Program struct {
+	statements []Stmt
+	locals     map[Expr]int
+
+	chunk *Chunk
+}
+
+// ParseProgram scans, parses, resolves and optimizes source for the
+// Interpreter.
+func ParseProgram(source string) (*Program, error) {
+	tokens, errs := NewScanner(source).ScanTokens()
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	statements, err := NewParser(tokens).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	locals := make(map[Expr]int)
+	if err := newResolver(locals).Resolve(statements); err != nil {
+		return nil, err
+	}
+
+	return &Program{statements: NewOptimizer().Optimize(statements), locals: locals}, nil
+}
+
+// CompileProgram compiles source for the VM. Compile errors are reported to
+// the stderr set in opts.
+func CompileProgram(source string, opts ...Option) (*Program, error) {
+	var chunk Chunk
+	if !compile(source, &chunk, newOptions(opts)) {
+		return nil, ErrInterpretCompile
+	}
+
+	return &Program{chunk: &chunk}, nil
+}
+
+// Run executes the program with a fresh Interpreter or VM configured by opts.
+func (p *Program) Run(ctx context.Context, opts ...Option) error {
+	if p.chunk != nil {
+		return NewVM(p.chunk, opts...).runContext(ctx)
+	}
+
+	return NewInterpreter(opts...).RunProgram(ctx, p)
+}
+
+// RunProgram executes a program prepared by ParseProgram in this interpreter's
+// global environment. The program's scope information is copied in, so the
+// interpreter never writes to the shared Program.
+func (i *Interpreter) RunProgram(ctx context.Context, program *Program) error {
+	if program.chunk != nil {
+		return errors.New("program was compiled for the VM")
+	}
+
+	for expr, depth := range program.locals {
+		i.locals[expr] = depth
+	}
+
+	return i.InterpretContext(ctx, program.statements)
+}
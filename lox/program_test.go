@@ -0,0 +1,120 @@
+package lox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests in this file are meant to be run with -race: every goroutine
+// shares one Program and must not write to it.
+
+func TestProgramConcurrentRuns(t *testing.T) {
+	program, err := ParseProgram(`
+fun counter() {
+  var count = 0;
+  return () => { count += 1; return count; };
+}
+
+var next = counter();
+var total = 0;
+for (var c in "abc") {
+  total += next();
+}
+
+try {
+  throw "id " + str(id);
+} catch (e) {
+  print "${e}: ${total} ${upper("done")}";
+}
+`)
+	require.NoError(t, err)
+
+	const runs = 8
+	outputs := make([]bytes.Buffer, runs)
+
+	var wg sync.WaitGroup
+	for n := 0; n < runs; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			interpreter := NewInterpreter(WithStdout(&outputs[n]))
+			assert.NoError(t, interpreter.SetGlobal("id", n))
+			assert.NoError(t, interpreter.RunProgram(context.Background(), program))
+		}(n)
+	}
+	wg.Wait()
+
+	for n := range outputs {
+		assert.Equal(t, fmt.Sprintf("id %d: 6 DONE\n", n), outputs[n].String())
+	}
+}
+
+func TestProgramRunWithLimits(t *testing.T) {
+	program, err := ParseProgram(`var i = 0; while (i < 100) i = i + 1; print i;`)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			var output bytes.Buffer
+			assert.NoError(t, program.Run(context.Background(), WithStdout(&output)))
+			assert.Equal(t, "100\n", output.String())
+		}()
+		go func() {
+			defer wg.Done()
+			var stepErr *StepLimitError
+			assert.ErrorAs(t, program.Run(context.Background(), WithMaxSteps(50)), &stepErr)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCompiledProgramConcurrentRuns(t *testing.T) {
+	program, err := CompileProgram("(1 + 2) * 3 ** 2")
+	require.NoError(t, err)
+
+	outputs := make([]bytes.Buffer, 8)
+	var wg sync.WaitGroup
+	for n := range outputs {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			assert.NoError(t, program.Run(context.Background(), WithStdout(&outputs[n])))
+		}(n)
+	}
+	wg.Wait()
+
+	for n := range outputs {
+		assert.Equal(t, "27\n", outputs[n].String())
+	}
+
+	assert.Error(t, NewInterpreter().RunProgram(context.Background(), program))
+}
+
+func TestProgramErrors(t *testing.T) {
+	_, err := ParseProgram(`print "unterminated;`)
+	assert.EqualError(t, err, "[line 1] Error: Unterminated string.")
+
+	_, err = ParseProgram(`print ;`)
+	assert.EqualError(t, err, "[line 1] Error at ';': Expect expression.")
+
+	_, err = ParseProgram(`{ var a = a; }`)
+	assert.EqualError(t, err, "[line 1] Can't read local variable in its own initializer.")
+
+	_, err = ParseProgram(`{ var a = a += 1; }`)
+	assert.EqualError(t, err, "[line 1] Can't read local variable in its own initializer.")
+
+	var stderr strings.Builder
+	_, err = CompileProgram("1 +", WithStderr(&stderr))
+	assert.ErrorIs(t, err, ErrInterpretCompile)
+	assert.Equal(t, "[line 1] Error at end: Expect expression.\n", stderr.String())
+}
@@ -1,9 +1,14 @@
 package lox
 
 type Resolver struct {
-	interpreter     *Interpreter
+	locals          map[Expr]int // where the scope depth of each local is recorded
 	scopes          []map[string]bool
 	currentFunction FunctionType
+
+	// analysis is set by Analyze to record symbols as they are resolved.
+	// Errors are then collected in errs instead of stopping resolution.
+	analysis *Analysis
+	errs     []error
 }
 
 type FunctionType int
@@ -14,34 +19,35 @@ const (
 )
 
 func NewResolver(interpreter *Interpreter) *Resolver {
+	return newResolver(interpreter.locals)
+}
+
+func newResolver(locals map[Expr]int) *Resolver {
 	return &Resolver{
-		interpreter:     interpreter,
+		locals:          locals,
 		scopes:          []map[string]bool{},
 		currentFunction: NONE,
 	}
 }
 
 var (
-	_ exprVisitor = (*Resolver)(nil)
-	_ stmtVisitor = (*Resolver)(nil)
+	_ ExprVisitor = (*Resolver)(nil)
+	_ StmtVisitor = (*Resolver)(nil)
 )
 
-func (r *Resolver) visitBlockStmt(stmt *BlockStmt) (any, error) {
-	r.beginScope()
-
-	if err := r.Resolve(stmt.Statements); err != nil {
-		return nil, err
-	}
-
-	r.endScope()
-	return nil, nil
+func (r *Resolver) VisitBlockStmt(stmt *BlockStmt) (any, error) {
+	return nil, r.resolveBlock(stmt.Statements)
 }
 
-func (r *Resolver) visitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
+func (r *Resolver) VisitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
 	if err := r.declare(stmt.Name); err != nil {
 		return nil, err
 	}
 
+	if function, ok := stmt.Initializer.(*FunctionExpr); ok {
+		r.describe(stmt.Name, SymbolFunction, function.Parameters)
+	}
+
 	if stmt.Initializer != nil {
 		if err := r.resolveExpr(stmt.Initializer); err != nil {
 			return nil, err
@@ -52,18 +58,33 @@ func (r *Resolver) visitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
 	return nil, nil
 }
 
-func (r *Resolver) visitVariableExpr(expr *VariableExpr) (any, error) {
-	if len(r.scopes) > 0 {
-		if ready, ok := r.scopes[len(r.scopes)-1][expr.Name.Lexeme]; ok && !ready {
-			return nil, NewRuntimeError(expr.Name, "Can't read local variable in its own initializer.")
-		}
+func (r *Resolver) VisitVariableExpr(expr *VariableExpr) (any, error) {
+	if err := r.checkRead(expr.Name); err != nil {
+		return nil, err
 	}
 
 	r.resolveLocal(expr, expr.Name)
 	return nil, nil
 }
 
-func (r *Resolver) visitAssignExpr(expr *AssignExpr) (any, error) {
+// checkRead reports a read of a local variable in its own initializer.
+func (r *Resolver) checkRead(name Token) error {
+	if len(r.scopes) > 0 {
+		if ready, ok := r.scopes[len(r.scopes)-1][name.Lexeme]; ok && !ready {
+			return r.fail(NewRuntimeError(name, "Can't read local variable in its own initializer."))
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) VisitAssignExpr(expr *AssignExpr) (any, error) {
+	// A compound assignment reads the variable too.
+	if expr.Operator != nil {
+		if err := r.checkRead(expr.Name); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := r.resolveExpr(expr.Value); err != nil {
 		return nil, err
 	}
@@ -72,21 +93,32 @@ func (r *Resolver) visitAssignExpr(expr *AssignExpr) (any, error) {
 	return nil, nil
 }
 
-func (r *Resolver) visitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error) {
+func (r *Resolver) VisitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error) {
 	if err := r.declare(stmt.Name); err != nil {
 		return nil, err
 	}
 
+	r.describe(stmt.Name, SymbolFunction, stmt.Parameters)
 	r.define(stmt.Name)
 
-	return nil, r.resolveFunction(stmt, FUNCTION)
+	if r.analysis != nil {
+		enclosing := r.analysis.container
+		r.analysis.container = r.analysis.lookUp(stmt.Name, len(r.scopes)-1)
+		defer func() { r.analysis.container = enclosing }()
+	}
+
+	return nil, r.resolveFunction(stmt.Parameters, stmt.Body, FUNCTION)
+}
+
+func (r *Resolver) VisitFunctionExpr(expr *FunctionExpr) (any, error) {
+	return nil, r.resolveFunction(expr.Parameters, expr.Body, FUNCTION)
 }
 
-func (r *Resolver) visitExprStmt(stmt *ExprStmt) (any, error) {
+func (r *Resolver) VisitExprStmt(stmt *ExprStmt) (any, error) {
 	return nil, r.resolveExpr(stmt.Expression)
 }
 
-func (r *Resolver) visitIfStmt(stmt *IfStmt) (any, error) {
+func (r *Resolver) VisitIfStmt(stmt *IfStmt) (any, error) {
 	if err := r.resolveExpr(stmt.Condition); err != nil {
 		return nil, err
 	}
@@ -104,13 +136,15 @@ func (r *Resolver) visitIfStmt(stmt *IfStmt) (any, error) {
 	return nil, nil
 }
 
-func (r *Resolver) visitPrintStmt(stmt *PrintStmt) (any, error) {
+func (r *Resolver) VisitPrintStmt(stmt *PrintStmt) (any, error) {
 	return nil, r.resolveExpr(stmt.Expression)
 }
 
-func (r *Resolver) visitReturnStmt(stmt *ReturnStmt) (any, error) {
+func (r *Resolver) VisitReturnStmt(stmt *ReturnStmt) (any, error) {
 	if r.currentFunction == NONE {
-		return nil, NewRuntimeError(stmt.Keyword, "Can't return from top-level code.")
+		if err := r.fail(NewRuntimeError(stmt.Keyword, "Can't return from top-level code.")); err != nil {
+			return nil, err
+		}
 	}
 
 	if stmt.Value != nil {
@@ -122,7 +156,69 @@ func (r *Resolver) visitReturnStmt(stmt *ReturnStmt) (any, error) {
 	return nil, nil
 }
 
-func (r *Resolver) visitWhileStmt(stmt *WhileStmt) (any, error) {
+func (r *Resolver) VisitThrowStmt(stmt *ThrowStmt) (any, error) {
+	return nil, r.resolveExpr(stmt.Value)
+}
+
+func (r *Resolver) VisitTryStmt(stmt *TryStmt) (any, error) {
+	if err := r.resolveBlock(stmt.Body); err != nil {
+		return nil, err
+	}
+
+	if stmt.CatchName != nil {
+		r.beginScope()
+
+		if err := r.declare(*stmt.CatchName); err != nil {
+			return nil, err
+		}
+
+		r.define(*stmt.CatchName)
+
+		if err := r.Resolve(stmt.CatchBody); err != nil {
+			return nil, err
+		}
+
+		r.endScope()
+	}
+
+	if stmt.FinallyBody != nil {
+		if err := r.resolveBlock(stmt.FinallyBody); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *Resolver) VisitImportStmt(stmt *ImportStmt) (any, error) {
+	names := stmt.Names
+	if stmt.Alias != nil {
+		names = []Token{*stmt.Alias}
+	}
+
+	for _, name := range names {
+		if err := r.declare(name); err != nil {
+			return nil, err
+		}
+
+		r.describe(name, SymbolImport, nil)
+		r.define(name)
+	}
+
+	return nil, nil
+}
+
+func (r *Resolver) VisitExportStmt(stmt *ExportStmt) (any, error) {
+	if len(r.scopes) > 0 {
+		if err := r.fail(NewRuntimeError(stmt.Keyword, "Can only export from top-level code.")); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, r.resolveStmt(stmt.Declaration)
+}
+
+func (r *Resolver) VisitWhileStmt(stmt *WhileStmt) (any, error) {
 	if err := r.resolveExpr(stmt.Condition); err != nil {
 		return nil, err
 	}
@@ -130,7 +226,28 @@ func (r *Resolver) visitWhileStmt(stmt *WhileStmt) (any, error) {
 	return nil, r.resolveStmt(stmt.Body)
 }
 
-func (r *Resolver) visitBinaryExpr(expr *BinaryExpr) (any, error) {
+func (r *Resolver) VisitForInStmt(stmt *ForInStmt) (any, error) {
+	if err := r.resolveExpr(stmt.Iterable); err != nil {
+		return nil, err
+	}
+
+	r.beginScope()
+
+	if err := r.declare(stmt.Name); err != nil {
+		return nil, err
+	}
+
+	r.define(stmt.Name)
+
+	if err := r.resolveStmt(stmt.Body); err != nil {
+		return nil, err
+	}
+
+	r.endScope()
+	return nil, nil
+}
+
+func (r *Resolver) VisitBinaryExpr(expr *BinaryExpr) (any, error) {
 	if err := r.resolveExpr(expr.Left); err != nil {
 		return nil, err
 	}
@@ -138,7 +255,7 @@ func (r *Resolver) visitBinaryExpr(expr *BinaryExpr) (any, error) {
 	return nil, r.resolveExpr(expr.Right)
 }
 
-func (r *Resolver) visitCallExpr(expr *CallExpr) (any, error) {
+func (r *Resolver) VisitCallExpr(expr *CallExpr) (any, error) {
 	if err := r.resolveExpr(expr.Callee); err != nil {
 		return nil, err
 	}
@@ -152,15 +269,63 @@ func (r *Resolver) visitCallExpr(expr *CallExpr) (any, error) {
 	return nil, nil
 }
 
-func (r *Resolver) visitGroupingExpr(expr *GroupingExpr) (any, error) {
+func (r *Resolver) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	if err := r.resolveExpr(expr.Condition); err != nil {
+		return nil, err
+	}
+
+	if err := r.resolveExpr(expr.ThenBranch); err != nil {
+		return nil, err
+	}
+
+	return nil, r.resolveExpr(expr.ElseBranch)
+}
+
+func (r *Resolver) VisitStringifyExpr(expr *StringifyExpr) (any, error) {
+	return nil, r.resolveExpr(expr.Expression)
+}
+
+func (r *Resolver) VisitGetExpr(expr *GetExpr) (any, error) {
+	return nil, r.resolveExpr(expr.Object)
+}
+
+func (r *Resolver) VisitSetExpr(expr *SetExpr) (any, error) {
+	if err := r.resolveExpr(expr.Value); err != nil {
+		return nil, err
+	}
+
+	return nil, r.resolveExpr(expr.Object)
+}
+
+func (r *Resolver) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	if err := r.resolveExpr(expr.Object); err != nil {
+		return nil, err
+	}
+
+	return nil, r.resolveExpr(expr.Index)
+}
+
+func (r *Resolver) VisitIndexSetExpr(expr *IndexSetExpr) (any, error) {
+	if err := r.resolveExpr(expr.Value); err != nil {
+		return nil, err
+	}
+
+	if err := r.resolveExpr(expr.Object); err != nil {
+		return nil, err
+	}
+
+	return nil, r.resolveExpr(expr.Index)
+}
+
+func (r *Resolver) VisitGroupingExpr(expr *GroupingExpr) (any, error) {
 	return nil, r.resolveExpr(expr.Expression)
 }
 
-func (r *Resolver) visitLiteralExpr(expr *LiteralExpr) (any, error) {
+func (r *Resolver) VisitLiteralExpr(expr *LiteralExpr) (any, error) {
 	return nil, nil
 }
 
-func (r *Resolver) visitLogicalExpr(expr *LogicalExpr) (any, error) {
+func (r *Resolver) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
 	if err := r.resolveExpr(expr.Left); err != nil {
 		return nil, err
 	}
@@ -168,7 +333,7 @@ func (r *Resolver) visitLogicalExpr(expr *LogicalExpr) (any, error) {
 	return nil, r.resolveExpr(expr.Right)
 }
 
-func (r *Resolver) visitUnaryExpr(expr *UnaryExpr) (any, error) {
+func (r *Resolver) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
 	return nil, r.resolveExpr(expr.Right)
 }
 
@@ -183,31 +348,43 @@ func (r *Resolver) Resolve(statements []Stmt) error {
 }
 
 func (r *Resolver) resolveStmt(stmt Stmt) error {
-	_, err := stmt.accept(r)
+	_, err := stmt.Accept(r)
 	return err
 }
 
 func (r *Resolver) resolveExpr(expr Expr) error {
-	_, err := expr.accept(r)
+	_, err := expr.Accept(r)
 	return err
 }
 
-func (r *Resolver) resolveFunction(function *FunctionDeclStmt, functionType FunctionType) error {
+func (r *Resolver) resolveBlock(statements []Stmt) error {
+	r.beginScope()
+
+	if err := r.Resolve(statements); err != nil {
+		return err
+	}
+
+	r.endScope()
+	return nil
+}
+
+func (r *Resolver) resolveFunction(parameters []Token, body []Stmt, functionType FunctionType) error {
 	enclosingFunction := r.currentFunction
 	r.currentFunction = functionType
 	defer func() { r.currentFunction = enclosingFunction }()
 
 	r.beginScope()
 
-	for _, param := range function.Parameters {
+	for _, param := range parameters {
 		if err := r.declare(param); err != nil {
 			return err
 		}
 
+		r.describe(param, SymbolParameter, nil)
 		r.define(param)
 	}
 
-	if err := r.Resolve(function.Body); err != nil {
+	if err := r.Resolve(body); err != nil {
 		return err
 	}
 
@@ -217,26 +394,55 @@ func (r *Resolver) resolveFunction(function *FunctionDeclStmt, functionType Func
 
 func (r *Resolver) beginScope() {
 	r.scopes = append(r.scopes, map[string]bool{})
+	if r.analysis != nil {
+		r.analysis.beginScope()
+	}
 }
 
 func (r *Resolver) endScope() {
 	r.scopes = r.scopes[:len(r.scopes)-1]
+	if r.analysis != nil {
+		r.analysis.endScope()
+	}
 }
 
 func (r *Resolver) declare(name Token) error {
+	if r.analysis != nil {
+		r.analysis.declare(name)
+	}
+
 	if len(r.scopes) == 0 {
 		return nil
 	}
 
 	scope := r.scopes[len(r.scopes)-1]
 	if _, ok := scope[name.Lexeme]; ok {
-		return NewRuntimeError(name, "Already a variable with this name in this scope.")
+		return r.fail(NewRuntimeError(name, "Already a variable with this name in this scope."))
 	}
 
 	scope[name.Lexeme] = false
 	return nil
 }
 
+// describe records what kind of symbol was just declared for name, when
+// analyzing.
+func (r *Resolver) describe(name Token, kind SymbolKind, parameters []Token) {
+	if r.analysis != nil {
+		r.analysis.describe(name, kind, parameters)
+	}
+}
+
+// fail returns err, unless analyzing, in which case err is recorded and
+// resolution carries on.
+func (r *Resolver) fail(err error) error {
+	if r.analysis == nil {
+		return err
+	}
+
+	r.errs = append(r.errs, err)
+	return nil
+}
+
 func (r *Resolver) define(name Token) {
 	if len(r.scopes) == 0 {
 		return
@@ -249,8 +455,15 @@ func (r *Resolver) define(name Token) {
 func (r *Resolver) resolveLocal(expr Expr, name Token) {
 	for i := len(r.scopes) - 1; i >= 0; i-- {
 		if _, ok := r.scopes[i][name.Lexeme]; ok {
-			r.interpreter.resolve(expr, len(r.scopes)-1-i)
+			r.locals[expr] = len(r.scopes) - 1 - i
+			if r.analysis != nil {
+				r.analysis.use(name, i)
+			}
 			return
 		}
 	}
+
+	if r.analysis != nil {
+		r.analysis.use(name, -1)
+	}
 }
@@ -3,6 +3,7 @@ package lox
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -14,6 +15,21 @@ type Scanner struct {
 	current int // the character currently being considered
 	line    int // tracks what source line `current` is on
 	errs    []error
+
+	lineStart   int // the first character of the line `current` is on
+	startColumn int // the column of `start`
+
+	// unterminated is set when the source ends inside a string.
+	unterminated bool
+
+	// comments collects skipped comments once RecordComments is called.
+	recordComments bool
+	comments       []Comment
+	lastTokenLine  int
+
+	// interpolations holds, for each "${" we are inside of, how many
+	// unclosed '{' have been seen since it was opened.
+	interpolations []int
 }
 
 func NewScanner(source string) *Scanner {
@@ -26,14 +42,29 @@ func NewScanner(source string) *Scanner {
 	}
 }
 
+// RecordComments makes the scanner keep the comments it skips, for tools such
+// as the formatter that need to reproduce them.
+func (s *Scanner) RecordComments() {
+	s.recordComments = true
+}
+
+// Comments returns the comments skipped so far, in source order. It is empty
+// unless RecordComments was called before scanning.
+func (s *Scanner) Comments() []Comment {
+	return s.comments
+}
+
 func (s *Scanner) ScanTokens() ([]Token, []error) {
-	for !s.isAtEnd() {
+	for {
 		token, err := s.scanToken()
 		if err != nil {
 			s.errs = append(s.errs, err)
 		}
 
 		s.tokens = append(s.tokens, token)
+		if err == nil && token.Type == EOF {
+			break
+		}
 	}
 
 	return s.tokens, s.errs
@@ -42,6 +73,7 @@ func (s *Scanner) ScanTokens() ([]Token, []error) {
 func (s *Scanner) scanToken() (Token, error) {
 	s.skipWhitespace()
 	s.start = s.current
+	s.startColumn = s.start - s.lineStart + 1
 
 	if s.isAtEnd() {
 		return s.makeToken(EOF), nil
@@ -54,21 +86,60 @@ func (s *Scanner) scanToken() (Token, error) {
 	case ')':
 		return s.makeToken(RIGHT_PAREN), nil
 	case '{':
+		if n := len(s.interpolations); n > 0 {
+			s.interpolations[n-1]++
+		}
 		return s.makeToken(LEFT_BRACE), nil
 	case '}':
+		if n := len(s.interpolations); n > 0 {
+			if s.interpolations[n-1] == 0 {
+				// This closes the interpolated expression; resume the string.
+				s.interpolations = s.interpolations[:n-1]
+				return s.string()
+			}
+			s.interpolations[n-1]--
+		}
 		return s.makeToken(RIGHT_BRACE), nil
+	case '[':
+		return s.makeToken(LEFT_BRACKET), nil
+	case ']':
+		return s.makeToken(RIGHT_BRACKET), nil
 	case ',':
 		return s.makeToken(COMMA), nil
 	case '.':
 		return s.makeToken(DOT), nil
 	case '-':
-		return s.makeToken(MINUS), nil
+		if s.match('=') {
+			return s.makeToken(MINUS_EQUAL), nil
+		} else {
+			return s.makeToken(MINUS), nil
+		}
 	case '+':
-		return s.makeToken(PLUS), nil
+		if s.match('=') {
+			return s.makeToken(PLUS_EQUAL), nil
+		} else {
+			return s.makeToken(PLUS), nil
+		}
 	case ';':
 		return s.makeToken(SEMICOLON), nil
+	case '?':
+		return s.makeToken(QUESTION), nil
+	case ':':
+		return s.makeToken(COLON), nil
 	case '*':
-		return s.makeToken(STAR), nil
+		if s.match('*') {
+			return s.makeToken(STAR_STAR), nil
+		} else if s.match('=') {
+			return s.makeToken(STAR_EQUAL), nil
+		} else {
+			return s.makeToken(STAR), nil
+		}
+	case '%':
+		if s.match('=') {
+			return s.makeToken(PERCENT_EQUAL), nil
+		} else {
+			return s.makeToken(PERCENT), nil
+		}
 	case '!':
 		if s.match('=') {
 			return s.makeToken(BANG_EQUAL), nil
@@ -78,6 +149,8 @@ func (s *Scanner) scanToken() (Token, error) {
 	case '=':
 		if s.match('=') {
 			return s.makeToken(EQUAL_EQUAL), nil
+		} else if s.match('>') {
+			return s.makeToken(ARROW), nil
 		} else {
 			return s.makeToken(EQUAL), nil
 		}
@@ -94,7 +167,11 @@ func (s *Scanner) scanToken() (Token, error) {
 			return s.makeToken(GREATER), nil
 		}
 	case '/':
-		return s.makeToken(SLASH), nil
+		if s.match('=') {
+			return s.makeToken(SLASH_EQUAL), nil
+		} else {
+			return s.makeToken(SLASH), nil
+		}
 	case '"':
 		return s.string()
 	default:
@@ -105,7 +182,7 @@ func (s *Scanner) scanToken() (Token, error) {
 			return s.identifier(), nil
 		}
 
-		return Token{}, fmt.Errorf("[line %d] Error: Unexpected character: %s", s.line, string(r))
+		return Token{}, s.error("Unexpected character: %s", string(r))
 	}
 }
 
@@ -122,12 +199,23 @@ func (s *Scanner) skipWhitespace() {
 		case '\n':
 			s.line++
 			s.advance()
+			s.lineStart = s.current
 		case '/':
-			if s.match('/') {
+			if s.peekNext() == '/' {
 				// A comment goes until the end of the line.
+				start := s.current
 				for s.peek() != '\n' && !s.isAtEnd() {
 					s.advance()
 				}
+
+				if s.recordComments {
+					s.comments = append(s.comments, Comment{
+						Text:     strings.TrimRight(s.source[start:s.current], " \t\r"),
+						Line:     s.line,
+						Column:   start - s.lineStart + 1,
+						Trailing: s.lastTokenLine == s.line,
+					})
+				}
 			} else {
 				return
 			}
@@ -138,22 +226,29 @@ func (s *Scanner) skipWhitespace() {
 }
 
 var keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":     AND,
+	"catch":   CATCH,
+	"class":   CLASS,
+	"else":    ELSE,
+	"export":  EXPORT,
+	"false":   FALSE,
+	"finally": FINALLY,
+	"for":     FOR,
+	"fun":     FUN,
+	"if":      IF,
+	"import":  IMPORT,
+	"in":      IN,
+	"nil":     NIL,
+	"or":      OR,
+	"print":   PRINT,
+	"return":  RETURN,
+	"super":   SUPER,
+	"this":    THIS,
+	"throw":   THROW,
+	"true":    TRUE,
+	"try":     TRY,
+	"var":     VAR,
+	"while":   WHILE,
 }
 
 func (s *Scanner) identifier() Token {
@@ -186,31 +281,125 @@ func (s *Scanner) number() (Token, error) {
 
 	value, err := strconv.ParseFloat(s.source[s.start:s.current], 64)
 	if err != nil {
-		return Token{}, fmt.Errorf("[line %d] Error: Failed to parse number: %s", s.line, s.source[s.start:s.current])
+		return Token{}, s.error("Failed to parse number: %s", s.source[s.start:s.current])
 	}
 
 	token := s.makeTokenLiteral(NUMBER, value)
 	return token, nil
 }
 
+// string scans a string literal, or the rest of one after an interpolated
+// expression. A segment that ends in "${" produces an INTERPOLATION token; the
+// parser then expects an expression followed by the remainder of the string.
 func (s *Scanner) string() (Token, error) {
+	var value strings.Builder
+	var escapeErr error
+
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
+		switch r := s.advance(); r {
+		case '\n':
 			s.line++
+			s.lineStart = s.current
+			value.WriteRune(r)
+		case '\\':
+			if err := s.escape(&value); err != nil && escapeErr == nil {
+				escapeErr = err
+			}
+		case '$':
+			if s.match('{') {
+				if err := s.emptyInterpolation(); err != nil {
+					if escapeErr == nil {
+						escapeErr = err
+					}
+					continue
+				}
+				s.interpolations = append(s.interpolations, 0)
+				return s.makeTokenLiteral(INTERPOLATION, value.String()), escapeErr
+			}
+			value.WriteRune(r)
+		default:
+			value.WriteRune(r)
 		}
-		s.advance()
 	}
 
 	if s.isAtEnd() {
-		return Token{}, fmt.Errorf("[line %d] Error: Unterminated string.", s.line)
+		s.unterminated = true
+		return Token{}, s.error("Unterminated string.")
 	}
 
 	// closing "
 	s.advance()
 
-	value := s.source[s.start+1 : s.current-1]
-	token := s.makeTokenLiteral(STRING, value)
-	return token, nil
+	token := s.makeTokenLiteral(STRING, value.String())
+	return token, escapeErr
+}
+
+// emptyInterpolation reports a "${" that was just consumed and is closed again
+// with nothing but whitespace in between, and skips past its "}" so scanning
+// can carry on with the rest of the string.
+func (s *Scanner) emptyInterpolation() error {
+	end := s.current
+	for end < len(s.source) && (s.source[end] == ' ' || s.source[end] == '\t') {
+		end++
+	}
+	if end == len(s.source) || s.source[end] != '}' {
+		return nil
+	}
+
+	column := s.current - 2 - s.lineStart + 1
+	s.current = end + 1
+	return NewScanError(s.line, column, "Empty interpolation.")
+}
+
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'"':  '"',
+	'\\': '\\',
+	'$':  '$',
+}
+
+// escape decodes the escape sequence following a backslash into value.
+func (s *Scanner) escape(value *strings.Builder) error {
+	if s.isAtEnd() {
+		s.unterminated = true
+		return s.error("Unterminated string.")
+	}
+
+	r := s.advance()
+	if decoded, ok := escapes[r]; ok {
+		value.WriteRune(decoded)
+		return nil
+	}
+
+	if r != 'u' {
+		return s.error("Invalid escape sequence: \\%c", r)
+	}
+
+	// \u{XXXX} with one to six hex digits
+	if !s.match('{') {
+		return s.error("Expect '{' after \\u.")
+	}
+
+	digits := s.current
+	for s.peek() != '}' && s.peek() != '"' && !s.isAtEnd() {
+		s.advance()
+	}
+	hex := s.source[digits:s.current]
+
+	if !s.match('}') {
+		return s.error("Unterminated unicode escape sequence.")
+	}
+
+	code, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) > 6 || !utf8.ValidRune(rune(code)) {
+		return s.error("Invalid unicode escape sequence: \\u{%s}", hex)
+	}
+
+	value.WriteRune(rune(code))
+	return nil
 }
 
 func (s *Scanner) peek() rune {
@@ -259,15 +448,27 @@ func (s *Scanner) advance() rune {
 	return r
 }
 
+// error returns a ScanError at the token being scanned, or at the current
+// character if the token has run onto another line.
+func (s *Scanner) error(format string, args ...any) error {
+	column := s.startColumn
+	if s.lineStart > s.start {
+		column = s.current - s.lineStart + 1
+	}
+	return NewScanError(s.line, column, fmt.Sprintf(format, args...))
+}
+
 func (s *Scanner) makeToken(tokenType TokenType) Token {
 	return s.makeTokenLiteral(tokenType, nil)
 }
 
 func (s *Scanner) makeTokenLiteral(tokenType TokenType, literal any) Token {
+	s.lastTokenLine = s.line
 	return Token{
 		Type:    tokenType,
 		Lexeme:  s.source[s.start:s.current],
 		Literal: NewLiteral(literal),
 		Line:    s.line,
+		Column:  s.startColumn,
 	}
 }
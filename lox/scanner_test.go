@@ -0,0 +1,98 @@
+package lox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanStringErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{`"bad \q escape";`, `[line 1] Error: Invalid escape sequence: \q`},
+		{`"\u{110000}";`, `[line 1] Error: Invalid unicode escape sequence: \u{110000}`},
+		{`"\u{zz}";`, `[line 1] Error: Invalid unicode escape sequence: \u{zz}`},
+		{`"\u41";`, `[line 1] Error: Expect '{' after \u.`},
+		{"\"open\n", `[line 2] Error: Unterminated string.`},
+	}
+
+	for _, test := range tests {
+		_, errs := NewScanner(test.source).ScanTokens()
+		if assert.Len(t, errs, 1, test.source) {
+			assert.EqualError(t, errs[0], test.err, test.source)
+		}
+	}
+}
+
+func TestScanEmptyInterpolation(t *testing.T) {
+	for _, source := range []string{`"ab${}cd";`, `"ab${ }cd";`} {
+		tokens, errs := NewScanner(source).ScanTokens()
+		if assert.Len(t, errs, 1, source) {
+			assert.EqualError(t, errs[0], "[line 1] Error: Empty interpolation.", source)
+			assert.Equal(t, 4, errs[0].(*ScanError).Column, source)
+		}
+		// The rest of the string is still scanned.
+		assert.Equal(t, STRING, tokens[0].Type, source)
+		assert.Equal(t, Literal{Value: "abcd"}, tokens[0].Literal, source)
+	}
+}
+
+func TestScanBrackets(t *testing.T) {
+	tokens, errs := NewScanner(`a[0] += 1;`).ScanTokens()
+	assert.Empty(t, errs)
+
+	var types []TokenType
+	for _, token := range tokens {
+		types = append(types, token.Type)
+	}
+	assert.Equal(t, []TokenType{IDENTIFIER, LEFT_BRACKET, NUMBER, RIGHT_BRACKET, PLUS_EQUAL, NUMBER, SEMICOLON, EOF}, types)
+}
+
+func TestScanInterpolation(t *testing.T) {
+	tokens, errs := NewScanner(`"a${b}c${ {} }"`).ScanTokens()
+	assert.Empty(t, errs)
+
+	var types []TokenType
+	for _, token := range tokens {
+		types = append(types, token.Type)
+	}
+	assert.Equal(t, []TokenType{INTERPOLATION, IDENTIFIER, INTERPOLATION, LEFT_BRACE, RIGHT_BRACE, STRING, EOF}, types)
+	assert.Equal(t, "a", tokens[0].Literal.Value)
+	assert.Equal(t, "c", tokens[2].Literal.Value)
+	assert.Equal(t, "", tokens[5].Literal.Value)
+}
+
+func TestScanColumns(t *testing.T) {
+	tokens, errs := NewScanner("var a = \"x\n y\";\n  print a; @").ScanTokens()
+	if assert.Len(t, errs, 1) {
+		var scanErr *ScanError
+		assert.ErrorAs(t, errs[0], &scanErr)
+		assert.Equal(t, 3, scanErr.Line)
+		assert.Equal(t, 12, scanErr.Column)
+	}
+
+	var columns [][2]int
+	for _, token := range tokens {
+		if token.Lexeme != "" {
+			columns = append(columns, [2]int{token.Line, token.Column})
+		}
+	}
+	assert.Equal(t, [][2]int{{1, 1}, {1, 5}, {1, 7}, {2, 9}, {2, 4}, {3, 3}, {3, 9}, {3, 10}}, columns)
+}
+
+func TestScanComments(t *testing.T) {
+	scanner := NewScanner("// first\nvar a = 1; // second  \n  // third")
+	scanner.RecordComments()
+	_, errs := scanner.ScanTokens()
+	assert.Empty(t, errs)
+
+	assert.Equal(t, []Comment{
+		{Text: "// first", Line: 1, Column: 1},
+		{Text: "// second", Line: 2, Column: 12, Trailing: true},
+		{Text: "// third", Line: 3, Column: 3},
+	}, scanner.Comments())
+
+	assert.Empty(t, NewScanner("// dropped").Comments())
+}
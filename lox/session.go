@@ -0,0 +1,215 @@
+package lox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Session runs code typed at a REPL. Unlike a fresh Interpret for each line,
+// it keeps one interpreter so globals persist between calls to Eval, and it
+// prints the value of every top-level expression statement.
+type Session struct {
+	opts        []Option
+	interpreter *Interpreter
+
+	// builtins holds the globals that existed before the first Eval: the
+	// standard library and anything the host defined. Reset restores them
+	// and Globals leaves them out.
+	builtins map[string]any
+}
+
+func NewSession(opts ...Option) *Session {
+	return &Session{opts: opts, interpreter: NewInterpreter(opts...)}
+}
+
+// Interpreter returns the interpreter the session evaluates code in, so the
+// host can configure it or define natives before the first Eval.
+func (s *Session) Interpreter() *Interpreter {
+	return s.interpreter
+}
+
+// Eval scans, parses, resolves and runs source. A single expression may leave
+// off its trailing semicolon.
+func (s *Session) Eval(source string) error {
+	s.snapshotBuiltins()
+
+	tokens, errs := NewScanner(source).ScanTokens()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	statements, err := NewParser(tokens).Parse()
+	if err != nil {
+		parser := NewParser(tokens)
+		expr, exprErr := parser.ParseExpr()
+		if exprErr != nil || !parser.isAtEnd() {
+			return err
+		}
+		statements = []Stmt{NewExprStmt(expr)}
+	}
+
+	if err := NewResolver(s.interpreter).Resolve(statements); err != nil {
+		return err
+	}
+
+	return s.run(statements)
+}
+
+func (s *Session) run(statements []Stmt) error {
+	i := s.interpreter
+	defer i.limits.start(context.Background())()
+
+	for _, stmt := range statements {
+		exprStmt, ok := stmt.(*ExprStmt)
+		if !ok {
+			if err := i.execute(stmt); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := i.limits.step(); err != nil {
+			return err
+		}
+
+		value, err := i.Evaluate(exprStmt.Expression)
+		if err != nil {
+			return err
+		}
+
+		// Like most REPLs, stay quiet for calls that return nothing.
+		if value != nil {
+			fmt.Fprintln(i.stdout, stringify(value))
+		}
+	}
+
+	return nil
+}
+
+// Reset discards everything defined by Eval. The builtins, capabilities,
+// arguments and script path of the interpreter are kept.
+func (s *Session) Reset() {
+	s.snapshotBuiltins()
+
+	old := s.interpreter
+	s.interpreter = NewInterpreter(s.opts...)
+	s.interpreter.capabilities = old.capabilities
+	s.interpreter.args = old.args
+	if old.path != "" {
+		s.interpreter.SetScriptPath(old.path)
+	}
+
+	for name, value := range s.builtins {
+		s.interpreter.globals.Define(name, value)
+	}
+}
+
+// Globals describes the globals defined by Eval, one "name = value" line per
+// global in sorted order.
+func (s *Session) Globals() []string {
+	s.snapshotBuiltins()
+
+	var globals []string
+	for name, value := range s.interpreter.globals.values {
+		if _, ok := s.builtins[name]; ok {
+			continue
+		}
+		globals = append(globals, name+" = "+stringify(value))
+	}
+	sort.Strings(globals)
+	return globals
+}
+
+// Complete returns the completions of word, the text before the cursor back to
+// the start of the identifier. A plain word completes to keywords and
+// globals. A word such as "db.Na" completes to the properties of the object
+// the part before the last dot refers to.
+func (s *Session) Complete(word string) []string {
+	var candidates []string
+	prefix := word
+
+	if path, last, ok := strings.Cut(word, "."); ok {
+		object, ok := s.lookUpPath(path, last)
+		if !ok {
+			return nil
+		}
+		// Only the segment after the last dot is being completed.
+		idx := strings.LastIndex(word, ".")
+		for _, name := range object.properties() {
+			candidates = append(candidates, word[:idx+1]+name)
+		}
+	} else {
+		for keyword := range keywords {
+			candidates = append(candidates, keyword)
+		}
+		for name := range s.interpreter.globals.values {
+			candidates = append(candidates, name)
+		}
+	}
+
+	var completions []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			completions = append(completions, candidate)
+		}
+	}
+	sort.Strings(completions)
+	return completions
+}
+
+// lookUpPath follows a dotted path such as "a.b" from a global, given the
+// first name and the rest of the word after it. The segment after the last
+// dot is the one being completed, so it is not followed.
+func (s *Session) lookUpPath(first, rest string) (propertyLister, bool) {
+	value, ok := s.interpreter.globals.values[first]
+	if !ok {
+		return nil, false
+	}
+
+	segments := strings.Split(rest, ".")
+	for _, segment := range segments[:len(segments)-1] {
+		object, ok := value.(Object)
+		if !ok {
+			return nil, false
+		}
+		value, _ = object.Get(Token{Type: IDENTIFIER, Lexeme: segment})
+	}
+
+	object, ok := value.(propertyLister)
+	return object, ok
+}
+
+func (s *Session) snapshotBuiltins() {
+	if s.builtins != nil {
+		return
+	}
+
+	s.builtins = make(map[string]any)
+	for name, value := range s.interpreter.globals.values {
+		s.builtins[name] = value
+	}
+}
+
+// IsComplete reports whether source is a finished piece of input: every
+// string is closed and no parenthesis or brace is left open. A REPL uses it to
+// decide whether to keep reading lines.
+func IsComplete(source string) bool {
+	scanner := NewScanner(source)
+	tokens, _ := scanner.ScanTokens()
+	if scanner.unterminated || len(scanner.interpolations) > 0 {
+		return false
+	}
+
+	depth := 0
+	for _, token := range tokens {
+		switch token.Type {
+		case LEFT_PAREN, LEFT_BRACE:
+			depth++
+		case RIGHT_PAREN, RIGHT_BRACE:
+			depth--
+		}
+	}
+	return depth <= 0
+}
@@ -0,0 +1,68 @@
+package lox
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	var output bytes.Buffer
+	session := NewSession(WithStdout(&output))
+	require.NoError(t, session.Interpreter().SetGlobal("host", "value"))
+
+	require.NoError(t, session.Eval(`var a = 1;`))
+	require.NoError(t, session.Eval(`fun add(x) { return x + a; }`))
+	require.NoError(t, session.Eval(`add(2);`))
+	require.NoError(t, session.Eval(`a * 10`))
+	require.NoError(t, session.Eval(`sleep(0);`))
+	assert.Equal(t, "3\n10\n", output.String())
+
+	assert.Equal(t, []string{"a = 1", "add = <fn add>"}, session.Globals())
+
+	err := session.Eval(`a +`)
+	assert.EqualError(t, err, "[line 1] Error at end: Expect expression.")
+
+	session.Reset()
+	assert.Empty(t, session.Globals())
+	assert.EqualError(t, session.Eval(`a;`), "[line 1] Undefined variable 'a'.")
+
+	output.Reset()
+	require.NoError(t, session.Eval(`host + " " + str(len("abc"))`))
+	assert.Equal(t, "value 3\n", output.String())
+}
+
+func TestSessionComplete(t *testing.T) {
+	session := NewSession()
+	require.NoError(t, session.Interpreter().Bind("db", &testDB{Name: "main", Limits: map[string]int{"rows": 1}}))
+	require.NoError(t, session.Eval(`var printed = 1; var err; try { nil(); } catch (e) { err = e; }`))
+
+	assert.Equal(t, []string{"print", "printed"}, session.Complete("pri"))
+	assert.Equal(t, []string{"while"}, session.Complete("whi"))
+	assert.Equal(t, []string{"db.Each", "db.Enabled"}, session.Complete("db.E"))
+	assert.Equal(t, []string{"db.Address.City"}, session.Complete("db.Address.C"))
+	assert.Equal(t, []string{"db.Limits.rows"}, session.Complete("db.Limits."))
+	assert.Equal(t, []string{"err.message"}, session.Complete("err.m"))
+	assert.Empty(t, session.Complete("printed.x"))
+	assert.Empty(t, session.Complete("missing.x"))
+}
+
+func TestIsComplete(t *testing.T) {
+	for source, complete := range map[string]bool{
+		`print 1;`:                true,
+		`fun f() {`:               false,
+		"fun f() {\n}":            true,
+		`print (1 +`:              false,
+		`print "abc`:              false,
+		`print "a ${ (1 + 2`:      false,
+		`print "a ${1 + 2} b";`:   true,
+		`}`:                       true,
+		`print "{"; // {`:         true,
+		"var s = \"multi\nline":   false,
+		"var s = \"multi\nline\"": true,
+	} {
+		assert.Equal(t, complete, IsComplete(source), source)
+	}
+}
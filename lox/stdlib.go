@@ -0,0 +1,338 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// NativeFunction is a function implemented in Go and exposed to scripts as a
+// global.
+type NativeFunction struct {
+	name  string
+	arity int
+	fn    func(interpreter *Interpreter, arguments []any) (any, error)
+
+	// charged is set for natives that count their result against the
+	// interpreter's limits before building it, because it can be much larger
+	// than their arguments. The interpreter counts other natives' results.
+	charged bool
+}
+
+var _ Callable = (*NativeFunction)(nil)
+
+func (n *NativeFunction) Arity() int {
+	return n.arity
+}
+
+func (n *NativeFunction) Call(interpreter *Interpreter, arguments []any) (any, error) {
+	return n.fn(interpreter, arguments)
+}
+
+func (n *NativeFunction) String() string {
+	return "<native fn>"
+}
+
+// nativeError is returned by native functions for bad arguments and similar
+// failures. The interpreter reports it as a runtime error at the call site.
+type nativeError struct {
+	message string
+}
+
+func nativeErrorf(format string, args ...any) *nativeError {
+	return &nativeError{message: fmt.Sprintf(format, args...)}
+}
+
+func (e *nativeError) Error() string {
+	return e.message
+}
+
+var stdlib = []*NativeFunction{
+	// math
+	{name: "sqrt", arity: 1, fn: mathFunc("sqrt", math.Sqrt)},
+	{name: "floor", arity: 1, fn: mathFunc("floor", math.Floor)},
+	{name: "ceil", arity: 1, fn: mathFunc("ceil", math.Ceil)},
+	{name: "abs", arity: 1, fn: mathFunc("abs", math.Abs)},
+	{name: "min", arity: 2, fn: mathFunc2("min", math.Min)},
+	{name: "max", arity: 2, fn: mathFunc2("max", math.Max)},
+	{name: "random", arity: 0, fn: random},
+	{name: "seed", arity: 1, fn: seed},
+
+	// conversions
+	{name: "str", arity: 1, fn: str, charged: true},
+	{name: "num", arity: 1, fn: num},
+
+	// strings and lists
+	{name: "len", arity: 1, fn: length},
+	{name: "substr", arity: 3, fn: substr},
+	{name: "indexOf", arity: 2, fn: indexOf},
+	{name: "split", arity: 2, fn: split, charged: true},
+	{name: "join", arity: 2, fn: join, charged: true},
+	{name: "upper", arity: 1, fn: stringFunc("upper", strings.ToUpper)},
+	{name: "lower", arity: 1, fn: stringFunc("lower", strings.ToLower)},
+	{name: "trim", arity: 1, fn: stringFunc("trim", strings.TrimSpace)},
+
+	// time
+	{name: "now", arity: 0, fn: now},
+	{name: "sleep", arity: 1, fn: sleep},
+}
+
+func defineStdlib(globals *Environment) {
+	globals.Define("clock", Clock{})
+	for _, native := range stdlib {
+		globals.Define(native.name, native)
+	}
+	for _, native := range iolib {
+		globals.Define(native.name, native)
+	}
+}
+
+func mathFunc(name string, f func(float64) float64) func(*Interpreter, []any) (any, error) {
+	return func(_ *Interpreter, arguments []any) (any, error) {
+		x, err := numberArgument(name, arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		return f(x), nil
+	}
+}
+
+func mathFunc2(name string, f func(float64, float64) float64) func(*Interpreter, []any) (any, error) {
+	return func(_ *Interpreter, arguments []any) (any, error) {
+		x, err := numberArgument(name, arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		y, err := numberArgument(name, arguments, 1)
+		if err != nil {
+			return nil, err
+		}
+		return f(x, y), nil
+	}
+}
+
+func stringFunc(name string, f func(string) string) func(*Interpreter, []any) (any, error) {
+	return func(_ *Interpreter, arguments []any) (any, error) {
+		s, err := stringArgument(name, arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		return f(s), nil
+	}
+}
+
+func random(interpreter *Interpreter, _ []any) (any, error) {
+	return interpreter.random.Float64(), nil
+}
+
+func seed(interpreter *Interpreter, arguments []any) (any, error) {
+	n, err := numberArgument("seed", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	interpreter.random.Seed(int64(n))
+	return nil, nil
+}
+
+// str converts its argument to a string. A list holding the same list many
+// times makes a string far larger than itself, so str charges for the string
+// before building it.
+func str(interpreter *Interpreter, arguments []any) (any, error) {
+	size := stringLength(arguments[0], interpreter.limits.available())
+	if err := interpreter.limits.allocateBytes(stringSize + size); err != nil {
+		return nil, err
+	}
+	return stringify(arguments[0]), nil
+}
+
+// num parses a string as a number. It returns nil if the string is not a
+// valid number.
+func num(_ *Interpreter, arguments []any) (any, error) {
+	switch value := arguments[0].(type) {
+	case float64:
+		return value, nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, nil
+		}
+		return n, nil
+	default:
+		return nil, nativeErrorf("Argument 1 to 'num' must be a string or number.")
+	}
+}
+
+func length(_ *Interpreter, arguments []any) (any, error) {
+	switch value := arguments[0].(type) {
+	case string:
+		return float64(utf8.RuneCountInString(value)), nil
+	case *List:
+		return float64(len(value.Elements)), nil
+	case *Map:
+		return float64(len(value.Entries)), nil
+	default:
+		return nil, nativeErrorf("Argument 1 to 'len' must be a string, list or map.")
+	}
+}
+
+// substr returns the characters of s from start up to but not including end.
+func substr(_ *Interpreter, arguments []any) (any, error) {
+	s, err := stringArgument("substr", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+	start, err := indexArgument("substr", arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+	end, err := indexArgument("substr", arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	runes := []rune(s)
+	if start > end || end > len(runes) {
+		return nil, nativeErrorf("Range [%d, %d) is out of bounds for 'substr' on a string of length %d.", start, end, len(runes))
+	}
+	return string(runes[start:end]), nil
+}
+
+func indexOf(_ *Interpreter, arguments []any) (any, error) {
+	s, err := stringArgument("indexOf", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+	substring, err := stringArgument("indexOf", arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	i := strings.Index(s, substring)
+	if i < 0 {
+		return float64(-1), nil
+	}
+	return float64(utf8.RuneCountInString(s[:i])), nil
+}
+
+// split charges for the list and its strings before making them: each part
+// costs a header, which is many times the byte it may hold.
+func split(interpreter *Interpreter, arguments []any) (any, error) {
+	s, err := stringArgument("split", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+	separator, err := stringArgument("split", arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	n := strings.Count(s, separator) + 1
+	if separator == "" {
+		n = utf8.RuneCountInString(s)
+	}
+	size := listSize + (interfaceSize+stringSize)*n + len(s) - len(separator)*(n-1)
+	if err := interpreter.limits.allocateBytes(size); err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(s, separator)
+	elements := make([]any, len(parts))
+	for i, part := range parts {
+		elements[i] = part
+	}
+	return NewList(elements), nil
+}
+
+// join charges for the string before building it, since the separator is
+// repeated between every pair of elements.
+func join(interpreter *Interpreter, arguments []any) (any, error) {
+	list, ok := arguments[0].(*List)
+	if !ok {
+		return nil, nativeErrorf("Argument 1 to 'join' must be a list.")
+	}
+	separator, err := stringArgument("join", arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	available := interpreter.limits.available()
+	size := 0
+	for i, element := range list.Elements {
+		if size > available {
+			break
+		}
+		if i > 0 {
+			size += len(separator)
+		}
+		size += stringLength(element, available-size)
+	}
+	if err := interpreter.limits.allocateBytes(stringSize + size); err != nil {
+		return nil, err
+	}
+
+	parts := make([]string, len(list.Elements))
+	for i, element := range list.Elements {
+		parts[i] = stringify(element)
+	}
+	return strings.Join(parts, separator), nil
+}
+
+// now returns the current time in milliseconds since the Unix epoch.
+func now(_ *Interpreter, _ []any) (any, error) {
+	return float64(time.Now().UnixNano()) / float64(time.Millisecond), nil
+}
+
+// sleep pauses for the given number of milliseconds, waking early if the
+// script is interrupted.
+func sleep(interpreter *Interpreter, arguments []any) (any, error) {
+	ms, err := numberArgument("sleep", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+	if ms < 0 {
+		return nil, nativeErrorf("Argument 1 to 'sleep' must not be negative.")
+	}
+
+	timer := time.NewTimer(time.Duration(ms * float64(time.Millisecond)))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil, nil
+	case <-interpreter.limits.ctx.Done():
+		return nil, NewInterruptedError(interpreter.limits.ctx.Err())
+	}
+}
+
+func numberArgument(name string, arguments []any, index int) (float64, error) {
+	if n, ok := arguments[index].(float64); ok {
+		return n, nil
+	}
+	return 0, nativeErrorf("Argument %d to '%s' must be a number.", index+1, name)
+}
+
+func indexArgument(name string, arguments []any, index int) (int, error) {
+	n, err := numberArgument(name, arguments, index)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n != math.Trunc(n) {
+		return 0, nativeErrorf("Argument %d to '%s' must be a non-negative integer.", index+1, name)
+	}
+	// Larger values would wrap around when converted to an int.
+	if n > math.MaxInt32 {
+		return 0, nativeErrorf("Argument %d to '%s' is too large.", index+1, name)
+	}
+	return int(n), nil
+}
+
+func stringArgument(name string, arguments []any, index int) (string, error) {
+	if s, ok := arguments[index].(string); ok {
+		return s, nil
+	}
+	return "", nativeErrorf("Argument %d to '%s' must be a string.", index+1, name)
+}
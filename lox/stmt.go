@@ -1,44 +1,52 @@
 package lox
 
+// Stmt is a statement node. Accept calls the visitor's method for the
+// node's type and returns its result.
 type Stmt interface {
-	accept(visitor stmtVisitor) (any, error)
+	Accept(visitor StmtVisitor) (any, error)
 }
 
 type ExprStmt struct {
 	Expression Expr
+	Semicolon  Token // zero for a statement the parser made up
 }
 
 func NewExprStmt(expression Expr) *ExprStmt {
 	return &ExprStmt{Expression: expression}
 }
 
-func (s *ExprStmt) accept(visitor stmtVisitor) (any, error) {
-	return visitor.visitExprStmt(s)
+func (s *ExprStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitExprStmt(s)
 }
 
 type PrintStmt struct {
+	Keyword    Token
 	Expression Expr
+	Semicolon  Token
 }
 
-func NewPrintStmt(expression Expr) *PrintStmt {
-	return &PrintStmt{Expression: expression}
+func NewPrintStmt(keyword Token, expression Expr) *PrintStmt {
+	return &PrintStmt{Keyword: keyword, Expression: expression}
 }
 
-func (s *PrintStmt) accept(visitor stmtVisitor) (any, error) {
-	return visitor.visitPrintStmt(s)
+func (s *PrintStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitPrintStmt(s)
 }
 
 type VarDeclStmt struct {
+	Keyword     Token
 	Name        Token
+	Type        *TypeAnnotation // nil when the type is left out
 	Initializer Expr
+	Semicolon   Token
 }
 
-func NewVarDeclStmt(name Token, initializer Expr) *VarDeclStmt {
-	return &VarDeclStmt{Name: name, Initializer: initializer}
+func NewVarDeclStmt(keyword Token, name Token, initializer Expr) *VarDeclStmt {
+	return &VarDeclStmt{Keyword: keyword, Name: name, Initializer: initializer}
 }
 
-func (s *VarDeclStmt) accept(visitor stmtVisitor) (any, error) {
-	return visitor.visitVarDeclStmt(s)
+func (s *VarDeclStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitVarDeclStmt(s)
 }
 
 type BlockStmt struct {
@@ -49,40 +57,56 @@ func NewBlockStmt(statements []Stmt) *BlockStmt {
 	return &BlockStmt{Statements: statements}
 }
 
-func (s *BlockStmt) accept(visitor stmtVisitor) (any, error) {
-	return visitor.visitBlockStmt(s)
+func (s *BlockStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitBlockStmt(s)
 }
 
 type IfStmt struct {
+	Keyword    Token
 	Condition  Expr
 	ThenBranch Stmt
 	ElseBranch Stmt
 }
 
-func NewIfStmt(condition Expr, thenBranch Stmt, elseBranch Stmt) *IfStmt {
-	return &IfStmt{Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}
+func NewIfStmt(keyword Token, condition Expr, thenBranch Stmt, elseBranch Stmt) *IfStmt {
+	return &IfStmt{Keyword: keyword, Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}
 }
 
-func (s *IfStmt) accept(visitor stmtVisitor) (any, error) {
-	return visitor.visitIfStmt(s)
+func (s *IfStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitIfStmt(s)
 }
 
 type WhileStmt struct {
+	Keyword   Token // "while", or "for" for a desugared for loop
 	Condition Expr
 	Body      Stmt
+
+	// For holds the clauses of the for loop this was desugared from, nil for
+	// a while loop, so tools can print the loop as it was written.
+	For *ForClauses
+}
+
+// ForClauses are the clauses of a for loop as written, any of which may be
+// nil. The parser wraps the loop in a block with Initializer when there is
+// one, and appends Increment to the body.
+type ForClauses struct {
+	Initializer Stmt
+	Condition   Expr
+	Increment   Expr
 }
 
-func NewWhileStmt(condition Expr, body Stmt) *WhileStmt {
-	return &WhileStmt{Condition: condition, Body: body}
+func NewWhileStmt(keyword Token, condition Expr, body Stmt) *WhileStmt {
+	return &WhileStmt{Keyword: keyword, Condition: condition, Body: body}
 }
 
-func (s *WhileStmt) accept(visitor stmtVisitor) (any, error) {
-	return visitor.visitWhileStmt(s)
+func (s *WhileStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitWhileStmt(s)
 }
 
 type FunctionDeclStmt struct {
 	Name       Token
 	Parameters []Token
+	Types      *FunctionTypes // nil when the function has no annotations
 	Body       []Stmt
 }
 
@@ -90,30 +114,125 @@ func NewFunctionDeclStmt(name Token, parameters []Token, body []Stmt) *FunctionD
 	return &FunctionDeclStmt{Name: name, Parameters: parameters, Body: body}
 }
 
-func (stmt *FunctionDeclStmt) accept(visitor stmtVisitor) (any, error) {
-	return visitor.visitFunctionDeclStmt(stmt)
+func (stmt *FunctionDeclStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitFunctionDeclStmt(stmt)
+}
+
+// TypeAnnotation is a type written in the source, as in var x: number. The
+// interpreter ignores it; the TypeChecker reads it.
+type TypeAnnotation struct {
+	Name Token
+}
+
+// FunctionTypes are the annotations of a function's parameters, in order,
+// and of its return value. Any of them may be nil.
+type FunctionTypes struct {
+	Parameters []*TypeAnnotation
+	Return     *TypeAnnotation
 }
 
 type ReturnStmt struct {
-	Keyword Token
-	Value   Expr
+	Keyword   Token // "=>" for the body of an arrow function
+	Value     Expr
+	Semicolon Token // zero for the body of an arrow function
 }
 
 func NewReturnStmt(keyword Token, value Expr) *ReturnStmt {
 	return &ReturnStmt{Keyword: keyword, Value: value}
 }
 
-func (s *ReturnStmt) accept(visitor stmtVisitor) (any, error) {
-	return visitor.visitReturnStmt(s)
+func (s *ReturnStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitReturnStmt(s)
+}
+
+type ForInStmt struct {
+	Name     Token
+	Keyword  Token
+	Iterable Expr
+	Body     Stmt
+}
+
+func NewForInStmt(name Token, keyword Token, iterable Expr, body Stmt) *ForInStmt {
+	return &ForInStmt{Name: name, Keyword: keyword, Iterable: iterable, Body: body}
+}
+
+func (s *ForInStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitForInStmt(s)
+}
+
+type ThrowStmt struct {
+	Keyword   Token
+	Value     Expr
+	Semicolon Token
+}
+
+func NewThrowStmt(keyword Token, value Expr) *ThrowStmt {
+	return &ThrowStmt{Keyword: keyword, Value: value}
+}
+
+func (s *ThrowStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitThrowStmt(s)
+}
+
+type TryStmt struct {
+	Keyword     Token
+	Body        []Stmt
+	CatchName   *Token // nil when there is no catch clause
+	CatchBody   []Stmt
+	FinallyBody []Stmt
+}
+
+func NewTryStmt(keyword Token, body []Stmt, catchName *Token, catchBody []Stmt, finallyBody []Stmt) *TryStmt {
+	return &TryStmt{Keyword: keyword, Body: body, CatchName: catchName, CatchBody: catchBody, FinallyBody: finallyBody}
+}
+
+func (s *TryStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitTryStmt(s)
+}
+
+type ImportStmt struct {
+	Keyword   Token
+	Path      Token
+	Alias     *Token  // set for: import "path" as alias;
+	Names     []Token // set for: import { a, b } from "path";
+	Semicolon Token
+}
+
+func NewImportStmt(keyword Token, path Token, alias *Token, names []Token) *ImportStmt {
+	return &ImportStmt{Keyword: keyword, Path: path, Alias: alias, Names: names}
+}
+
+func (s *ImportStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitImportStmt(s)
+}
+
+type ExportStmt struct {
+	Keyword     Token
+	Declaration Stmt
+}
+
+func NewExportStmt(keyword Token, declaration Stmt) *ExportStmt {
+	return &ExportStmt{Keyword: keyword, Declaration: declaration}
+}
+
+func (s *ExportStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitExportStmt(s)
 }
 
-type stmtVisitor interface {
-	visitExprStmt(stmt *ExprStmt) (any, error)
-	visitPrintStmt(stmt *PrintStmt) (any, error)
-	visitVarDeclStmt(stmt *VarDeclStmt) (any, error)
-	visitBlockStmt(stmt *BlockStmt) (any, error)
-	visitIfStmt(stmt *IfStmt) (any, error)
-	visitWhileStmt(stmt *WhileStmt) (any, error)
-	visitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error)
-	visitReturnStmt(stmt *ReturnStmt) (any, error)
+// StmtVisitor is implemented by passes over statements, which must handle
+// every node type.
+type StmtVisitor interface {
+	VisitExprStmt(stmt *ExprStmt) (any, error)
+	VisitPrintStmt(stmt *PrintStmt) (any, error)
+	VisitVarDeclStmt(stmt *VarDeclStmt) (any, error)
+	VisitBlockStmt(stmt *BlockStmt) (any, error)
+	VisitIfStmt(stmt *IfStmt) (any, error)
+	VisitWhileStmt(stmt *WhileStmt) (any, error)
+	VisitForInStmt(stmt *ForInStmt) (any, error)
+	VisitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error)
+	VisitReturnStmt(stmt *ReturnStmt) (any, error)
+	VisitThrowStmt(stmt *ThrowStmt) (any, error)
+	VisitTryStmt(stmt *TryStmt) (any, error)
+	VisitImportStmt(stmt *ImportStmt) (any, error)
+	VisitExportStmt(stmt *ExportStmt) (any, error)
 }
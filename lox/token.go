@@ -11,6 +11,8 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 
 	COMMA
 	DOT
@@ -19,30 +21,46 @@ const (
 	SEMICOLON
 	SLASH
 	STAR
+	PERCENT
+	QUESTION
+	COLON
 
 	// One or two character tokens.
 	BANG
 	BANG_EQUAL
 	EQUAL
 	EQUAL_EQUAL
+	ARROW
 	GREATER
 	GREATER_EQUAL
 	LESS
 	LESS_EQUAL
+	PLUS_EQUAL
+	MINUS_EQUAL
+	STAR_EQUAL
+	SLASH_EQUAL
+	PERCENT_EQUAL
+	STAR_STAR
 
 	// Literals.
 	IDENTIFIER
 	STRING
+	INTERPOLATION // a string segment followed by "${"
 	NUMBER
 
 	// Keywords.
 	AND
+	CATCH
 	CLASS
 	ELSE
+	EXPORT
 	FALSE
+	FINALLY
 	FUN
 	FOR
 	IF
+	IMPORT
+	IN
 	NIL
 	OR
 
@@ -50,7 +68,9 @@ const (
 	RETURN
 	SUPER
 	THIS
+	THROW
 	TRUE
+	TRY
 	VAR
 	WHILE
 
@@ -61,13 +81,24 @@ type Token struct {
 	Type    TokenType
 	Lexeme  string
 	Literal Literal
-	Line    int
+	Line    int // the line the token ends on
+	Column  int // the byte the token starts at within its first line, from 1
 }
 
 func (t Token) String() string {
 	return fmt.Sprintf("%s %s %s", t.Type, t.Lexeme, t.Literal)
 }
 
+// Comment is a line comment the scanner skipped over.
+type Comment struct {
+	Text   string // from "//" up to the end of the line
+	Line   int
+	Column int
+
+	// Trailing is set when the comment follows a token on the same line.
+	Trailing bool
+}
+
 type Literal struct {
 	Value any
 }
@@ -12,46 +12,66 @@ func _() {
 	_ = x[RIGHT_PAREN-1]
 	_ = x[LEFT_BRACE-2]
 	_ = x[RIGHT_BRACE-3]
-	_ = x[COMMA-4]
-	_ = x[DOT-5]
-	_ = x[MINUS-6]
-	_ = x[PLUS-7]
-	_ = x[SEMICOLON-8]
-	_ = x[SLASH-9]
-	_ = x[STAR-10]
-	_ = x[BANG-11]
-	_ = x[BANG_EQUAL-12]
-	_ = x[EQUAL-13]
-	_ = x[EQUAL_EQUAL-14]
-	_ = x[GREATER-15]
-	_ = x[GREATER_EQUAL-16]
-	_ = x[LESS-17]
-	_ = x[LESS_EQUAL-18]
-	_ = x[IDENTIFIER-19]
-	_ = x[STRING-20]
-	_ = x[NUMBER-21]
-	_ = x[AND-22]
-	_ = x[CLASS-23]
-	_ = x[ELSE-24]
-	_ = x[FALSE-25]
-	_ = x[FUN-26]
-	_ = x[FOR-27]
-	_ = x[IF-28]
-	_ = x[NIL-29]
-	_ = x[OR-30]
-	_ = x[PRINT-31]
-	_ = x[RETURN-32]
-	_ = x[SUPER-33]
-	_ = x[THIS-34]
-	_ = x[TRUE-35]
-	_ = x[VAR-36]
-	_ = x[WHILE-37]
-	_ = x[EOF-38]
+	_ = x[LEFT_BRACKET-4]
+	_ = x[RIGHT_BRACKET-5]
+	_ = x[COMMA-6]
+	_ = x[DOT-7]
+	_ = x[MINUS-8]
+	_ = x[PLUS-9]
+	_ = x[SEMICOLON-10]
+	_ = x[SLASH-11]
+	_ = x[STAR-12]
+	_ = x[PERCENT-13]
+	_ = x[QUESTION-14]
+	_ = x[COLON-15]
+	_ = x[BANG-16]
+	_ = x[BANG_EQUAL-17]
+	_ = x[EQUAL-18]
+	_ = x[EQUAL_EQUAL-19]
+	_ = x[ARROW-20]
+	_ = x[GREATER-21]
+	_ = x[GREATER_EQUAL-22]
+	_ = x[LESS-23]
+	_ = x[LESS_EQUAL-24]
+	_ = x[PLUS_EQUAL-25]
+	_ = x[MINUS_EQUAL-26]
+	_ = x[STAR_EQUAL-27]
+	_ = x[SLASH_EQUAL-28]
+	_ = x[PERCENT_EQUAL-29]
+	_ = x[STAR_STAR-30]
+	_ = x[IDENTIFIER-31]
+	_ = x[STRING-32]
+	_ = x[INTERPOLATION-33]
+	_ = x[NUMBER-34]
+	_ = x[AND-35]
+	_ = x[CATCH-36]
+	_ = x[CLASS-37]
+	_ = x[ELSE-38]
+	_ = x[EXPORT-39]
+	_ = x[FALSE-40]
+	_ = x[FINALLY-41]
+	_ = x[FUN-42]
+	_ = x[FOR-43]
+	_ = x[IF-44]
+	_ = x[IMPORT-45]
+	_ = x[IN-46]
+	_ = x[NIL-47]
+	_ = x[OR-48]
+	_ = x[PRINT-49]
+	_ = x[RETURN-50]
+	_ = x[SUPER-51]
+	_ = x[THIS-52]
+	_ = x[THROW-53]
+	_ = x[TRUE-54]
+	_ = x[TRY-55]
+	_ = x[VAR-56]
+	_ = x[WHILE-57]
+	_ = x[EOF-58]
 }
 
-const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALIDENTIFIERSTRINGNUMBERANDCLASSELSEFALSEFUNFORIFNILORPRINTRETURNSUPERTHISTRUEVARWHILEEOF"
+const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACELEFT_BRACKETRIGHT_BRACKETCOMMADOTMINUSPLUSSEMICOLONSLASHSTARPERCENTQUESTIONCOLONBANGBANG_EQUALEQUALEQUAL_EQUALARROWGREATERGREATER_EQUALLESSLESS_EQUALPLUS_EQUALMINUS_EQUALSTAR_EQUALSLASH_EQUALPERCENT_EQUALSTAR_STARIDENTIFIERSTRINGINTERPOLATIONNUMBERANDCATCHCLASSELSEEXPORTFALSEFINALLYFUNFORIFIMPORTINNILORPRINTRETURNSUPERTHISTHROWTRUETRYVARWHILEEOF"
 
-var _TokenType_index = [...]uint8{0, 10, 21, 31, 42, 47, 50, 55, 59, 68, 73, 77, 81, 91, 96, 107, 114, 127, 131, 141, 151, 157, 163, 166, 171, 175, 180, 183, 186, 188, 191, 193, 198, 204, 209, 213, 217, 220, 225, 228}
+var _TokenType_index = [...]uint16{0, 10, 21, 31, 42, 54, 67, 72, 75, 80, 84, 93, 98, 102, 109, 117, 122, 126, 136, 141, 152, 157, 164, 177, 181, 191, 201, 212, 222, 233, 246, 255, 265, 271, 284, 290, 293, 298, 303, 307, 313, 318, 325, 328, 331, 333, 339, 341, 344, 346, 351, 357, 362, 366, 371, 375, 378, 381, 386, 389}
 
 func (i TokenType) String() string {
 	if i < 0 || i >= TokenType(len(_TokenType_index)-1) {
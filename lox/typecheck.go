@@ -0,0 +1,514 @@
+package lox
+
+import (
+	"fmt"
+)
+
+// staticType is what the TypeChecker knows about a value. A function type
+// may also know its signature.
+type staticType struct {
+	name string // one of the names in namedTypes
+
+	signature  bool // whether parameters and result are known
+	parameters []*staticType
+	result     *staticType
+}
+
+var (
+	anyType    = &staticType{name: "any"}
+	numberType = &staticType{name: "number"}
+	stringType = &staticType{name: "string"}
+	boolType   = &staticType{name: "bool"}
+	nilType    = &staticType{name: "nil"}
+	funType    = &staticType{name: "fun"}
+	listType   = &staticType{name: "list"}
+	mapType    = &staticType{name: "map"}
+)
+
+// namedTypes are the types an annotation can name.
+var namedTypes = map[string]*staticType{
+	"any":    anyType,
+	"number": numberType,
+	"string": stringType,
+	"bool":   boolType,
+	"nil":    nilType,
+	"fun":    funType,
+	"list":   listType,
+	"map":    mapType,
+}
+
+func (t *staticType) String() string {
+	return t.name
+}
+
+// assignable reports whether a value of type from can be stored where type
+// to is expected. Either being any makes it so: that is what lets annotated
+// and unannotated code mix.
+func assignable(to, from *staticType) bool {
+	return to == anyType || from == anyType || to.name == from.name
+}
+
+// checkedVar is a variable as the TypeChecker sees it.
+type checkedVar struct {
+	typ       *staticType
+	annotated bool
+}
+
+// TypeChecker checks a resolved script against its type annotations. It
+// infers the types of expressions from literals, operators and annotated
+// declarations, and reports the operations that would fail at runtime.
+//
+// Checking is gradual: a parameter without an annotation is any, as is every
+// value the checker cannot tell. A variable without an annotation takes the
+// type of its initializer until it is assigned a value of another type, and
+// is any from then on. Nothing is reported about a value of type any.
+type TypeChecker struct {
+	errs    []error
+	scopes  []map[string]*checkedVar
+	globals map[string]*checkedVar
+
+	// returns holds the return type of each function being checked,
+	// innermost last.
+	returns []*staticType
+}
+
+func NewTypeChecker() *TypeChecker {
+	return &TypeChecker{globals: make(map[string]*checkedVar)}
+}
+
+var (
+	_ ExprVisitor = (*TypeChecker)(nil)
+	_ StmtVisitor = (*TypeChecker)(nil)
+)
+
+// Check checks statements and returns every type error found, each a
+// *TypeError.
+func (c *TypeChecker) Check(statements []Stmt) []error {
+	c.errs = nil
+	c.statements(statements)
+	return c.errs
+}
+
+func (c *TypeChecker) report(token Token, format string, args ...any) {
+	c.errs = append(c.errs, NewTypeError(token, fmt.Sprintf(format, args...)))
+}
+
+func (c *TypeChecker) statements(statements []Stmt) {
+	for _, stmt := range statements {
+		_, _ = stmt.Accept(c)
+	}
+}
+
+func (c *TypeChecker) typeOf(expr Expr) *staticType {
+	typ, _ := expr.Accept(c)
+	return typ.(*staticType)
+}
+
+// annotated returns the type an annotation names, or any for none.
+func (c *TypeChecker) annotated(annotation *TypeAnnotation) *staticType {
+	if annotation == nil {
+		return anyType
+	}
+
+	typ, ok := namedTypes[annotation.Name.Lexeme]
+	if !ok {
+		c.report(annotation.Name, "Unknown type '%s'.", annotation.Name.Lexeme)
+		return anyType
+	}
+	return typ
+}
+
+func (c *TypeChecker) beginScope() {
+	c.scopes = append(c.scopes, make(map[string]*checkedVar))
+}
+
+func (c *TypeChecker) endScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+func (c *TypeChecker) declare(name Token, variable *checkedVar) {
+	if len(c.scopes) == 0 {
+		c.globals[name.Lexeme] = variable
+		return
+	}
+	c.scopes[len(c.scopes)-1][name.Lexeme] = variable
+}
+
+// lookUp finds a variable, innermost scope first. A name the checker has not
+// seen declared, such as a native, is nil.
+func (c *TypeChecker) lookUp(name Token) *checkedVar {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if variable, ok := c.scopes[i][name.Lexeme]; ok {
+			return variable
+		}
+	}
+	return c.globals[name.Lexeme]
+}
+
+// function checks a function body and returns the function's type.
+func (c *TypeChecker) function(parameters []Token, types *FunctionTypes, body []Stmt) *staticType {
+	if types == nil {
+		types = &FunctionTypes{Parameters: make([]*TypeAnnotation, len(parameters))}
+	}
+
+	typ := &staticType{name: "fun", signature: true, result: c.annotated(types.Return)}
+	c.beginScope()
+	for i, param := range parameters {
+		paramType := c.annotated(types.Parameters[i])
+		typ.parameters = append(typ.parameters, paramType)
+		c.declare(param, &checkedVar{typ: paramType, annotated: true})
+	}
+
+	c.returns = append(c.returns, typ.result)
+	c.statements(body)
+	c.returns = c.returns[:len(c.returns)-1]
+	c.endScope()
+	return typ
+}
+
+func (c *TypeChecker) VisitExprStmt(stmt *ExprStmt) (any, error) {
+	c.typeOf(stmt.Expression)
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitPrintStmt(stmt *PrintStmt) (any, error) {
+	c.typeOf(stmt.Expression)
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitVarDeclStmt(stmt *VarDeclStmt) (any, error) {
+	variable := &checkedVar{typ: anyType}
+	if stmt.Type != nil {
+		variable.typ = c.annotated(stmt.Type)
+		variable.annotated = true
+	}
+
+	if stmt.Initializer != nil {
+		value := c.typeOf(stmt.Initializer)
+		if !variable.annotated {
+			variable.typ = value
+		} else if !assignable(variable.typ, value) {
+			c.report(stmt.Name, "Expected a value of type %s for '%s' but got %s.", variable.typ, stmt.Name.Lexeme, value)
+		}
+	}
+
+	c.declare(stmt.Name, variable)
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitBlockStmt(stmt *BlockStmt) (any, error) {
+	c.beginScope()
+	c.statements(stmt.Statements)
+	c.endScope()
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitIfStmt(stmt *IfStmt) (any, error) {
+	c.typeOf(stmt.Condition)
+	_, _ = stmt.ThenBranch.Accept(c)
+	if stmt.ElseBranch != nil {
+		_, _ = stmt.ElseBranch.Accept(c)
+	}
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitWhileStmt(stmt *WhileStmt) (any, error) {
+	c.typeOf(stmt.Condition)
+	_, _ = stmt.Body.Accept(c)
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitForInStmt(stmt *ForInStmt) (any, error) {
+	c.typeOf(stmt.Iterable)
+	c.beginScope()
+	c.declare(stmt.Name, &checkedVar{typ: anyType})
+	_, _ = stmt.Body.Accept(c)
+	c.endScope()
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitFunctionDeclStmt(stmt *FunctionDeclStmt) (any, error) {
+	// Declared before the body is checked so the function can call itself.
+	variable := &checkedVar{typ: funType}
+	c.declare(stmt.Name, variable)
+	variable.typ = c.function(stmt.Parameters, stmt.Types, stmt.Body)
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitReturnStmt(stmt *ReturnStmt) (any, error) {
+	expected := anyType
+	if len(c.returns) > 0 {
+		expected = c.returns[len(c.returns)-1]
+	}
+
+	if stmt.Value == nil {
+		if !assignable(expected, nilType) {
+			c.report(stmt.Keyword, "Expected a return value of type %s.", expected)
+		}
+		return nil, nil
+	}
+
+	value := c.typeOf(stmt.Value)
+	if !assignable(expected, value) {
+		at := firstToken(exprStart(stmt.Value), stmt.Keyword)
+		c.report(at, "Expected a return value of type %s but got %s.", expected, value)
+	}
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitThrowStmt(stmt *ThrowStmt) (any, error) {
+	c.typeOf(stmt.Value)
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitTryStmt(stmt *TryStmt) (any, error) {
+	c.beginScope()
+	c.statements(stmt.Body)
+	c.endScope()
+
+	if stmt.CatchName != nil {
+		c.beginScope()
+		c.declare(*stmt.CatchName, &checkedVar{typ: anyType})
+		c.statements(stmt.CatchBody)
+		c.endScope()
+	}
+
+	c.beginScope()
+	c.statements(stmt.FinallyBody)
+	c.endScope()
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitImportStmt(stmt *ImportStmt) (any, error) {
+	names := stmt.Names
+	if stmt.Alias != nil {
+		names = []Token{*stmt.Alias}
+	}
+	for _, name := range names {
+		c.declare(name, &checkedVar{typ: anyType})
+	}
+	return nil, nil
+}
+
+func (c *TypeChecker) VisitExportStmt(stmt *ExportStmt) (any, error) {
+	return stmt.Declaration.Accept(c)
+}
+
+func (c *TypeChecker) VisitBinaryExpr(expr *BinaryExpr) (any, error) {
+	left := c.typeOf(expr.Left)
+	right := c.typeOf(expr.Right)
+	return c.binary(expr.Operator, left, right), nil
+}
+
+// binary checks the operands of a binary operator, which may come from a
+// compound assignment, and returns the type of its result.
+func (c *TypeChecker) binary(operator Token, left, right *staticType) *staticType {
+	known := left != anyType && right != anyType
+
+	switch operator.Type {
+	case PLUS:
+		if left == numberType && right == numberType {
+			return numberType
+		}
+		if left == stringType && right == stringType {
+			return stringType
+		}
+		if (known && left.name != right.name) || !canAdd(left) || !canAdd(right) {
+			c.report(operator, "Operands must be two numbers or two strings.")
+		}
+		return anyType
+	case MINUS, STAR, SLASH, PERCENT, STAR_STAR:
+		c.numberOperands(operator, left, right)
+		return numberType
+	case GREATER, GREATER_EQUAL, LESS, LESS_EQUAL:
+		c.numberOperands(operator, left, right)
+		return boolType
+	}
+	return boolType
+}
+
+// canAdd reports whether a value of a type might be an operand of "+".
+func canAdd(typ *staticType) bool {
+	return typ == anyType || typ == numberType || typ == stringType
+}
+
+func (c *TypeChecker) numberOperands(operator Token, left, right *staticType) {
+	if !assignable(numberType, left) || !assignable(numberType, right) {
+		c.report(operator, "Operands must be numbers.")
+	}
+}
+
+func (c *TypeChecker) VisitGroupingExpr(expr *GroupingExpr) (any, error) {
+	return c.typeOf(expr.Expression), nil
+}
+
+func (c *TypeChecker) VisitLiteralExpr(expr *LiteralExpr) (any, error) {
+	switch expr.Value.Value.(type) {
+	case float64:
+		return numberType, nil
+	case string:
+		return stringType, nil
+	case bool:
+		return boolType, nil
+	case nil:
+		return nilType, nil
+	}
+	return anyType, nil
+}
+
+func (c *TypeChecker) VisitUnaryExpr(expr *UnaryExpr) (any, error) {
+	right := c.typeOf(expr.Right)
+	if expr.Operator.Type == BANG {
+		return boolType, nil
+	}
+
+	if !assignable(numberType, right) {
+		c.report(expr.Operator, "Operand must be a number.")
+	}
+	return numberType, nil
+}
+
+func (c *TypeChecker) VisitVariableExpr(expr *VariableExpr) (any, error) {
+	if variable := c.lookUp(expr.Name); variable != nil {
+		return variable.typ, nil
+	}
+	return anyType, nil
+}
+
+func (c *TypeChecker) VisitAssignExpr(expr *AssignExpr) (any, error) {
+	variable := c.lookUp(expr.Name)
+	value := c.typeOf(expr.Value)
+	if expr.Operator != nil {
+		current := anyType
+		if variable != nil {
+			current = variable.typ
+		}
+		value = c.binary(*expr.Operator, current, value)
+	}
+
+	switch {
+	case variable == nil:
+	case variable.annotated:
+		if !assignable(variable.typ, value) {
+			c.report(expr.Name, "Expected a value of type %s for '%s' but got %s.", variable.typ, expr.Name.Lexeme, value)
+		}
+	case variable.typ != value:
+		// An unannotated variable holding values of several types could be
+		// any of them.
+		variable.typ = anyType
+	}
+	return value, nil
+}
+
+func (c *TypeChecker) VisitLogicalExpr(expr *LogicalExpr) (any, error) {
+	left := c.typeOf(expr.Left)
+	right := c.typeOf(expr.Right)
+	if left == right {
+		return left, nil
+	}
+	return anyType, nil
+}
+
+func (c *TypeChecker) VisitCallExpr(expr *CallExpr) (any, error) {
+	callee := c.typeOf(expr.Callee)
+	arguments := make([]*staticType, len(expr.Arguments))
+	for i, argument := range expr.Arguments {
+		arguments[i] = c.typeOf(argument)
+	}
+
+	if callee == anyType {
+		return anyType, nil
+	}
+	if callee.name != "fun" {
+		c.report(expr.Paren, "Can only call functions and classes.")
+		return anyType, nil
+	}
+	if !callee.signature {
+		return anyType, nil
+	}
+
+	if len(arguments) != len(callee.parameters) {
+		c.report(expr.Paren, "Expected %d arguments but got %d.", len(callee.parameters), len(arguments))
+		return callee.result, nil
+	}
+
+	for i, argument := range arguments {
+		if !assignable(callee.parameters[i], argument) {
+			at := firstToken(exprStart(expr.Arguments[i]), expr.Paren)
+			c.report(at, "Expected argument %d to be %s but got %s.", i+1, callee.parameters[i], argument)
+		}
+	}
+	return callee.result, nil
+}
+
+func (c *TypeChecker) VisitFunctionExpr(expr *FunctionExpr) (any, error) {
+	return c.function(expr.Parameters, expr.Types, expr.Body), nil
+}
+
+func (c *TypeChecker) VisitGetExpr(expr *GetExpr) (any, error) {
+	object := c.typeOf(expr.Object)
+	if object != anyType && object != mapType {
+		c.report(expr.Name, "Only objects have properties.")
+	}
+	return anyType, nil
+}
+
+func (c *TypeChecker) VisitSetExpr(expr *SetExpr) (any, error) {
+	object := c.typeOf(expr.Object)
+	value := c.typeOf(expr.Value)
+	if object != anyType && object != mapType {
+		c.report(expr.Name, "Only objects have fields.")
+	}
+	if expr.Operator != nil {
+		// Fields are not typed, so only the value can be checked.
+		value = c.binary(*expr.Operator, anyType, value)
+	}
+	return value, nil
+}
+
+func (c *TypeChecker) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	c.index(expr.Bracket, c.typeOf(expr.Object), c.typeOf(expr.Index))
+	return anyType, nil
+}
+
+func (c *TypeChecker) VisitIndexSetExpr(expr *IndexSetExpr) (any, error) {
+	c.index(expr.Bracket, c.typeOf(expr.Object), c.typeOf(expr.Index))
+	value := c.typeOf(expr.Value)
+	if expr.Operator != nil {
+		// Elements are not typed, so only the value can be checked.
+		value = c.binary(*expr.Operator, anyType, value)
+	}
+	return value, nil
+}
+
+// index checks the types of an index expression's object and index.
+func (c *TypeChecker) index(bracket Token, object, index *staticType) {
+	switch object {
+	case anyType:
+	case listType:
+		if !assignable(numberType, index) {
+			c.report(bracket, "List index must be an integer.")
+		}
+	case mapType:
+		if !assignable(stringType, index) {
+			c.report(bracket, "Map keys must be strings.")
+		}
+	default:
+		c.report(bracket, "Only lists and maps can be indexed.")
+	}
+}
+
+func (c *TypeChecker) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	c.typeOf(expr.Condition)
+	thenBranch := c.typeOf(expr.ThenBranch)
+	elseBranch := c.typeOf(expr.ElseBranch)
+	if thenBranch == elseBranch {
+		return thenBranch, nil
+	}
+	return anyType, nil
+}
+
+func (c *TypeChecker) VisitStringifyExpr(expr *StringifyExpr) (any, error) {
+	c.typeOf(expr.Expression)
+	return stringType, nil
+}
@@ -0,0 +1,140 @@
+package lox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func typeCheck(t *testing.T, source string) []string {
+	t.Helper()
+
+	tokens, errs := NewScanner(source).ScanTokens()
+	require.Empty(t, errs)
+	statements, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+	require.NoError(t, newResolver(make(map[Expr]int)).Resolve(statements))
+
+	var messages []string
+	for _, err := range NewTypeChecker().Check(statements) {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func TestTypeCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		errs   []string
+	}{
+		{
+			name: "annotations",
+			source: `
+var count: number = "one";
+var label: string;
+label = 2;
+var flag: boolean = true;`,
+			errs: []string{
+				"[line 2] Error at 'count': Expected a value of type number for 'count' but got string.",
+				"[line 4] Error at 'label': Expected a value of type string for 'label' but got number.",
+				"[line 5] Error at 'boolean': Unknown type 'boolean'.",
+			},
+		},
+		{
+			name: "functions",
+			source: `
+fun greet(name: string, times: number): string {
+  if (times < 1) return;
+  return times;
+}
+greet(1, 2);
+greet("a");
+var length = greet("a", 1) - 1;
+var twice = (n: number): number => n * 2;
+twice(true);
+var f = 1;
+f();`,
+			errs: []string{
+				"[line 3] Error at 'return': Expected a return value of type string.",
+				"[line 4] Error at 'times': Expected a return value of type string but got number.",
+				"[line 6] Error at '1': Expected argument 1 to be string but got number.",
+				"[line 7] Error at ')': Expected 2 arguments but got 1.",
+				"[line 8] Error at '-': Operands must be numbers.",
+				"[line 10] Error at 'true': Expected argument 1 to be number but got bool.",
+				"[line 12] Error at ')': Can only call functions and classes.",
+			},
+		},
+		{
+			name: "inference",
+			source: `
+var a = 1;
+var b = "b" + "${a}";
+print a + b;
+print -b;
+print b.length;
+var c = nil;
+c = 2;
+print c + "any";
+var d: number = a * 2 ** 3 % 4;
+a += "x";`,
+			errs: []string{
+				"[line 4] Error at '+': Operands must be two numbers or two strings.",
+				"[line 5] Error at '-': Operand must be a number.",
+				"[line 6] Error at 'length': Only objects have properties.",
+				"[line 11] Error at '+': Operands must be two numbers or two strings.",
+			},
+		},
+		{
+			name: "indexes",
+			source: `
+var s = "abc";
+print s[0];
+var xs: list = split("a,b", ",");
+print xs["0"];
+xs[0] += 1;
+xs[1] -= "x";`,
+			errs: []string{
+				"[line 3] Error at ']': Only lists and maps can be indexed.",
+				"[line 5] Error at ']': List index must be an integer.",
+				"[line 7] Error at '-': Operands must be numbers.",
+			},
+		},
+		{
+			name: "gradual",
+			source: `
+fun add(a, b) { return a + b; }
+var total: number = add(1, 2);
+var text: string = add("a", "b");
+var n = 1;
+n = "now a string";
+print n - 1;
+var callback: fun = (x) => x;
+print callback(1, 2, 3);
+print sqrt(true);`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.errs, typeCheck(t, test.source))
+		})
+	}
+}
+
+func TestTypeAnnotationsIgnoredAtRuntime(t *testing.T) {
+	output := runSource(t, `
+var x: number = 1;
+fun label(n: number, suffix: string): string { return "${n}${suffix}"; }
+var double = (n: number): number => n * 2;
+var anything: string = 3;
+print label(double(x), "!");
+print anything;`)
+	assert.Equal(t, "2!\n3\n", output)
+}
+
+func TestTypeAnnotationParseErrors(t *testing.T) {
+	err := runSourceErr(t, `var x: = 1;`)
+	assert.EqualError(t, err, "[line 1] Error at '=': Expect type after ':'.")
+}
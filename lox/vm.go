@@ -1,8 +1,11 @@
 package lox
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 )
 
 type VM struct {
@@ -13,6 +16,9 @@ type VM struct {
 	stack    [256]Value
 	stackTop int
 
+	stdout io.Writer
+	limits *limits
+
 	DebugTraceExecution bool
 }
 
@@ -21,34 +27,56 @@ var (
 	ErrInterpretRuntime = errors.New("interpret: runtime error")
 )
 
-func NewVM(chunk *Chunk) *VM {
+func NewVM(chunk *Chunk, opts ...Option) *VM {
+	o := newOptions(opts)
+
 	return &VM{
 		chunk:               chunk,
 		ip:                  0,
 		DebugTraceExecution: false,
 		stackTop:            0,
+		stdout:              o.stdout,
+		limits:              newLimits(o),
 	}
 }
 
-func Interpret(source string) error {
+func Interpret(source string, opts ...Option) error {
+	return InterpretContext(context.Background(), source, opts...)
+}
+
+// InterpretContext is like Interpret but stops with an InterruptedError once
+// ctx is done.
+func InterpretContext(ctx context.Context, source string, opts ...Option) error {
 	var chunk Chunk
-	if !compile(source, &chunk) {
+	if !compile(source, &chunk, newOptions(opts)) {
 		return ErrInterpretCompile
 	}
 
-	vm := NewVM(&chunk)
+	return NewVM(&chunk, opts...).runContext(ctx)
+}
+
+func (vm *VM) runContext(ctx context.Context) error {
+	defer vm.limits.start(ctx)()
+	if err := vm.limits.checkContext(); err != nil {
+		return err
+	}
+
 	return vm.run()
 }
 
 func (vm *VM) run() error {
 	for {
+		if err := vm.limits.step(); err != nil {
+			return err
+		}
+
 		if vm.DebugTraceExecution {
-			fmt.Print("          ")
+			fmt.Fprint(vm.stdout, "          ")
 			for i := 0; i < vm.stackTop; i++ {
-				fmt.Printf("[ %v ]", vm.stack[i])
+				fmt.Fprintf(vm.stdout, "[ %v ]", vm.stack[i])
 			}
-			fmt.Println()
-			vm.chunk.disassembleInstruction(vm.ip)
+			fmt.Fprintln(vm.stdout)
+			vm.chunk.disassembleInstruction(vm.stdout, vm.ip)
 		}
 
 		instruction := vm.readByte()
@@ -69,11 +97,17 @@ func (vm *VM) run() error {
 		case OP_DIVIDE:
 			vm.binaryOp("/")
 
+		case OP_MODULO:
+			vm.binaryOp("%")
+
+		case OP_POWER:
+			vm.binaryOp("**")
+
 		case OP_NEGATE:
 			vm.push(-vm.pop())
 
 		case OP_RETURN:
-			fmt.Println(vm.pop())
+			fmt.Fprintln(vm.stdout, vm.pop())
 			return nil
 		}
 	}
@@ -119,6 +153,10 @@ func (vm *VM) binaryOp(operator string) {
 		vm.push(a * b)
 	case "/":
 		vm.push(a / b)
+	case "%":
+		vm.push(Value(math.Mod(float64(a), float64(b))))
+	case "**":
+		vm.push(Value(math.Pow(float64(a), float64(b))))
 	default:
 		panic("unknown operator")
 	}
@@ -1,6 +1,9 @@
 package lox
 
 import (
+	"bytes"
+	"context"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,3 +39,38 @@ func TestInterpret(t *testing.T) {
 	err := Interpret("(-1 + 2) * 3 - -4")
 	assert.NoError(t, err)
 }
+
+func TestInterpretModuloAndPower(t *testing.T) {
+	var output bytes.Buffer
+	err := Interpret("-2 ** 3 ** 2 % 7", WithStdout(&output))
+	assert.NoError(t, err)
+	assert.Equal(t, "-1\n", output.String())
+}
+
+func TestInterpretRedirectedStreams(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Interpret("1 + 2", WithStdout(&stdout), WithStderr(&stderr))
+	assert.NoError(t, err)
+	assert.Equal(t, "3\n", stdout.String())
+	assert.Empty(t, stderr.String())
+
+	stdout.Reset()
+	err = Interpret("1 +", WithStdout(&stdout), WithStderr(&stderr))
+	assert.ErrorIs(t, err, ErrInterpretCompile)
+	assert.Empty(t, stdout.String())
+	assert.Equal(t, "[line 1] Error at end: Expect expression.\n", stderr.String())
+}
+
+func TestInterpretContextLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := InterpretContext(ctx, "1 + 2", WithStdout(io.Discard))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = InterpretContext(context.Background(), "1 + 2 * 3", WithStdout(io.Discard), WithMaxSteps(3))
+	var stepErr *StepLimitError
+	assert.ErrorAs(t, err, &stepErr)
+
+	err = InterpretContext(context.Background(), "1 + 2 * 3", WithStdout(io.Discard), WithMaxSteps(6))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,537 @@
+// Package lsp implements a Language Server Protocol server for Lox, giving
+// editors diagnostics, navigation, hovers, document symbols and renaming.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"interpreter/lox"
+)
+
+// JSON-RPC error codes.
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeRequestFailed  = -32803
+)
+
+// Server serves one client over a pair of streams.
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	documents map[string]*document // by URI
+	builtins  *lox.Interpreter     // to describe natives in hovers
+}
+
+// document is an open file and what is known about its current text.
+type document struct {
+	lines    []string
+	analysis *lox.Analysis
+}
+
+// NewServer returns a server that reads requests from in and writes responses
+// and notifications to out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: make(map[string]*document),
+		builtins:  lox.NewInterpreter(),
+	}
+}
+
+type message struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// responseError is a failed request.
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+// Serve handles messages until the client sends exit or closes the input.
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			// Notifications have no response.
+			continue
+		}
+
+		response := map[string]any{"jsonrpc": "2.0", "id": msg.ID}
+		var rpcErr *responseError
+		switch {
+		case errors.As(err, &rpcErr):
+			response["error"] = rpcErr
+		case err != nil:
+			response["error"] = &responseError{Code: codeRequestFailed, Message: err.Error()}
+		default:
+			response["result"] = result
+		}
+		if err := s.write(response); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) read() (*message, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(s.in, content); err != nil {
+		return nil, err
+	}
+
+	var msg message
+	if err := json.Unmarshal(content, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (s *Server) write(msg any) error {
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	return err
+}
+
+func (s *Server) notify(method string, params any) error {
+	return s.write(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type textRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string    `json:"uri"`
+	Range textRange `json:"range"`
+}
+
+type textDocumentPosition struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position position `json:"position"`
+}
+
+func (s *Server) handle(msg *message) (any, error) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":       1, // the full text on every change
+				"definitionProvider":     true,
+				"referencesProvider":     true,
+				"hoverProvider":          true,
+				"documentSymbolProvider": true,
+				"renameProvider":         true,
+			},
+			"serverInfo": map[string]any{"name": "loxls"},
+		}, nil
+
+	case "shutdown":
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		return nil, s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+
+	case "textDocument/didClose":
+		var params textDocumentPosition
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics", map[string]any{
+			"uri":         params.TextDocument.URI,
+			"diagnostics": []any{},
+		})
+
+	case "textDocument/definition":
+		c, err := s.cursorAt(msg.Params)
+		if err != nil || c.symbol == nil {
+			return nil, err
+		}
+		return location{URI: c.uri, Range: c.doc.tokenRange(c.symbol.Name)}, nil
+
+	case "textDocument/references":
+		var params struct {
+			Context struct {
+				IncludeDeclaration bool `json:"includeDeclaration"`
+			} `json:"context"`
+		}
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		c, err := s.cursorAt(msg.Params)
+		if err != nil || c.symbol == nil {
+			return []location{}, err
+		}
+
+		locations := []location{}
+		if params.Context.IncludeDeclaration {
+			locations = append(locations, location{URI: c.uri, Range: c.doc.tokenRange(c.symbol.Name)})
+		}
+		for _, reference := range c.symbol.References {
+			locations = append(locations, location{URI: c.uri, Range: c.doc.tokenRange(reference)})
+		}
+		return locations, nil
+
+	case "textDocument/hover":
+		return s.hover(msg.Params)
+
+	case "textDocument/documentSymbol":
+		var params textDocumentPosition
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok {
+			return nil, fmt.Errorf("Unknown document %s.", params.TextDocument.URI)
+		}
+		return doc.symbols(params.TextDocument.URI), nil
+
+	case "textDocument/rename":
+		var params struct {
+			NewName string `json:"newName"`
+		}
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if !isIdentifier(params.NewName) {
+			return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("'%s' is not a valid name.", params.NewName)}
+		}
+
+		c, err := s.cursorAt(msg.Params)
+		if err != nil {
+			return nil, err
+		}
+		if c.symbol == nil {
+			return nil, errors.New("There is nothing to rename here.")
+		}
+		if c.symbol.Kind == lox.SymbolImport {
+			return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("'%s' is imported and cannot be renamed.", c.symbol.Name.Lexeme)}
+		}
+		if other := c.symbol.Sibling(params.NewName); other != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("'%s' is already declared in this scope on line %d.", params.NewName, other.Name.Line)}
+		}
+
+		edits := []map[string]any{{"range": c.doc.tokenRange(c.symbol.Name), "newText": params.NewName}}
+		for _, reference := range c.symbol.References {
+			edits = append(edits, map[string]any{"range": c.doc.tokenRange(reference), "newText": params.NewName})
+		}
+		return map[string]any{"changes": map[string]any{c.uri: edits}}, nil
+	}
+
+	if msg.ID == nil || strings.HasPrefix(msg.Method, "$/") {
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("Unsupported method '%s'.", msg.Method)}
+}
+
+func unmarshal(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// update analyzes the new text of a document and publishes its diagnostics.
+func (s *Server) update(uri, text string) error {
+	doc := &document{lines: strings.Split(text, "\n"), analysis: lox.Analyze(text)}
+	s.documents[uri] = doc
+
+	diagnostics := make([]map[string]any, len(doc.analysis.Diagnostics))
+	for idx, diagnostic := range doc.analysis.Diagnostics {
+		start := doc.position(diagnostic.Line, diagnostic.Column)
+		end := doc.position(diagnostic.Line, diagnostic.Column+diagnostic.Length)
+		diagnostics[idx] = map[string]any{
+			"range":    textRange{Start: start, End: end},
+			"severity": 1, // error
+			"source":   "lox",
+			"message":  diagnostic.Message,
+		}
+	}
+
+	return s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": diagnostics})
+}
+
+// cursor is the place in a document a request is about.
+type cursor struct {
+	uri    string
+	doc    *document
+	line   int // from 1
+	column int // the byte in the line, from 1
+	symbol *lox.Symbol
+}
+
+// cursorAt returns the place given by a request's parameters and the symbol
+// there, which is nil if there is none.
+func (s *Server) cursorAt(raw json.RawMessage) (*cursor, error) {
+	var params textDocumentPosition
+	if err := unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+
+	uri := params.TextDocument.URI
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil, fmt.Errorf("Unknown document %s.", uri)
+	}
+
+	line, column := doc.column(params.Position)
+	return &cursor{
+		uri:    uri,
+		doc:    doc,
+		line:   line,
+		column: column,
+		symbol: doc.analysis.SymbolAt(line, column),
+	}, nil
+}
+
+func (s *Server) hover(raw json.RawMessage) (any, error) {
+	c, err := s.cursorAt(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var text string
+	var token lox.Token
+	if c.symbol != nil {
+		text = describe(c.symbol)
+		token = c.symbol.Name
+		for _, reference := range c.symbol.References {
+			if reference.Line == c.line && c.column >= reference.Column && c.column <= reference.Column+len(reference.Lexeme) {
+				token = reference
+			}
+		}
+	} else {
+		// Natives are not declared in the script, so look them up by name.
+		name, start := c.doc.word(c.line, c.column)
+		value, _ := s.builtins.GetGlobal(name)
+		callable, ok := value.(lox.Callable)
+		if !ok {
+			return nil, nil
+		}
+		text = fmt.Sprintf("```lox\nfun %s(%s)\n```\nNative function %s.", name, placeholders(callable.Arity()), arity(callable.Arity()))
+		token = lox.Token{Lexeme: name, Line: c.line, Column: start}
+	}
+
+	return map[string]any{
+		"contents": map[string]any{"kind": "markdown", "value": text},
+		"range":    c.doc.tokenRange(token),
+	}, nil
+}
+
+// describe returns the hover text for a symbol.
+func describe(symbol *lox.Symbol) string {
+	switch symbol.Kind {
+	case lox.SymbolFunction:
+		names := make([]string, len(symbol.Parameters))
+		for idx, param := range symbol.Parameters {
+			names[idx] = param.Lexeme
+		}
+		return fmt.Sprintf("```lox\nfun %s(%s)\n```\nFunction %s.", symbol.Name.Lexeme, strings.Join(names, ", "), arity(len(names)))
+	case lox.SymbolParameter:
+		return fmt.Sprintf("```lox\n%s\n```\nParameter.", symbol.Name.Lexeme)
+	case lox.SymbolImport:
+		return fmt.Sprintf("```lox\n%s\n```\nImported.", symbol.Name.Lexeme)
+	}
+
+	scope := "Local variable."
+	if symbol.Global {
+		scope = "Global variable."
+	}
+	return fmt.Sprintf("```lox\nvar %s\n```\n%s", symbol.Name.Lexeme, scope)
+}
+
+func arity(n int) string {
+	if n == 1 {
+		return "taking 1 argument"
+	}
+	return fmt.Sprintf("taking %d arguments", n)
+}
+
+func placeholders(n int) string {
+	names := make([]string, n)
+	for idx := range names {
+		names[idx] = string(rune('a' + idx%26))
+	}
+	return strings.Join(names, ", ")
+}
+
+// symbols lists the functions, at any depth, and the global variables of a
+// document.
+func (doc *document) symbols(uri string) []map[string]any {
+	symbols := []map[string]any{}
+	for _, symbol := range doc.analysis.Symbols {
+		kind := 13 // Variable
+		switch {
+		case symbol.Kind == lox.SymbolFunction:
+			kind = 12 // Function
+		case symbol.Global && symbol.Kind != lox.SymbolImport:
+		default:
+			continue
+		}
+
+		entry := map[string]any{
+			"name":     symbol.Name.Lexeme,
+			"kind":     kind,
+			"location": location{URI: uri, Range: doc.tokenRange(symbol.Name)},
+		}
+		if symbol.Container != nil {
+			entry["containerName"] = symbol.Container.Name.Lexeme
+		}
+		symbols = append(symbols, entry)
+	}
+	return symbols
+}
+
+// position converts a 1-based line and byte column to an LSP position, which
+// counts lines from 0 and characters in UTF-16 code units.
+func (doc *document) position(line, column int) position {
+	if line < 1 || line > len(doc.lines) {
+		return position{Line: max(line-1, 0)}
+	}
+
+	text := doc.lines[line-1]
+	column = min(max(column, 1), len(text)+1)
+	return position{Line: line - 1, Character: len(utf16.Encode([]rune(text[:column-1])))}
+}
+
+// column converts an LSP position back to a 1-based line and byte column.
+func (doc *document) column(pos position) (int, int) {
+	if pos.Line < 0 || pos.Line >= len(doc.lines) {
+		return pos.Line + 1, 1
+	}
+
+	text := doc.lines[pos.Line]
+	units := 0
+	for offset, r := range text {
+		if units >= pos.Character {
+			return pos.Line + 1, offset + 1
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return pos.Line + 1, len(text) + 1
+}
+
+func (doc *document) tokenRange(token lox.Token) textRange {
+	return textRange{
+		Start: doc.position(token.Line, token.Column),
+		End:   doc.position(token.Line, token.Column+len(token.Lexeme)),
+	}
+}
+
+// word returns the identifier around a line and byte column, and the column
+// it starts at.
+func (doc *document) word(line, column int) (string, int) {
+	if line < 1 || line > len(doc.lines) {
+		return "", column
+	}
+
+	text := doc.lines[line-1]
+	start := min(column-1, len(text))
+	for start > 0 {
+		r, width := utf8.DecodeLastRuneInString(text[:start])
+		if !isWordRune(r) {
+			break
+		}
+		start -= width
+	}
+	end := start
+	for end < len(text) {
+		r, width := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(r) {
+			break
+		}
+		end += width
+	}
+	return text[start:end], start + 1
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// isIdentifier reports whether name can be used as a variable name.
+func isIdentifier(name string) bool {
+	tokens, errs := lox.NewScanner(name).ScanTokens()
+	return len(errs) == 0 && len(tokens) == 2 && tokens[0].Type == lox.IDENTIFIER && tokens[0].Lexeme == name
+}
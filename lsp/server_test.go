@@ -0,0 +1,230 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const uri = "file:///main.lox"
+
+// client drives a Server the way an editor would.
+type client struct {
+	t   *testing.T
+	in  io.Writer
+	out *bufio.Reader
+	id  int
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	done := make(chan error)
+	go func() {
+		done <- NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+	}()
+	t.Cleanup(func() {
+		clientOut.Close()
+		assert.NoError(t, <-done)
+	})
+
+	return &client{t: t, in: clientOut, out: bufio.NewReader(clientIn)}
+}
+
+func (c *client) send(msg map[string]any) {
+	c.t.Helper()
+
+	msg["jsonrpc"] = "2.0"
+	content, err := json.Marshal(msg)
+	require.NoError(c.t, err)
+	_, err = fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	require.NoError(c.t, err)
+}
+
+func (c *client) receive() map[string]any {
+	c.t.Helper()
+
+	header, err := textproto.NewReader(c.out).ReadMIMEHeader()
+	require.NoError(c.t, err)
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	require.NoError(c.t, err)
+	content := make([]byte, length)
+	_, err = io.ReadFull(c.out, content)
+	require.NoError(c.t, err)
+
+	var msg map[string]any
+	require.NoError(c.t, json.Unmarshal(content, &msg))
+	return msg
+}
+
+// request sends a request and returns its response.
+func (c *client) request(method string, params any) map[string]any {
+	c.t.Helper()
+
+	c.id++
+	c.send(map[string]any{"id": c.id, "method": method, "params": params})
+	response := c.receive()
+	require.Equal(c.t, float64(c.id), response["id"])
+	return response
+}
+
+// open sends the text of the document and returns the diagnostics published
+// for it.
+func (c *client) open(text string) []any {
+	c.t.Helper()
+
+	c.send(map[string]any{
+		"method": "textDocument/didOpen",
+		"params": map[string]any{"textDocument": map[string]any{"uri": uri, "languageId": "lox", "version": 1, "text": text}},
+	})
+	notification := c.receive()
+	require.Equal(c.t, "textDocument/publishDiagnostics", notification["method"])
+	return notification["params"].(map[string]any)["diagnostics"].([]any)
+}
+
+func at(line, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+func span(line, start, end int) map[string]any {
+	return map[string]any{
+		"start": map[string]any{"line": float64(line), "character": float64(start)},
+		"end":   map[string]any{"line": float64(line), "character": float64(end)},
+	}
+}
+
+const source = `fun add(a, b) {
+  return a + b;
+}
+var café = add(1, 2);
+print add(café, len("x"));
+`
+
+func TestServerNavigation(t *testing.T) {
+	c := newClient(t)
+	result := c.request("initialize", map[string]any{"capabilities": map[string]any{}})["result"].(map[string]any)
+	assert.Equal(t, true, result["capabilities"].(map[string]any)["hoverProvider"])
+	assert.Empty(t, c.open(source))
+
+	definition := c.request("textDocument/definition", at(4, 7))["result"]
+	assert.Equal(t, map[string]any{"uri": uri, "range": span(0, 4, 7)}, definition)
+
+	params := at(0, 5)
+	params["context"] = map[string]any{"includeDeclaration": true}
+	references := c.request("textDocument/references", params)["result"]
+	assert.Equal(t, []any{
+		map[string]any{"uri": uri, "range": span(0, 4, 7)},
+		map[string]any{"uri": uri, "range": span(3, 11, 14)},
+		map[string]any{"uri": uri, "range": span(4, 6, 9)},
+	}, references)
+
+	// Columns count UTF-16 code units, so "é" is one character.
+	references = c.request("textDocument/references", at(4, 12))["result"]
+	assert.Equal(t, []any{map[string]any{"uri": uri, "range": span(4, 10, 14)}}, references)
+
+	hover := c.request("textDocument/hover", at(3, 12))["result"].(map[string]any)
+	assert.Equal(t, "```lox\nfun add(a, b)\n```\nFunction taking 2 arguments.", hover["contents"].(map[string]any)["value"])
+	assert.Equal(t, span(3, 11, 14), hover["range"])
+
+	hover = c.request("textDocument/hover", at(4, 17))["result"].(map[string]any)
+	assert.Equal(t, "```lox\nfun len(a)\n```\nNative function taking 1 argument.", hover["contents"].(map[string]any)["value"])
+
+	assert.Nil(t, c.request("textDocument/hover", at(1, 0))["result"])
+
+	symbols := c.request("textDocument/documentSymbol", map[string]any{"textDocument": map[string]any{"uri": uri}})["result"]
+	assert.Equal(t, []any{
+		map[string]any{"name": "add", "kind": 12.0, "location": map[string]any{"uri": uri, "range": span(0, 4, 7)}},
+		map[string]any{"name": "café", "kind": 13.0, "location": map[string]any{"uri": uri, "range": span(3, 4, 8)}},
+	}, symbols)
+
+	params = at(1, 9)
+	params["newName"] = "left"
+	rename := c.request("textDocument/rename", params)["result"]
+	assert.Equal(t, map[string]any{"changes": map[string]any{uri: []any{
+		map[string]any{"range": span(0, 8, 9), "newText": "left"},
+		map[string]any{"range": span(1, 9, 10), "newText": "left"},
+	}}}, rename)
+
+	params["newName"] = "while"
+	failed := c.request("textDocument/rename", params)
+	assert.Equal(t, "'while' is not a valid name.", failed["error"].(map[string]any)["message"])
+
+	assert.Nil(t, c.request("shutdown", nil)["result"])
+	c.send(map[string]any{"method": "exit"})
+}
+
+func TestServerRenameRejected(t *testing.T) {
+	c := newClient(t)
+	c.request("initialize", map[string]any{})
+	c.open(`import { value } from "lib.lox";
+fun f(a, b) {
+  var c = a;
+  return c + b;
+}
+var d = value;
+`)
+
+	tests := []struct {
+		line, character int
+		newName         string
+		message         string
+	}{
+		{0, 10, "other", "'value' is imported and cannot be renamed."},
+		{5, 9, "other", "'value' is imported and cannot be renamed."},
+		{1, 6, "b", "'b' is already declared in this scope on line 2."},
+		{2, 6, "a", "'a' is already declared in this scope on line 2."},
+		{5, 4, "f", "'f' is already declared in this scope on line 2."},
+	}
+	for _, test := range tests {
+		params := at(test.line, test.character)
+		params["newName"] = test.newName
+		failed := c.request("textDocument/rename", params)["error"].(map[string]any)
+		assert.Equal(t, float64(codeInvalidParams), failed["code"], test.message)
+		assert.Equal(t, test.message, failed["message"])
+	}
+
+	// Shadowing a name from an enclosing scope is allowed.
+	params := at(2, 6)
+	params["newName"] = "d"
+	rename := c.request("textDocument/rename", params)
+	assert.Nil(t, rename["error"])
+	assert.Len(t, rename["result"].(map[string]any)["changes"].(map[string]any)[uri], 2)
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	c := newClient(t)
+	c.request("initialize", map[string]any{})
+	assert.Empty(t, c.open("var a = 1;\n"))
+
+	c.send(map[string]any{
+		"method": "textDocument/didChange",
+		"params": map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": 2},
+			"contentChanges": []any{map[string]any{"text": "var a = 1;\nfun f() {\n  return a +;\n"}},
+		},
+	})
+	notification := c.receive()
+	assert.Equal(t, []any{
+		map[string]any{"range": span(2, 12, 13), "severity": 1.0, "source": "lox", "message": "Expect expression."},
+		map[string]any{"range": span(3, 0, 0), "severity": 1.0, "source": "lox", "message": "Expect '}' after block."},
+	}, notification["params"].(map[string]any)["diagnostics"])
+
+	// f is still known even though its body is incomplete.
+	symbols := c.request("textDocument/documentSymbol", map[string]any{"textDocument": map[string]any{"uri": uri}})["result"].([]any)
+	assert.Len(t, symbols, 2)
+
+	unknown := c.request("textDocument/codeLens", at(0, 0))
+	assert.Equal(t, -32601.0, unknown["error"].(map[string]any)["code"])
+}
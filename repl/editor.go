@@ -0,0 +1,394 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// errInterrupted is returned by ReadLine when the user presses Ctrl-C to
+// abandon the current input.
+var errInterrupted = errors.New("interrupted")
+
+// lineReader reads one line of input after showing a prompt.
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// plainReader reads lines without editing, for input that is not a terminal.
+type plainReader struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func (r *plainReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.in.Scan() {
+		if err := r.in.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.in.Text(), nil
+}
+
+// editor reads lines from a terminal with cursor movement, history, reverse
+// search and tab completion. The terminal is in raw mode only while a line is
+// being read, so output from the script is not affected.
+type editor struct {
+	fd       uintptr
+	raw      bool // whether to switch fd to raw mode while reading
+	in       *bufio.Reader
+	out      io.Writer
+	history  *history
+	complete func(word string) []string
+
+	prompt string
+	buf    []rune
+	pos    int    // cursor position in buf
+	index  int    // history entry being shown; len(history.lines) is the new line
+	saved  []rune // the new line, kept while browsing the history
+}
+
+func ctrl(r rune) rune {
+	return r & 0x1f
+}
+
+const (
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+func (e *editor) ReadLine(prompt string) (string, error) {
+	if e.raw {
+		restore, err := makeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+
+	e.prompt = prompt
+	e.buf = nil
+	e.pos = 0
+	e.index = len(e.history.lines)
+	e.saved = nil
+	e.refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			e.write("\r\n")
+			return e.accept(), nil
+		case ctrl('C'):
+			e.write("^C\r\n")
+			return "", errInterrupted
+		case ctrl('D'):
+			if len(e.buf) == 0 {
+				return "", io.EOF
+			}
+			e.delete()
+		case ctrl('A'):
+			e.pos = 0
+		case ctrl('E'):
+			e.pos = len(e.buf)
+		case ctrl('B'):
+			e.left()
+		case ctrl('F'):
+			e.right()
+		case ctrl('K'):
+			e.buf = e.buf[:e.pos]
+		case ctrl('U'):
+			e.buf = e.buf[e.pos:]
+			e.pos = 0
+		case ctrl('W'):
+			e.deleteWord()
+		case ctrl('P'):
+			e.previous()
+		case ctrl('N'):
+			e.next()
+		case ctrl('R'):
+			accepted, err := e.reverseSearch()
+			if err != nil {
+				return "", err
+			}
+			if accepted {
+				e.refresh()
+				e.write("\r\n")
+				return e.accept(), nil
+			}
+		case '\t':
+			e.completeWord()
+		case keyBackspace, ctrl('H'):
+			if e.pos > 0 {
+				e.pos--
+				e.delete()
+			}
+		case keyEscape:
+			if err := e.escape(); err != nil {
+				return "", err
+			}
+		default:
+			if unicode.IsPrint(r) {
+				e.insert([]rune{r})
+			}
+		}
+
+		e.refresh()
+	}
+}
+
+// accept records the finished line in the history and returns it. A history
+// file that cannot be written should not end the session, so errors saving
+// it are ignored.
+func (e *editor) accept() string {
+	line := string(e.buf)
+	_ = e.history.add(line)
+	return line
+}
+
+// escape handles the rest of an escape sequence, such as an arrow key.
+func (e *editor) escape() error {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return err
+	}
+	if r != '[' && r != 'O' {
+		return nil
+	}
+
+	var param strings.Builder
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return err
+		}
+		if r >= 0x40 && r <= 0x7e {
+			break
+		}
+		param.WriteRune(r)
+	}
+
+	switch r {
+	case 'A':
+		e.previous()
+	case 'B':
+		e.next()
+	case 'C':
+		e.right()
+	case 'D':
+		e.left()
+	case 'H':
+		e.pos = 0
+	case 'F':
+		e.pos = len(e.buf)
+	case '~':
+		switch param.String() {
+		case "1", "7":
+			e.pos = 0
+		case "4", "8":
+			e.pos = len(e.buf)
+		case "3":
+			e.delete()
+		}
+	}
+	return nil
+}
+
+func (e *editor) left() {
+	if e.pos > 0 {
+		e.pos--
+	}
+}
+
+func (e *editor) right() {
+	if e.pos < len(e.buf) {
+		e.pos++
+	}
+}
+
+func (e *editor) insert(runes []rune) {
+	e.buf = append(e.buf[:e.pos], append(runes, e.buf[e.pos:]...)...)
+	e.pos += len(runes)
+}
+
+// delete removes the character under the cursor.
+func (e *editor) delete() {
+	if e.pos < len(e.buf) {
+		e.buf = append(e.buf[:e.pos], e.buf[e.pos+1:]...)
+	}
+}
+
+func (e *editor) deleteWord() {
+	start := e.pos
+	for start > 0 && e.buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && e.buf[start-1] != ' ' {
+		start--
+	}
+	e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+	e.pos = start
+}
+
+// previous shows the history entry before the one shown now.
+func (e *editor) previous() {
+	if e.index == 0 {
+		return
+	}
+	if e.index == len(e.history.lines) {
+		e.saved = e.buf
+	}
+	e.index--
+	e.show([]rune(e.history.lines[e.index]))
+}
+
+// next shows the history entry after the one shown now, and finally the new
+// line again.
+func (e *editor) next() {
+	if e.index == len(e.history.lines) {
+		return
+	}
+	e.index++
+	if e.index == len(e.history.lines) {
+		e.show(e.saved)
+	} else {
+		e.show([]rune(e.history.lines[e.index]))
+	}
+}
+
+func (e *editor) show(line []rune) {
+	e.buf = append([]rune(nil), line...)
+	e.pos = len(e.buf)
+}
+
+// reverseSearch searches backwards through the history as the user types, as
+// started by Ctrl-R. Pressing Ctrl-R again finds an older match. Enter
+// accepts the match as the input line, Ctrl-G cancels the search, and any
+// other key leaves the match in the buffer and is then handled as usual. It reports whether
+// the line was accepted.
+func (e *editor) reverseSearch() (bool, error) {
+	original := e.buf
+	var query []rune
+	match := len(e.history.lines)
+	found := true
+
+	for {
+		text := ""
+		if match < len(e.history.lines) {
+			text = e.history.lines[match]
+		}
+		status := "(reverse-i-search)'"
+		if !found {
+			status = "(failed reverse-i-search)'"
+		}
+		e.write("\r" + status + string(query) + "': " + text + "\x1b[K")
+
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return false, err
+		}
+
+		switch r {
+		case ctrl('R'):
+			if idx := e.history.search(string(query), match); idx >= 0 {
+				match = idx
+			}
+		case keyBackspace, ctrl('H'):
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				match = len(e.history.lines)
+				if idx := e.history.search(string(query), match); idx >= 0 {
+					match = idx
+				}
+				found = true
+			}
+		case ctrl('G'), ctrl('C'):
+			e.show(original)
+			return false, nil
+		case '\r', '\n':
+			e.show([]rune(text))
+			return true, nil
+		default:
+			if !unicode.IsPrint(r) {
+				e.show([]rune(text))
+				// Let the editor handle the key that ended the search.
+				return false, e.in.UnreadRune()
+			}
+			query = append(query, r)
+			// The current match may still match the longer query.
+			if idx := e.history.search(string(query), match+1); idx >= 0 {
+				match = idx
+				found = true
+			} else {
+				found = false
+			}
+		}
+	}
+}
+
+// completeWord completes the word before the cursor. A single completion is
+// inserted; with several, their common prefix is inserted, or if there is
+// none to add they are listed.
+func (e *editor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+
+	start := e.pos
+	for start > 0 && isWordRune(e.buf[start-1]) {
+		start--
+	}
+	word := string(e.buf[start:e.pos])
+	if word == "" {
+		return
+	}
+
+	completions := e.complete(word)
+	if len(completions) == 0 {
+		e.write("\a")
+		return
+	}
+
+	common := completions[0]
+	for _, completion := range completions[1:] {
+		for !strings.HasPrefix(completion, common) {
+			common = common[:len(common)-1]
+		}
+	}
+
+	if len(common) > len(word) {
+		e.insert([]rune(common[len(word):]))
+		return
+	}
+	e.write("\r\n" + strings.Join(completions, "  ") + "\r\n")
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// refresh redraws the prompt and line and puts the cursor where it belongs.
+func (e *editor) refresh() {
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(e.prompt)
+	b.WriteString(string(e.buf))
+	b.WriteString("\x1b[K")
+	if n := len(e.buf) - e.pos; n > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", n)
+	}
+	e.write(b.String())
+}
+
+func (e *editor) write(s string) {
+	io.WriteString(e.out, s)
+}
@@ -0,0 +1,140 @@
+package repl
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	keyUp    = "\x1b[A"
+	keyDown  = "\x1b[B"
+	keyRight = "\x1b[C"
+	keyLeft  = "\x1b[D"
+	keyHome  = "\x1b[H"
+	keyEnd   = "\x1b[F"
+	keyDel   = "\x1b[3~"
+)
+
+// readLines feeds keys to an editor and returns every line it reads.
+func readLines(t *testing.T, e *editor, keys string) []string {
+	t.Helper()
+
+	e.in = bufio.NewReader(strings.NewReader(keys))
+	e.out = io.Discard
+	if e.history == nil {
+		e.history = &history{}
+	}
+
+	var lines []string
+	for {
+		line, err := e.ReadLine("> ")
+		if err == io.EOF {
+			return lines
+		}
+		if err == errInterrupted {
+			lines = append(lines, "<interrupted>")
+			continue
+		}
+		require.NoError(t, err)
+		lines = append(lines, line)
+	}
+}
+
+func TestEditorEditing(t *testing.T) {
+	lines := readLines(t, &editor{}, strings.Join([]string{
+		"ac" + keyLeft + "b\r",
+		"world" + keyHome + "hello " + keyEnd + "!\r",
+		"abcd" + keyLeft + keyLeft + "\x7f" + keyDel + "\r",
+		"one two three\x17\x17four\r",
+		"keep\x01\x0b\rdrop\x15\r",
+		"x" + keyLeft + keyLeft + keyRight + keyRight + "y\r",
+		"abandoned\x03",
+		"héllo" + keyLeft + "\x7f\r",
+		"\x04",
+	}, ""))
+
+	assert.Equal(t, []string{"abc", "hello world!", "ad", "one four", "", "", "xy", "<interrupted>", "hélo"}, lines)
+}
+
+func TestEditorHistory(t *testing.T) {
+	e := &editor{}
+	lines := readLines(t, e, strings.Join([]string{
+		"first\r",
+		"second\r",
+		"second\r",
+		"draft" + keyUp + keyUp + "\r",
+		keyUp + keyUp + keyDown + keyDown + "\r",
+		"\x10\x10\x0e\r",
+	}, ""))
+
+	assert.Equal(t, []string{"first", "second", "second", "first", "", "first"}, lines)
+	assert.Equal(t, []string{"first", "second", "first"}, e.history.lines)
+}
+
+func TestEditorReverseSearch(t *testing.T) {
+	e := &editor{history: &history{lines: []string{"var answer = 42;", "print answer;", "fun f() {}"}}}
+	lines := readLines(t, e, strings.Join([]string{
+		"\x12ans\r",                         // newest match
+		"\x12ans\x12\x12\r",                 // Ctrl-R again finds older ones
+		"\x12fun" + keyEnd + " // edited\r", // other keys end the search and still apply
+		"typed\x12ans\x07\r",                // Ctrl-G restores the line
+		"\x12zzz\r",
+	}, ""))
+
+	assert.Equal(t, []string{
+		"print answer;",
+		"var answer = 42;",
+		"fun f() {} // edited",
+		"typed",
+		"",
+	}, lines)
+}
+
+func TestEditorCompletion(t *testing.T) {
+	words := []string{"print", "printer", "prime", "db.Name", "db.Nickname"}
+	complete := func(word string) []string {
+		var completions []string
+		for _, w := range words {
+			if strings.HasPrefix(w, word) {
+				completions = append(completions, w)
+			}
+		}
+		return completions
+	}
+
+	lines := readLines(t, &editor{complete: complete}, strings.Join([]string{
+		"x = pri\t\r",     // common prefix only
+		"x = print\t\r",   // nothing to add, so the choices are listed
+		"pr\tm\t(1)\r",    // "pri" then "prime"
+		"db.Na\t;\r",      // properties
+		"zzz\t\r",         // no completions
+		"(db.Ni\t) + 1\r", // completes mid-line
+	}, ""))
+
+	assert.Equal(t, []string{"x = pri", "x = print", "prime(1)", "db.Name;", "zzz", "(db.Nickname) + 1"}, lines)
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", ".lox_history")
+
+	h, err := loadHistory(path)
+	require.NoError(t, err)
+	require.NoError(t, h.add("one"))
+	require.NoError(t, h.add(""))
+	require.NoError(t, h.add("two"))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", string(content))
+
+	h, err = loadHistory(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, h.lines)
+}
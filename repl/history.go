@@ -0,0 +1,93 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistory is how many lines are kept in memory and loaded from the file.
+const maxHistory = 1000
+
+// DefaultHistoryFile returns ~/.lox_history, or "" if there is no home
+// directory to keep it in.
+func DefaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".lox_history")
+}
+
+// history is the list of lines entered so far, oldest first, optionally backed
+// by a file that each new line is appended to.
+type history struct {
+	lines []string
+	path  string
+}
+
+// loadHistory reads the history file at path. A missing file is not an error;
+// it is created when the first line is added.
+func loadHistory(path string) (*history, error) {
+	h := &history{path: path}
+	if path == "" {
+		return h, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		h.lines = append(h.lines, scanner.Text())
+	}
+	if len(h.lines) > maxHistory {
+		h.lines = h.lines[len(h.lines)-maxHistory:]
+	}
+	return h, scanner.Err()
+}
+
+// add records line unless it is blank or repeats the previous line.
+func (h *history) add(line string) error {
+	if line == "" || (len(h.lines) > 0 && h.lines[len(h.lines)-1] == line) {
+		return nil
+	}
+
+	h.lines = append(h.lines, line)
+	if len(h.lines) > maxHistory {
+		h.lines = h.lines[1:]
+	}
+
+	if h.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(line + "\n")
+	return err
+}
+
+// search returns the index of the newest line before index that contains
+// query, or -1.
+func (h *history) search(query string, index int) int {
+	for idx := min(index, len(h.lines)) - 1; idx >= 0; idx-- {
+		if strings.Contains(h.lines[idx], query) {
+			return idx
+		}
+	}
+	return -1
+}
@@ -0,0 +1,190 @@
+// Package repl implements the interactive loop shared by the Lox commands.
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"interpreter/lox"
+)
+
+// Evaluator runs the code typed at the prompt.
+type Evaluator interface {
+	// Eval runs one complete piece of input.
+	Eval(source string) error
+	// Reset discards everything defined so far.
+	Reset()
+	// Globals describes the globals defined so far, one per line.
+	Globals() []string
+}
+
+const (
+	prompt             = "> "
+	continuationPrompt = "... "
+)
+
+const help = `Enter Lox statements or expressions. Input continues on the next line while
+a string, parenthesis or brace is left open.
+
+Commands:
+  :help         show this help
+  :reset        discard all definitions
+  :load <file>  run a file in the current session
+  :env          list the globals defined in this session
+`
+
+// Completer is implemented by evaluators that can complete the word before
+// the cursor when the user presses Tab.
+type Completer interface {
+	Complete(word string) []string
+}
+
+// REPL reads input, runs it with an Evaluator and reports errors. When the
+// input is a terminal, lines can be edited and recalled from the history.
+type REPL struct {
+	eval        Evaluator
+	in          io.Reader
+	stdout      io.Writer
+	stderr      io.Writer
+	historyPath string
+}
+
+func New(eval Evaluator, in io.Reader, stdout, stderr io.Writer) *REPL {
+	return &REPL{
+		eval:   eval,
+		in:     in,
+		stdout: stdout,
+		stderr: stderr,
+	}
+}
+
+// SetHistoryFile sets the file that history is loaded from and saved to when
+// the input is a terminal.
+func (r *REPL) SetHistoryFile(path string) {
+	r.historyPath = path
+}
+
+// Run reads and evaluates input until the end of the input.
+func (r *REPL) Run() error {
+	reader, err := r.lineReader()
+	if err != nil {
+		return err
+	}
+
+	var pending strings.Builder
+	for {
+		currentPrompt := prompt
+		if pending.Len() > 0 {
+			currentPrompt = continuationPrompt
+		}
+
+		line, err := reader.ReadLine(currentPrompt)
+		if errors.Is(err, errInterrupted) {
+			pending.Reset()
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(r.stdout)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if pending.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			if err := r.command(strings.TrimSpace(line)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		pending.WriteString(line)
+		pending.WriteString("\n")
+
+		source := pending.String()
+		if !lox.IsComplete(source) {
+			continue
+		}
+		pending.Reset()
+
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+		if err := r.run(source); err != nil {
+			return err
+		}
+	}
+}
+
+// run evaluates source and reports any error, except that a script calling
+// exit() ends the session: its ExitError is returned.
+func (r *REPL) run(source string) error {
+	err := r.eval.Eval(source)
+	var exitErr *lox.ExitError
+	if errors.As(err, &exitErr) {
+		return err
+	}
+
+	r.report(err)
+	return nil
+}
+
+// lineReader returns an editor if the input is a terminal, or reads plain
+// lines otherwise.
+func (r *REPL) lineReader() (lineReader, error) {
+	file, ok := r.in.(*os.File)
+	if !ok || !isTerminal(file.Fd()) {
+		return &plainReader{in: bufio.NewScanner(r.in), out: r.stdout}, nil
+	}
+
+	history, err := loadHistory(r.historyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &editor{fd: file.Fd(), raw: true, in: bufio.NewReader(file), out: r.stdout, history: history}
+	if completer, ok := r.eval.(Completer); ok {
+		e.complete = completer.Complete
+	}
+	return e, nil
+}
+
+func (r *REPL) command(line string) error {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case ":help":
+		fmt.Fprint(r.stdout, help)
+	case ":reset":
+		r.eval.Reset()
+	case ":load":
+		if arg == "" {
+			fmt.Fprintln(r.stderr, "Usage: :load <file>")
+			return nil
+		}
+		source, err := os.ReadFile(arg)
+		if err != nil {
+			r.report(err)
+			return nil
+		}
+		return r.run(string(source))
+	case ":env":
+		for _, global := range r.eval.Globals() {
+			fmt.Fprintln(r.stdout, global)
+		}
+	default:
+		fmt.Fprintf(r.stderr, "Unknown command '%s'. Type :help for a list of commands.\n", name)
+	}
+	return nil
+}
+
+func (r *REPL) report(err error) {
+	if err != nil {
+		fmt.Fprintln(r.stderr, err)
+	}
+}
@@ -0,0 +1,52 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"interpreter/lox"
+)
+
+func TestREPL(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.lox"), []byte("fun twice(x) { return x * 2; }\n"), 0o644))
+
+	input := strings.Join([]string{
+		`var a = 1;`,
+		`fun f(x) {`,
+		`  return x + a;`,
+		`}`,
+		`f(2)`,
+		`:load ` + filepath.Join(dir, "lib.lox"),
+		`twice(a)`,
+		`:env`,
+		`:reset`,
+		`:env`,
+		`a`,
+		`:nope`,
+	}, "\n")
+
+	var stdout, stderr bytes.Buffer
+	session := lox.NewSession(lox.WithStdout(&stdout))
+	require.NoError(t, New(session, strings.NewReader(input), &stdout, &stderr).Run())
+
+	assert.Equal(t, "> > ... ... > 3\n> > 2\n> a = 1\nf = <fn f>\ntwice = <fn twice>\n> > > > > \n", stdout.String())
+	assert.Equal(t, "[line 1] Undefined variable 'a'.\nUnknown command ':nope'. Type :help for a list of commands.\n", stderr.String())
+}
+
+func TestREPLExit(t *testing.T) {
+	var stdout bytes.Buffer
+	session := lox.NewSession(lox.WithStdout(&stdout))
+	err := New(session, strings.NewReader("print 1;\nexit(2);\nprint 3;\n"), &stdout, &stdout).Run()
+
+	var exitErr *lox.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 2, exitErr.Code)
+	assert.Equal(t, "> 1\n> ", stdout.String())
+}
@@ -0,0 +1,153 @@
+//go:build linux
+
+package repl
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"interpreter/lox"
+)
+
+// openPTY opens a pseudo-terminal pair. The test plays the user at the
+// master end; the REPL reads and writes the slave end as its terminal.
+func openPTY(t *testing.T) (master, slave *os.File) {
+	t.Helper()
+
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("no pseudo-terminals: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+
+	var unlock int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		t.Skipf("unlocking pseudo-terminal: %v", errno)
+	}
+	var n uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		t.Skipf("getting pseudo-terminal number: %v", errno)
+	}
+
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("opening pseudo-terminal: %v", err)
+	}
+	t.Cleanup(func() { slave.Close() })
+	return master, slave
+}
+
+// syncBuffer collects terminal output written by one goroutine while another
+// polls it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestREPLOnTerminal(t *testing.T) {
+	master, slave := openPTY(t)
+	require.True(t, isTerminal(slave.Fd()))
+
+	var screen syncBuffer
+	go func() { _, _ = copyUntilClosed(&screen, master) }()
+
+	before, err := getTermios(slave.Fd())
+	require.NoError(t, err)
+
+	historyPath := filepath.Join(t.TempDir(), ".lox_history")
+	require.NoError(t, os.WriteFile(historyPath, []byte("var fromHistory = 1;\n"), 0o600))
+
+	var output bytes.Buffer
+	session := lox.NewSession(lox.WithStdout(&output))
+	r := New(session, slave, slave, slave)
+	r.SetHistoryFile(historyPath)
+
+	done := make(chan error)
+	go func() { done <- r.Run() }()
+
+	// Wait for an empty prompt before typing each line, so the keys reach the
+	// editor in raw mode rather than the kernel's line editing.
+	emptyPrompt := "\r> \x1b[K"
+	prompts := 0
+	for _, keys := range []string{
+		"var greting = \"hi\";" + "\x1b[H" + strings.Repeat("\x1b[C", 7) + "e\x1b[Fx\x7f\r", // fixed with arrow keys
+		"\x1b[A\x1b[A\r", // recalled from the history file
+		"gree\t + \"!\"\r",
+		"\x04",
+	} {
+		waitFor(t, func() bool { return strings.Count(screen.String(), emptyPrompt) > prompts })
+		prompts++
+
+		_, err := master.WriteString(keys)
+		require.NoError(t, err)
+	}
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("REPL did not exit")
+	}
+
+	assert.Equal(t, "hi!\n", output.String())
+	assert.Equal(t, []string{`fromHistory = 1`, `greeting = hi`}, session.Globals())
+
+	history, err := os.ReadFile(historyPath)
+	require.NoError(t, err)
+	assert.Equal(t, "var fromHistory = 1;\nvar greeting = \"hi\";\nvar fromHistory = 1;\ngreeting + \"!\"\n", string(history))
+
+	// The terminal is back in its original mode.
+	after, err := getTermios(slave.Fd())
+	require.NoError(t, err)
+	assert.Equal(t, before.Lflag, after.Lflag)
+}
+
+func copyUntilClosed(dst *syncBuffer, src *os.File) (int64, error) {
+	buf := make([]byte, 1024)
+	var total int64
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			_, _ = dst.Write(buf[:n])
+			total += int64(n)
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the terminal")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
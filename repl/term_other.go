@@ -0,0 +1,16 @@
+//go:build !linux
+
+package repl
+
+import "errors"
+
+// Raw mode is only implemented for Linux. Elsewhere the REPL reads plain
+// lines without editing.
+
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+func makeRaw(fd uintptr) (func() error, error) {
+	return nil, errors.New("raw mode is not supported on this platform")
+}